@@ -6,12 +6,130 @@ import (
 
 // Config holds all settings of finportal
 type Config struct {
-	AppName     string `yaml:"app_name" mapstructure:"app_name"`
-	HTTPPort    string `yaml:"port" mapstructure:"port"`
-	MySQL       *MySQL `yaml:"mysql" mapstructure:"mysql"`
-	Environment string `yaml:"environment" mapstructure:"environment"`
-	TokenTTL    int64  `yaml:"token_ttl" mapstructure:"token_ttl"`
-	LogLevel    uint8  `yaml:"log_level" mapstructure:"log_level"`
+	AppName     string      `yaml:"app_name" mapstructure:"app_name"`
+	HTTPPort    string      `yaml:"port" mapstructure:"port"`
+	MySQL       *MySQL      `yaml:"mysql" mapstructure:"mysql"`
+	Environment string      `yaml:"environment" mapstructure:"environment"`
+	TokenTTL    int64       `yaml:"token_ttl" mapstructure:"token_ttl"`
+	LogLevel    uint8       `yaml:"log_level" mapstructure:"log_level"`
+	Scheduler   *Scheduler  `yaml:"scheduler" mapstructure:"scheduler"`
+	BreakGlass  *BreakGlass `yaml:"break_glass" mapstructure:"break_glass"`
+	Resolver    *Resolver   `yaml:"resolver" mapstructure:"resolver"`
+	Gateway     *Gateway    `yaml:"gateway" mapstructure:"gateway"`
+	Provider    *Provider   `yaml:"provider" mapstructure:"provider"`
+	ExtAuthz    *ExtAuthz   `yaml:"ext_authz" mapstructure:"ext_authz"`
+	SSO         *SSO        `yaml:"sso" mapstructure:"sso"`
+	Cache       *Cache      `yaml:"cache" mapstructure:"cache"`
+	RateLimit   *RateLimit  `yaml:"rate_limit" mapstructure:"rate_limit"`
+	Network     *Network    `yaml:"network" mapstructure:"network"`
+	Signature   *Signature  `yaml:"signature" mapstructure:"signature"`
+}
+
+// Resolver how the service of an incoming request is found: path, host, header or routes
+type Resolver struct {
+	Type   string            `yaml:"type" mapstructure:"type"`
+	Header string            `yaml:"header" mapstructure:"header"` // header type, e.g. X-Forwarded-Prefix
+	Hosts  map[string]string `yaml:"hosts" mapstructure:"hosts"`   // host type, host --> service, empty means first host label
+	Routes []*Route          `yaml:"routes" mapstructure:"routes"` // routes type
+}
+
+// Route requests of host (any if empty) under path_prefix belong to service
+type Route struct {
+	Host        string `yaml:"host" mapstructure:"host"`
+	PathPrefix  string `yaml:"path_prefix" mapstructure:"path_prefix"`
+	Service     string `yaml:"service" mapstructure:"service"`
+	StripPrefix bool   `yaml:"strip_prefix" mapstructure:"strip_prefix"`
+}
+
+// Gateway built-in reverse proxy, serves the routes of traefik.yml without Traefik
+type Gateway struct {
+	Enable bool            `yaml:"enable" mapstructure:"enable"`
+	Port   string          `yaml:"port" mapstructure:"port"`
+	Routes []*GatewayRoute `yaml:"routes" mapstructure:"routes"`
+}
+
+// GatewayRoute requests of host (any if empty) under path_prefix are validated, unless public, and proxied to
+// upstream. The path is rewritten by regex and replacement like replacePathRegex
+type GatewayRoute struct {
+	Host        string `yaml:"host" mapstructure:"host"`
+	PathPrefix  string `yaml:"path_prefix" mapstructure:"path_prefix"`
+	Upstream    string `yaml:"upstream" mapstructure:"upstream"`
+	Regex       string `yaml:"regex" mapstructure:"regex"`
+	Replacement string `yaml:"replacement" mapstructure:"replacement"`
+	Public      bool   `yaml:"public" mapstructure:"public"` // no token required, like route-to-auth
+}
+
+// Provider Traefik HTTP provider rendering the routing of the registered services
+type Provider struct {
+	Token              string   `yaml:"token" mapstructure:"token"`                               // bearer token set in providers.http.headers, empty for none
+	ForwardAuthAddress string   `yaml:"forward_auth_address" mapstructure:"forward_auth_address"` // validate endpoint as Traefik reaches it
+	EntryPoints        []string `yaml:"entry_points" mapstructure:"entry_points"`
+}
+
+// ExtAuthz Envoy external authorization, the HTTP service is always served under /internal_api/ext_authz
+type ExtAuthz struct {
+	GRPCEnable bool   `yaml:"grpc_enable" mapstructure:"grpc_enable"` // envoy.service.auth.v3.Authorization
+	GRPCPort   string `yaml:"grpc_port" mapstructure:"grpc_port"`
+}
+
+// SSO browser sessions, browsers navigating to a protected route without a token are redirected to login_url
+type SSO struct {
+	LoginURL     string   `yaml:"login_url" mapstructure:"login_url"`         // /sso/login as browsers reach it, no redirect if empty
+	CookieName   string   `yaml:"cookie_name" mapstructure:"cookie_name"`     // session cookie, accepted by every service
+	CookieDomain string   `yaml:"cookie_domain" mapstructure:"cookie_domain"` // e.g. .example.com to share the session, host only if empty
+	CookieSecure bool     `yaml:"cookie_secure" mapstructure:"cookie_secure"`
+	ReturnTTL    int64    `yaml:"return_ttl" mapstructure:"return_ttl"`     // second, validity of the signed return url
+	ReturnHosts  []string `yaml:"return_hosts" mapstructure:"return_hosts"` // hosts browsers are sent back to besides the routing host of the service
+}
+
+// Cache in-process decision cache of the validate endpoints, invalidated by the changes made through this replica
+type Cache struct {
+	Size int   `yaml:"size" mapstructure:"size"` // entries, no cache if 0
+	TTL  int64 `yaml:"ttl" mapstructure:"ttl"`   // second, also capped at the token expiry
+}
+
+// RateLimit token buckets of the validate endpoints, per replica. Policies with a rate_limit raise the object one
+type RateLimit struct {
+	Object   *Bucket `yaml:"object" mapstructure:"object"`
+	Service  *Bucket `yaml:"service" mapstructure:"service"`
+	ClientIP *Bucket `yaml:"client_ip" mapstructure:"client_ip"` // first X-Forwarded-For hop from the right not a trusted proxy
+}
+
+// Network how the client IP is found, the network conditions of services and policies and the client_ip bucket apply to it
+type Network struct {
+	TrustedProxies []string `yaml:"trusted_proxies" mapstructure:"trusted_proxies"` // CIDRs of the proxies in front, e.g. the load balancer, the last X-Forwarded-For hop is the client if empty
+}
+
+// Signature HMAC signed requests of machine clients, verified by the validate endpoints
+type Signature struct {
+	Skew int64 `yaml:"skew" mapstructure:"skew"` // second, tolerated clock skew of the Date header, nonces are kept as long
+}
+
+// Bucket rate tokens per second up to burst, none if rate is 0
+type Bucket struct {
+	Rate  float64 `yaml:"rate" mapstructure:"rate"`
+	Burst int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// Scheduler background jobs, only the replica holding the lease runs them
+type Scheduler struct {
+	Enable   bool  `yaml:"enable" mapstructure:"enable"`
+	Interval int64 `yaml:"interval" mapstructure:"interval"`   // second
+	LeaseTTL int64 `yaml:"lease_ttl" mapstructure:"lease_ttl"` // second, must be longer than interval
+}
+
+// BreakGlass emergency accounts, usable when policies lock every admin out
+type BreakGlass struct {
+	TokenTTL int64                `yaml:"token_ttl" mapstructure:"token_ttl"` // second
+	Attempts *Bucket              `yaml:"attempts" mapstructure:"attempts"`   // failed attempts per client IP, 5 then 1 a minute if absent
+	Accounts []*BreakGlassAccount `yaml:"accounts" mapstructure:"accounts"`
+}
+
+// BreakGlassAccount secret is sealed like service keys: base64url(sha256(secret key + secret)), name is up to 24 characters
+type BreakGlassAccount struct {
+	Name     string   `yaml:"name" mapstructure:"name"`
+	Secret   string   `yaml:"secret" mapstructure:"secret"`
+	Services []string `yaml:"services" mapstructure:"services"` // service ids the account can open, empty means all
 }
 
 // MySQL ...
@@ -125,6 +243,49 @@ mysql:
   connection_idle_max: 0
   connection_idle_time: 10
   log: true
+scheduler:
+  enable: true
+  interval: 30
+  lease_ttl: 90
+break_glass:
+  token_ttl: 900
+  accounts: []
+resolver:
+  type: path
+gateway:
+  enable: false
+  port: "4480"
+  routes: []
+provider:
+  token: ""
+  forward_auth_address: "http://sso_service:4445/internal_api/validate"
+  entry_points: ["web"]
+ext_authz:
+  grpc_enable: false
+  grpc_port: "9191"
+sso:
+  login_url: ""
+  cookie_name: kontrol_session
+  cookie_domain: ""
+  cookie_secure: true
+  return_ttl: 600
+cache:
+  size: 10000
+  ttl: 30
+rate_limit:
+  object:
+    rate: 0
+    burst: 0
+  service:
+    rate: 0
+    burst: 0
+  client_ip:
+    rate: 0
+    burst: 0
+network:
+  trusted_proxies: []
+signature:
+  skew: 300
 `
 
 // Auto testing config
@@ -147,4 +308,47 @@ mysql:
  connection_idle_max: 0
  connection_idle_time: 10
  log: true
+scheduler:
+ enable: false
+ interval: 30
+ lease_ttl: 90
+break_glass:
+ token_ttl: 900
+ accounts: []
+resolver:
+ type: path
+gateway:
+ enable: false
+ port: "4480"
+ routes: []
+provider:
+ token: ""
+ forward_auth_address: "http://sso_service:4445/internal_api/validate"
+ entry_points: ["web"]
+ext_authz:
+ grpc_enable: false
+ grpc_port: "9191"
+sso:
+ login_url: ""
+ cookie_name: kontrol_session
+ cookie_domain: ""
+ cookie_secure: false
+ return_ttl: 600
+cache:
+ size: 0
+ ttl: 30
+rate_limit:
+ object:
+  rate: 0
+  burst: 0
+ service:
+  rate: 0
+  burst: 0
+ client_ip:
+  rate: 0
+  burst: 0
+network:
+ trusted_proxies: []
+signature:
+ skew: 300
 `
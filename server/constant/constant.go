@@ -23,6 +23,13 @@ type dbtablename struct {
 	TB_OBJECT_POLICY_MESH  string
 	TB_OBJECT_SERVICE_MESH string
 	TB_POLICIES            string
+	TB_SCHEDULER_LEASES    string
+	TB_ORGANIZATIONS       string
+	TB_SERVICE_ADMINS      string
+	TB_AUDIT_EVENTS        string
+	TB_POLICY_TEMPLATES    string
+	TB_SIGNING_KEYS        string
+	TB_SIGNATURE_NONCES    string
 }
 
 var DBTableName = dbtablename{
@@ -32,14 +39,23 @@ var DBTableName = dbtablename{
 	TB_OBJECT_POLICY_MESH:  "object_policy_mesh",
 	TB_OBJECT_SERVICE_MESH: "object_service_mesh",
 	TB_POLICIES:            "policies",
+	TB_SCHEDULER_LEASES:    "scheduler_leases",
+	TB_ORGANIZATIONS:       "organizations",
+	TB_SERVICE_ADMINS:      "service_admins",
+	TB_AUDIT_EVENTS:        "audit_events",
+	TB_POLICY_TEMPLATES:    "policy_templates",
+	TB_SIGNING_KEYS:        "signing_keys",
+	TB_SIGNATURE_NONCES:    "signature_nonces",
 }
 
 type commonerror struct {
 	INVALID_PARAM error
 	FORBIDDEN     error
+	LEASE_HELD    error
 }
 
 var CommonError = commonerror{
 	INVALID_PARAM: errors.New("invalid params"),
 	FORBIDDEN:     errors.New("forbidden"),
+	LEASE_HELD:    errors.New("lease held by another holder"),
 }
@@ -0,0 +1,126 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hungvtc/traefik-integrate/server/constant"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
+)
+
+const usage = "usage: gitops export|plan|apply [-f file] [-organization id]"
+
+//Run gitops command: export dumps the database into a manifest, plan prints the changes reconciling the
+//database with a manifest, apply makes them. Files ending in .json are json, yaml otherwise
+func Run(ctx context.Context, s *wrapper.Service, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	flags := flag.NewFlagSet("gitops "+args[0], flag.ContinueOnError)
+	file := flags.String("f", "", "manifest file, standard output for export when empty")
+	organization := flags.String("organization", "", "restrict to the services of an organization")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *organization != "" {
+		ctx = gokontrol.WithOrganization(ctx, *organization)
+	}
+
+	txi, err := s.DB.Transaction()
+	if err != nil {
+		return err
+	}
+	tx := txi.(*gorm.DB)
+	ctx = context.WithValue(ctx, constant.ContextKeyTransaction, tx)
+	defer tx.Rollback()
+
+	switch args[0] {
+	case "export":
+		m, err := s.Kontrol.ExportManifest(ctx)
+		if err != nil {
+			return err
+		}
+		if *file == "" {
+			return encode(out, m, false)
+		}
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return encode(f, m, isJSON(*file))
+	case "plan", "apply":
+		if *file == "" {
+			return errors.New(usage)
+		}
+		m, err := decode(*file)
+		if err != nil {
+			return err
+		}
+		var changes []*gokontrol.PlanChange
+		if args[0] == "plan" {
+			changes, err = s.Kontrol.PlanManifest(ctx, m)
+		} else {
+			changes, err = s.Kontrol.ApplyManifest(ctx, m)
+		}
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Fprintln(out, "no changes")
+		}
+		for _, c := range changes {
+			fmt.Fprintf(out, "%s %s %s/%s\n", c.Action, c.Kind, c.ServiceID, c.ID)
+		}
+		if args[0] == "apply" {
+			return tx.Commit().Error
+		}
+		return nil
+	}
+	return errors.New(usage)
+}
+
+func isJSON(file string) bool {
+	return strings.HasSuffix(strings.ToLower(file), ".json")
+}
+
+func encode(w io.Writer, m *gokontrol.Manifest, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	}
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func decode(file string) (*gokontrol.Manifest, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var m gokontrol.Manifest
+	if isJSON(file) {
+		err = json.Unmarshal(b, &m)
+	} else {
+		err = yaml.UnmarshalStrict(b, &m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,56 @@
+package lint
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/hungvtc/traefik-integrate/server/constant"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"gorm.io/gorm"
+)
+
+// ErrFindings lint found errors, the command exits non zero
+var ErrFindings = errors.New("policy linter found errors")
+
+//Run lint command: print the policy linter findings of every service, or of the -service one
+func Run(ctx context.Context, s *wrapper.Service, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	serviceID := flags.String("service", "", "lint the policies of this service only")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	txi, err := s.DB.Session()
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, constant.ContextKeyTransaction, txi.(*gorm.DB))
+
+	services, err := s.StorageKontrol.GetServices(ctx)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, service := range services {
+		if *serviceID != "" && service.ID != *serviceID {
+			continue
+		}
+		// operators lint as the organization of each service
+		issues, err := s.Kontrol.LintPolicies(gokontrol.WithOrganization(ctx, service.OrganizationID), "", service.ID)
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			fmt.Fprintf(out, "%s %s/%s %q %s: %s\n", issue.Severity, service.ID, issue.PolicyID, issue.Key, issue.Rule, issue.Message)
+			failed = failed || issue.Severity == gokontrol.LintSeverity.ERROR
+		}
+	}
+	if failed {
+		return ErrFindings
+	}
+	return nil
+}
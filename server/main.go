@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/gitops"
+	"github.com/hungvtc/traefik-integrate/server/lint"
 	"github.com/hungvtc/traefik-integrate/server/repository"
+	"github.com/hungvtc/traefik-integrate/server/scheduler"
 	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
 	"github.com/hungvtc/traefik-integrate/server/transport"
 	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	echoLog "github.com/labstack/gommon/log"
@@ -57,7 +64,53 @@ func main() {
 
 	// kontrol
 	storagekontrol := repository.NewKontrolStorage()
-	kontrol := gokontrol.NewBasicKontrol(storagekontrol)
+	option := gokontrol.DefaultKontrolOption
+	if cfg.BreakGlass != nil {
+		option.BreakGlassTTL = cfg.BreakGlass.TokenTTL
+		if cfg.BreakGlass.Attempts != nil {
+			option.RateLimit.BreakGlass = rateLimit(cfg.BreakGlass.Attempts)
+			if err := option.RateLimit.BreakGlass.Validate(); err != nil {
+				logger.Fatal(err)
+			}
+		}
+		for _, a := range cfg.BreakGlass.Accounts {
+			option.BreakGlass = append(option.BreakGlass, &gokontrol.BreakGlassAccount{Name: a.Name, Secret: a.Secret, Services: a.Services})
+		}
+	}
+	if cfg.Resolver != nil {
+		ro := gokontrol.ResolverOption{Type: cfg.Resolver.Type, Header: cfg.Resolver.Header, Hosts: cfg.Resolver.Hosts}
+		for _, r := range cfg.Resolver.Routes {
+			ro.Routes = append(ro.Routes, &gokontrol.Route{Host: r.Host, PathPrefix: r.PathPrefix, Service: r.Service, StripPrefix: r.StripPrefix})
+		}
+		option.Resolver, err = gokontrol.NewServiceResolver(ro)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if cfg.SSO != nil {
+		option.SessionCookie = cfg.SSO.CookieName
+	}
+	if cfg.Cache != nil {
+		option.CacheSize, option.CacheTTL = cfg.Cache.Size, cfg.Cache.TTL
+	}
+	if cfg.RateLimit != nil {
+		option.RateLimit.Object, option.RateLimit.Service, option.RateLimit.ClientIP = rateLimit(cfg.RateLimit.Object), rateLimit(cfg.RateLimit.Service), rateLimit(cfg.RateLimit.ClientIP)
+		for _, l := range []gokontrol.RateLimit{option.RateLimit.Object, option.RateLimit.Service, option.RateLimit.ClientIP} {
+			if err := l.Validate(); err != nil {
+				logger.Fatal(err)
+			}
+		}
+	}
+	if cfg.Network != nil {
+		option.TrustedProxies, err = gokontrol.ParseCIDRs(cfg.Network.TrustedProxies)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if cfg.Signature != nil {
+		option.SignatureSkew = cfg.Signature.Skew
+	}
+	kontrol := gokontrol.NewKontrol(storagekontrol, option)
 
 	ser := &wrapper.Service{
 		Logger:         logger,
@@ -68,7 +121,58 @@ func main() {
 		StorageKontrol: storagekontrol,
 	}
 
+	// cancelled on interrupt or termination, the background jobs stop and the server shuts down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// commands, run instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gitops": // declarative import/export
+			err = gitops.Run(ctx, ser, os.Args[2:], os.Stdout)
+		case "lint": // policy linter
+			err = lint.Run(ctx, ser, os.Args[2:], os.Stdout)
+		default:
+			logger.Fatal(fmt.Sprintf("unknown command %s", os.Args[1]))
+		}
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
+	// background jobs, leader elected across replicas
+	if cfg.Scheduler != nil && cfg.Scheduler.Enable && cfg.Scheduler.Interval > 0 {
+		go scheduler.NewScheduler(ser).Run(ctx)
+	}
+
+	// reverse proxy, serves the traefik.yml routes without Traefik
+	if cfg.Gateway != nil && cfg.Gateway.Enable {
+		g, err := transport.NewGateway(ser)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		go func() {
+			logger.Fatal(g.Start(":" + cfg.Gateway.Port))
+		}()
+	}
+
+	// envoy ext_authz grpc service, the http one is served by the echo server
+	if cfg.ExtAuthz != nil && cfg.ExtAuthz.GRPCEnable {
+		go func() {
+			logger.Fatal(transport.ServeExtAuthzGRPC(ser, cfg.ExtAuthz.GRPCPort))
+		}()
+	}
+
 	e := transport.NewEcho(ser)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.Shutdown(shutdown); err != nil {
+			logger.Error(err)
+		}
+	}()
 
 	switch cfg.Environment {
 	case "dev":
@@ -77,7 +181,15 @@ func main() {
 	default:
 		err = e.Start(":" + cfg.HTTPPort)
 	}
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		logger.Fatal(err)
 	}
 }
+
+// rateLimit bucket of the config, none if absent
+func rateLimit(b *config.Bucket) gokontrol.RateLimit {
+	if b == nil {
+		return gokontrol.RateLimit{}
+	}
+	return gokontrol.RateLimit{Rate: b.Rate, Burst: b.Burst}
+}
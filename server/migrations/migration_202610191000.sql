@@ -0,0 +1,2 @@
+ALTER TABLE `policies`
+  ADD COLUMN `schedule` varchar(4096) NOT NULL DEFAULT '' AFTER `apply_to`;
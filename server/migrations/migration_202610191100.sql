@@ -0,0 +1,36 @@
+CREATE TABLE `scheduler_leases` (
+  `name` varchar(64) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  `holder` varchar(150) NOT NULL DEFAULT '',
+  `expires_at` bigint(20) NOT NULL DEFAULT '0',
+  `checkpoint` bigint(20) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+DELIMITER $$
+$$
+CREATE TRIGGER tgr_b_i_scheduler_leases
+BEFORE INSERT
+ON scheduler_leases FOR EACH ROW
+BEGIN 
+	set new.created_at = UNIX_TIMESTAMP();
+	set new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+$$
+CREATE TRIGGER trg_b_u_scheduler_leases
+BEFORE UPDATE
+ON scheduler_leases FOR EACH ROW
+BEGIN 
+	SET new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+DELIMITER ;
+
+
+ALTER TABLE `policies`
+  ADD KEY `policies_updated_at_IDX` (`updated_at`) USING BTREE;
@@ -0,0 +1,49 @@
+CREATE TABLE `organizations` (
+  `id` varchar(36) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  `name` varchar(100) NOT NULL DEFAULT '',
+  `key` varchar(150) NOT NULL DEFAULT '',
+  `status` varchar(10) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `organizations_status_IDX` (`status`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+DELIMITER $$
+$$
+CREATE TRIGGER tgr_b_i_organizations
+BEFORE INSERT
+ON organizations FOR EACH ROW
+BEGIN 
+	set new.created_at = UNIX_TIMESTAMP();
+	set new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+$$
+CREATE TRIGGER trg_b_u_organizations
+BEFORE UPDATE
+ON organizations FOR EACH ROW
+BEGIN 
+	SET new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+DELIMITER ;
+
+
+-- existing rows belong to the default organization, its empty key can not authenticate
+INSERT INTO `organizations` (`id`, `name`, `key`, `status`) VALUES ('default', 'default', '', 'enable');
+
+ALTER TABLE `services`
+  ADD COLUMN `organization_id` varchar(36) NOT NULL DEFAULT 'default' AFTER `service_id`,
+  ADD KEY `services_organization_id_IDX` (`organization_id`) USING BTREE;
+
+ALTER TABLE `objects`
+  ADD COLUMN `organization_id` varchar(36) NOT NULL DEFAULT 'default' AFTER `service_id`,
+  ADD KEY `objects_organization_id_IDX` (`organization_id`) USING BTREE;
+
+ALTER TABLE `policies`
+  ADD COLUMN `organization_id` varchar(36) NOT NULL DEFAULT 'default' AFTER `service_id`,
+  ADD KEY `policies_organization_id_IDX` (`organization_id`) USING BTREE;
@@ -0,0 +1,32 @@
+CREATE TABLE `service_admins` (
+  `object_id` varchar(36) NOT NULL,
+  `service_id` varchar(36) NOT NULL,
+  `role` varchar(20) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`object_id`, `service_id`, `role`),
+  KEY `service_admins_service_id_IDX` (`service_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+DELIMITER $$
+$$
+CREATE TRIGGER tgr_b_i_service_admins
+BEFORE INSERT
+ON service_admins FOR EACH ROW
+BEGIN 
+	set new.created_at = UNIX_TIMESTAMP();
+	set new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+$$
+CREATE TRIGGER trg_b_u_service_admins
+BEFORE UPDATE
+ON service_admins FOR EACH ROW
+BEGIN 
+	SET new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+DELIMITER ;
@@ -0,0 +1,7 @@
+ALTER TABLE `object_policy_mesh`
+  ADD COLUMN `expires_at` bigint(20) NOT NULL DEFAULT '0' AFTER `policy_id`,
+  ADD COLUMN `reason` varchar(255) NOT NULL DEFAULT '' AFTER `expires_at`,
+  ADD KEY `object_policy_mesh_expires_at_IDX` (`expires_at`) USING BTREE;
+
+ALTER TABLE `policies`
+  ADD COLUMN `max_elevation` bigint(20) NOT NULL DEFAULT '0' AFTER `apply_to`;
@@ -0,0 +1,37 @@
+CREATE TABLE `audit_events` (
+  `id` varchar(36) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  `type` varchar(50) NOT NULL,
+  `actor` varchar(100) NOT NULL DEFAULT '',
+  `service_id` varchar(36) NOT NULL DEFAULT '',
+  `reason` varchar(1024) NOT NULL DEFAULT '',
+  `client_ip` varchar(45) NOT NULL DEFAULT '',
+  `detail` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `audit_events_type_IDX` (`type`, `created_at`) USING BTREE,
+  KEY `audit_events_service_id_IDX` (`service_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+DELIMITER $$
+$$
+CREATE TRIGGER tgr_b_i_audit_events
+BEFORE INSERT
+ON audit_events FOR EACH ROW
+BEGIN 
+	set new.created_at = UNIX_TIMESTAMP();
+	set new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+$$
+CREATE TRIGGER trg_b_u_audit_events
+BEFORE UPDATE
+ON audit_events FOR EACH ROW
+BEGIN 
+	SET new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+DELIMITER ;
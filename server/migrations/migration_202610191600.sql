@@ -0,0 +1,5 @@
+-- existing grants were written by hand and stay in force
+ALTER TABLE `object_service_mesh`
+  ADD COLUMN `status` varchar(10) NOT NULL DEFAULT 'enable' AFTER `object_id`,
+  ADD COLUMN `expires_at` bigint(20) NOT NULL DEFAULT '0' AFTER `status`,
+  ADD KEY `object_service_mesh_expires_at_IDX` (`expires_at`) USING BTREE;
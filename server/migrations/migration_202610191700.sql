@@ -0,0 +1,42 @@
+CREATE TABLE `policy_templates` (
+  `id` varchar(36) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  `name` varchar(100) NOT NULL DEFAULT '',
+  `service_id` varchar(36) NOT NULL,
+  `organization_id` varchar(36) NOT NULL DEFAULT 'default',
+  `parameters` varchar(1024) NOT NULL DEFAULT '[]',
+  `permission` longtext NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `policy_templates_service_id_IDX` (`service_id`) USING BTREE,
+  KEY `policy_templates_organization_id_IDX` (`organization_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+DELIMITER $$
+$$
+CREATE TRIGGER tgr_b_i_policy_templates
+BEFORE INSERT
+ON policy_templates FOR EACH ROW
+BEGIN 
+	set new.created_at = UNIX_TIMESTAMP();
+	set new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+$$
+CREATE TRIGGER trg_b_u_policy_templates
+BEFORE UPDATE
+ON policy_templates FOR EACH ROW
+BEGIN 
+	SET new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+DELIMITER ;
+
+
+ALTER TABLE `policies`
+  ADD COLUMN `template_id` varchar(36) NOT NULL DEFAULT '' AFTER `max_elevation`,
+  ADD COLUMN `template_params` varchar(2048) NOT NULL DEFAULT '' AFTER `template_id`,
+  ADD KEY `policies_template_id_IDX` (`template_id`) USING BTREE;
@@ -0,0 +1,2 @@
+ALTER TABLE `services`
+  ADD COLUMN `routes` varchar(8192) NOT NULL DEFAULT '' AFTER `expiry_date`;
@@ -0,0 +1,5 @@
+ALTER TABLE `objects`
+  ADD COLUMN `attributes` varchar(4096) NOT NULL DEFAULT '' AFTER `status`;
+
+ALTER TABLE `services`
+  ADD COLUMN `identity_attributes` varchar(1024) NOT NULL DEFAULT '' AFTER `routes`;
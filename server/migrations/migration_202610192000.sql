@@ -0,0 +1,6 @@
+ALTER TABLE `services`
+  ADD COLUMN `routing_host` varchar(255) NOT NULL DEFAULT '' AFTER `identity_attributes`,
+  ADD COLUMN `routing_path_prefix` varchar(255) NOT NULL DEFAULT '' AFTER `routing_host`,
+  ADD COLUMN `routing_upstream` varchar(1024) NOT NULL DEFAULT '' AFTER `routing_path_prefix`,
+  ADD COLUMN `routing_regex` varchar(255) NOT NULL DEFAULT '' AFTER `routing_upstream`,
+  ADD COLUMN `routing_replacement` varchar(255) NOT NULL DEFAULT '' AFTER `routing_regex`;
@@ -0,0 +1,2 @@
+ALTER TABLE `services`
+  ADD COLUMN `token_sources` varchar(2048) NOT NULL DEFAULT '' AFTER `routing_replacement`;
@@ -0,0 +1,3 @@
+ALTER TABLE `policies`
+  ADD COLUMN `rate_limit` double NOT NULL DEFAULT '0' AFTER `template_params`,
+  ADD COLUMN `rate_burst` int(11) NOT NULL DEFAULT '0' AFTER `rate_limit`;
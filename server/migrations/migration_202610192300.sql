@@ -0,0 +1,7 @@
+ALTER TABLE `services`
+  ADD COLUMN `network_allow` varchar(2048) NOT NULL DEFAULT '' AFTER `token_sources`,
+  ADD COLUMN `network_deny` varchar(2048) NOT NULL DEFAULT '' AFTER `network_allow`;
+
+ALTER TABLE `policies`
+  ADD COLUMN `network_allow` varchar(2048) NOT NULL DEFAULT '' AFTER `rate_burst`,
+  ADD COLUMN `network_deny` varchar(2048) NOT NULL DEFAULT '' AFTER `network_allow`;
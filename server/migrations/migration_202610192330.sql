@@ -0,0 +1,3 @@
+ALTER TABLE `services`
+  ADD COLUMN `anonymous_permission` varchar(2048) NOT NULL DEFAULT '' AFTER `network_deny`,
+  ADD COLUMN `anonymous_object_id` varchar(36) NOT NULL DEFAULT '' AFTER `anonymous_permission`;
@@ -0,0 +1,2 @@
+ALTER TABLE `services`
+  ADD COLUMN `cors` varchar(2048) NOT NULL DEFAULT '' AFTER `anonymous_object_id`;
@@ -0,0 +1,43 @@
+CREATE TABLE `signing_keys` (
+  `id` varchar(36) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  `object_id` varchar(36) NOT NULL,
+  `organization_id` varchar(36) NOT NULL DEFAULT 'default',
+  `sealed_secret` varchar(255) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `signing_keys_object_id_IDX` (`object_id`) USING BTREE,
+  KEY `signing_keys_organization_id_IDX` (`organization_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+DELIMITER $$
+$$
+CREATE TRIGGER tgr_b_i_signing_keys
+BEFORE INSERT
+ON signing_keys FOR EACH ROW
+BEGIN 
+	set new.created_at = UNIX_TIMESTAMP();
+	set new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+$$
+CREATE TRIGGER trg_b_u_signing_keys
+BEFORE UPDATE
+ON signing_keys FOR EACH ROW
+BEGIN 
+	SET new.updated_at = UNIX_TIMESTAMP();
+END
+$$
+
+DELIMITER ;
+
+
+CREATE TABLE `signature_nonces` (
+  `key_id` varchar(36) NOT NULL,
+  `nonce` varchar(128) NOT NULL,
+  `expires_at` bigint(20) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`key_id`, `nonce`),
+  KEY `signature_nonces_expires_at_IDX` (`expires_at`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -1,9 +1,21 @@
 package repository
 
+import "context"
+
 type Database interface {
 	Transaction() (interface{}, error)
 	Session() (interface{}, error)
 }
 
 type Storage interface {
+	AcquireLease(c context.Context, name string, holder string, ttl int64) (*Lease, error) // take or renew named lease, fails with LEASE_HELD when held by another holder
+	SaveLeaseCheckpoint(c context.Context, name string, holder string, checkpoint int64) error
+}
+
+//Lease named lock held by one replica at a time
+type Lease struct {
+	Name       string
+	Holder     string
+	ExpiresAt  int64
+	Checkpoint int64 // progress of the job guarded by the lease, survive leader changes
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormSession struct {
@@ -65,7 +66,56 @@ func NewGormStorage() Storage {
 	return &gormStorage{}
 }
 
-//kontrol support storage
+type leaseStore struct {
+	Name       string
+	Holder     string
+	ExpiresAt  int64
+	Checkpoint int64
+}
+
+//AcquireLease take the lease when free or expired, renew it when already held by holder
+func (g *gormStorage) AcquireLease(c context.Context, name string, holder string, ttl int64) (*Lease, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	now := time.Now().Unix()
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_SCHEDULER_LEASES).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": now + ttl}).Error
+	if err != nil {
+		return nil, err
+	}
+	// first run, nobody holds the lease yet
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_SCHEDULER_LEASES).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&leaseStore{Name: name, Holder: holder, ExpiresAt: now + ttl}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var lease leaseStore
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_SCHEDULER_LEASES).Where("name = ? ", name).First(&lease).Error
+	if err != nil {
+		return nil, err
+	}
+	if lease.Holder != holder {
+		return nil, constant.CommonError.LEASE_HELD
+	}
+	return &Lease{
+		Name:       lease.Name,
+		Holder:     lease.Holder,
+		ExpiresAt:  lease.ExpiresAt,
+		Checkpoint: lease.Checkpoint,
+	}, nil
+}
+
+//SaveLeaseCheckpoint record job progress, only the current holder can
+func (g *gormStorage) SaveLeaseCheckpoint(c context.Context, name string, holder string, checkpoint int64) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SCHEDULER_LEASES).
+		Where("name = ? AND holder = ?", name, holder).
+		Update("checkpoint", checkpoint).Error
+}
+
+//kontrol support storage, reads and writes are restricted to the organization of the context if any
 type kontrolStorage struct{}
 
 func NewKontrolStorage() gokontrol.KontrolStore {
@@ -73,12 +123,129 @@ func NewKontrolStorage() gokontrol.KontrolStore {
 }
 
 type serviceStore struct {
-	ID         string
-	ServiceID  string
-	Name       string
-	Key        string
-	Status     string
-	ExpiryDate int64
+	ID                  string
+	ServiceID           string
+	OrganizationID      string
+	Name                string
+	Key                 string
+	Status              string
+	ExpiryDate          int64
+	Routes              string
+	IdentityAttributes  string
+	RoutingHost         string
+	RoutingPathPrefix   string
+	RoutingUpstream     string
+	RoutingRegex        string
+	RoutingReplacement  string
+	TokenSources        string
+	NetworkAllow        string
+	NetworkDeny         string
+	AnonymousPermission string
+	AnonymousObjectID   string
+	CORS                string
+}
+
+// toService service row, policies are loaded by the callers
+func (servicestore *serviceStore) toService() (*gokontrol.Service, error) {
+	var routes, identityAttributes []string
+	var tokenSources []*gokontrol.TokenSource
+	if servicestore.TokenSources != "" {
+		if err := json.Unmarshal([]byte(servicestore.TokenSources), &tokenSources); err != nil {
+			return nil, err
+		}
+	}
+	if servicestore.Routes != "" {
+		if err := json.Unmarshal([]byte(servicestore.Routes), &routes); err != nil {
+			return nil, err
+		}
+	}
+	if servicestore.IdentityAttributes != "" {
+		if err := json.Unmarshal([]byte(servicestore.IdentityAttributes), &identityAttributes); err != nil {
+			return nil, err
+		}
+	}
+	network, err := decodeNetwork(servicestore.NetworkAllow, servicestore.NetworkDeny)
+	if err != nil {
+		return nil, err
+	}
+	var cors *gokontrol.CORS
+	if servicestore.CORS != "" {
+		if err := json.Unmarshal([]byte(servicestore.CORS), &cors); err != nil {
+			return nil, err
+		}
+	}
+	anonymous := gokontrol.AnonymousAccess{ObjectID: servicestore.AnonymousObjectID}
+	if servicestore.AnonymousPermission != "" {
+		if err := json.Unmarshal([]byte(servicestore.AnonymousPermission), &anonymous.Permission); err != nil {
+			return nil, err
+		}
+	}
+	return &gokontrol.Service{
+		ID:                 servicestore.ID,
+		ServiceID:          servicestore.ServiceID,
+		OrganizationID:     servicestore.OrganizationID,
+		Name:               servicestore.Name,
+		Key:                servicestore.Key,
+		Status:             servicestore.Status,
+		ExpiryDate:         servicestore.ExpiryDate,
+		Routes:             routes,
+		IdentityAttributes: identityAttributes,
+		Routing: gokontrol.ServiceRouting{
+			Host:        servicestore.RoutingHost,
+			PathPrefix:  servicestore.RoutingPathPrefix,
+			Upstream:    servicestore.RoutingUpstream,
+			Regex:       servicestore.RoutingRegex,
+			Replacement: servicestore.RoutingReplacement,
+		},
+		TokenSources: tokenSources,
+		Network:      network,
+		Anonymous:    anonymous,
+		CORS:         cors,
+	}, nil
+}
+
+type organizationStore struct {
+	ID     string
+	Name   string
+	Key    string
+	Status string
+}
+
+// scoped restrict db to the rows of the organization c is scoped to
+func scoped(c context.Context, db *gorm.DB) *gorm.DB {
+	if orgID, ok := gokontrol.OrganizationFromContext(c); ok {
+		return db.Where("organization_id = ?", orgID)
+	}
+	return db
+}
+
+type serviceadmin struct {
+	ObjectID  string
+	ServiceID string
+	Role      string
+}
+
+type auditevent struct {
+	ID        string
+	Type      string
+	Actor     string
+	ServiceID string
+	Reason    string
+	ClientIP  string
+	Detail    string
+}
+
+type signingkey struct {
+	ID             string
+	ObjectID       string
+	OrganizationID string
+	SealedSecret   string
+}
+
+type signaturenonce struct {
+	KeyID     string
+	Nonce     string
+	ExpiresAt int64
 }
 
 type servicepolicymesh struct {
@@ -89,35 +256,87 @@ type servicepolicymesh struct {
 }
 
 type objectStore struct {
-	ID         string
-	GlobalID   string
-	ExternalID string
-	ServiceID  string
-	Status     string
-	Token      string
-	ExpiryDate int64
+	ID             string
+	GlobalID       string
+	ExternalID     string
+	ServiceID      string
+	OrganizationID string
+	Status         string
+	Attributes     string
+	Token          string
+	ExpiryDate     int64
+}
+
+// objectAttributes decode the attributes column, empty when never set
+func objectAttributes(raw string) (map[string]interface{}, error) {
+	attributes := make(map[string]interface{})
+	if raw == "" {
+		return attributes, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// encodeAttributes encode attributes for the column, nil is left empty so that updates keep the stored ones
+func encodeAttributes(attributes map[string]interface{}) (string, error) {
+	if attributes == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(attributes)
+	return string(b), err
 }
 
 type objectpolicymesh struct {
-	ID       string
-	ObjectID string
-	PolicyID string
+	ID        string
+	ObjectID  string
+	PolicyID  string
+	ExpiresAt int64 // 0 for permanent policies, elevation expiry otherwise
+	Reason    string
+}
+
+func (m *objectpolicymesh) toElevation() *gokontrol.Elevation {
+	return &gokontrol.Elevation{
+		ObjectID:  m.ObjectID,
+		PolicyID:  m.PolicyID,
+		Reason:    m.Reason,
+		ExpiresAt: m.ExpiresAt,
+	}
 }
 
 type policystore struct {
-	ID         string
-	Name       string
-	ServiceID  string
-	Permission string
-	Status     string
-	ApplyFrom  int64
-	ApplyTo    int64
+	ID             string
+	Name           string
+	ServiceID      string
+	OrganizationID string
+	Permission     string
+	Status         string
+	ApplyFrom      int64
+	ApplyTo        int64
+	Schedule       string
+	MaxElevation   int64
+	TemplateID     string
+	TemplateParams string
+	RateLimit      float64
+	RateBurst      int
+	NetworkAllow   string
+	NetworkDeny    string
+}
+
+type policytemplatestore struct {
+	ID             string
+	Name           string
+	ServiceID      string
+	OrganizationID string
+	Parameters     string
+	Permission     string
 }
 
 func (k *kontrolStorage) GetObjectByToken(c context.Context, token string, timestamp int64) (*gokontrol.Object, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var objectstore objectStore
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Where("token = ? AND expiry_date >= ?", token, timestamp).First(&objectstore).Error
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS)).Where("token = ? AND expiry_date >= ?", token, timestamp).First(&objectstore).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -126,49 +345,68 @@ func (k *kontrolStorage) GetObjectByToken(c context.Context, token string, times
 	}
 
 	var mesh []*objectpolicymesh
-	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("object_id = ? ", objectstore.ID).Scan(&mesh).Error
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("object_id = ? AND (expires_at = 0 OR expires_at > ?)", objectstore.ID, time.Now().Unix()).Scan(&mesh).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	defaultpolicy := make([]*gokontrol.Policy, 0)
+	elevations := make([]*gokontrol.Elevation, 0)
 	for _, m := range mesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
 		defaultpolicy = append(defaultpolicy, policy)
+		if m.ExpiresAt > 0 {
+			elevations = append(elevations, m.toElevation())
+		}
+	}
+	attributes, err := objectAttributes(objectstore.Attributes)
+	if err != nil {
+		return nil, err
 	}
 	return &gokontrol.Object{
-		ID:          objectstore.ID,
-		GlobalID:    objectstore.GlobalID,
-		ExternalID:  objectstore.ExternalID,
-		ServiceID:   objectstore.ServiceID,
-		Status:      objectstore.Status,
-		Attributes:  nil,
-		Token:       objectstore.Token,
-		ExpiryDate:  objectstore.ExpiryDate,
-		ApplyPolicy: defaultpolicy,
+		ID:             objectstore.ID,
+		GlobalID:       objectstore.GlobalID,
+		ExternalID:     objectstore.ExternalID,
+		ServiceID:      objectstore.ServiceID,
+		OrganizationID: objectstore.OrganizationID,
+		Status:         objectstore.Status,
+		Attributes:     attributes,
+		Token:          objectstore.Token,
+		ExpiryDate:     objectstore.ExpiryDate,
+		ApplyPolicy:    defaultpolicy,
+		Elevations:     elevations,
 	}, nil
 }
 
 func (k *kontrolStorage) CreateObject(c context.Context, obj *gokontrol.Object) error {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	attributes, err := encodeAttributes(obj.Attributes)
+	if err != nil {
+		return err
+	}
 	object := objectStore{
-		ID:         obj.ID,
-		GlobalID:   obj.GlobalID,
-		ExternalID: obj.ExternalID,
-		ServiceID:  obj.ServiceID,
-		Status:     obj.Status,
-		Token:      obj.Token,
-		ExpiryDate: obj.ExpiryDate,
+		ID:             obj.ID,
+		GlobalID:       obj.GlobalID,
+		ExternalID:     obj.ExternalID,
+		ServiceID:      obj.ServiceID,
+		OrganizationID: obj.OrganizationID,
+		Status:         obj.Status,
+		Attributes:     attributes,
+		Token:          obj.Token,
+		ExpiryDate:     obj.ExpiryDate,
 	}
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Create(&object).Error
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Create(&object).Error
 	if err != nil {
 		return err
 	}
 
-	// assuming policies are validated
+	// assuming policies are validated, still never attach a policy of another organization
 	for _, p := range obj.ApplyPolicy {
+		if p.OrganizationID != obj.OrganizationID {
+			return gokontrol.CommonError.CROSS_TENANT
+		}
 		opm := objectpolicymesh{
 			ID:       uuid.NewString(),
 			ObjectID: obj.ID,
@@ -184,34 +422,49 @@ func (k *kontrolStorage) CreateObject(c context.Context, obj *gokontrol.Object)
 
 func (k *kontrolStorage) UpdateObject(c context.Context, obj *gokontrol.Object) error {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	attributes, err := encodeAttributes(obj.Attributes)
+	if err != nil {
+		return err
+	}
 	object := objectStore{
-		ID:         obj.ID,
-		GlobalID:   obj.GlobalID,
-		ExternalID: obj.ExternalID,
-		ServiceID:  obj.ServiceID,
-		Status:     obj.Status,
-		Token:      obj.Token,
-		ExpiryDate: obj.ExpiryDate,
+		ID:             obj.ID,
+		GlobalID:       obj.GlobalID,
+		ExternalID:     obj.ExternalID,
+		ServiceID:      obj.ServiceID,
+		OrganizationID: obj.OrganizationID,
+		Status:         obj.Status,
+		Attributes:     attributes,
+		Token:          obj.Token,
+		ExpiryDate:     obj.ExpiryDate,
 	}
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Updates(&object).Where("id = ?", obj.ID).Error
+	err = scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS)).Where("id = ?", obj.ID).Updates(&object).Error
 	if err != nil {
 		return err
 	}
 
-	// clean old policy
-	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Delete(&objectpolicymesh{}, "object_id = ? ", obj.ID).Error
+	// clean old policy, elevations stay until they lapse
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Delete(&objectpolicymesh{}, "object_id = ? AND expires_at = 0", obj.ID).Error
 	if err != nil {
 		return err
 	}
 
-	// assuming policies are validated
+	// assuming policies are validated, still never attach a policy of another organization
 	for _, p := range obj.ApplyPolicy {
+		if p.OrganizationID != obj.OrganizationID {
+			return gokontrol.CommonError.CROSS_TENANT
+		}
+		if obj.Elevated(p.ID) {
+			continue
+		}
 		opm := objectpolicymesh{
 			ID:       uuid.NewString(),
 			ObjectID: obj.ID,
 			PolicyID: p.ID,
 		}
-		err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Save(&opm).Error
+		// a policy applied permanently supersedes its elevation
+		err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"expires_at": 0, "reason": ""}),
+		}).Create(&opm).Error
 		if err != nil {
 			return err
 		}
@@ -222,7 +475,7 @@ func (k *kontrolStorage) UpdateObject(c context.Context, obj *gokontrol.Object)
 func (k *kontrolStorage) GetObjectByID(c context.Context, id string) (*gokontrol.Object, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var objectstore objectStore
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Where("id = ? ", id).First(&objectstore).Error
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS)).Where("id = ? ", id).First(&objectstore).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -231,35 +484,45 @@ func (k *kontrolStorage) GetObjectByID(c context.Context, id string) (*gokontrol
 	}
 
 	var mesh []*objectpolicymesh
-	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("object_id = ? ", id).Scan(&mesh).Error
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("object_id = ? AND (expires_at = 0 OR expires_at > ?)", id, time.Now().Unix()).Scan(&mesh).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	defaultpolicy := make([]*gokontrol.Policy, 0)
+	elevations := make([]*gokontrol.Elevation, 0)
 	for _, m := range mesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
 		defaultpolicy = append(defaultpolicy, policy)
+		if m.ExpiresAt > 0 {
+			elevations = append(elevations, m.toElevation())
+		}
+	}
+	attributes, err := objectAttributes(objectstore.Attributes)
+	if err != nil {
+		return nil, err
 	}
 	return &gokontrol.Object{
-		ID:          objectstore.ID,
-		GlobalID:    objectstore.GlobalID,
-		ExternalID:  objectstore.ExternalID,
-		ServiceID:   objectstore.ServiceID,
-		Status:      objectstore.Status,
-		Attributes:  nil,
-		Token:       objectstore.Token,
-		ExpiryDate:  objectstore.ExpiryDate,
-		ApplyPolicy: defaultpolicy,
+		ID:             objectstore.ID,
+		GlobalID:       objectstore.GlobalID,
+		ExternalID:     objectstore.ExternalID,
+		ServiceID:      objectstore.ServiceID,
+		OrganizationID: objectstore.OrganizationID,
+		Status:         objectstore.Status,
+		Attributes:     attributes,
+		Token:          objectstore.Token,
+		ExpiryDate:     objectstore.ExpiryDate,
+		ApplyPolicy:    defaultpolicy,
+		Elevations:     elevations,
 	}, nil
 }
 
 func (k *kontrolStorage) GetObjectByExternalID(c context.Context, extid string, serviceid string) (*gokontrol.Object, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var objectstore objectStore
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Where("external_id = ? AND service_id = ? ", extid, serviceid).First(&objectstore).Error
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS)).Where("external_id = ? AND service_id = ? ", extid, serviceid).First(&objectstore).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -268,56 +531,100 @@ func (k *kontrolStorage) GetObjectByExternalID(c context.Context, extid string,
 	}
 
 	var mesh []*objectpolicymesh
-	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("object_id = ? ", objectstore.ID).Scan(&mesh).Error
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("object_id = ? AND (expires_at = 0 OR expires_at > ?)", objectstore.ID, time.Now().Unix()).Scan(&mesh).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	defaultpolicy := make([]*gokontrol.Policy, 0)
+	elevations := make([]*gokontrol.Elevation, 0)
 	for _, m := range mesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
 		defaultpolicy = append(defaultpolicy, policy)
+		if m.ExpiresAt > 0 {
+			elevations = append(elevations, m.toElevation())
+		}
+	}
+	attributes, err := objectAttributes(objectstore.Attributes)
+	if err != nil {
+		return nil, err
 	}
 	return &gokontrol.Object{
-		ID:          objectstore.ID,
-		GlobalID:    objectstore.GlobalID,
-		ExternalID:  objectstore.ExternalID,
-		ServiceID:   objectstore.ServiceID,
-		Status:      objectstore.Status,
-		Attributes:  nil,
-		Token:       objectstore.Token,
-		ExpiryDate:  objectstore.ExpiryDate,
-		ApplyPolicy: defaultpolicy,
+		ID:             objectstore.ID,
+		GlobalID:       objectstore.GlobalID,
+		ExternalID:     objectstore.ExternalID,
+		ServiceID:      objectstore.ServiceID,
+		OrganizationID: objectstore.OrganizationID,
+		Status:         objectstore.Status,
+		Attributes:     attributes,
+		Token:          objectstore.Token,
+		ExpiryDate:     objectstore.ExpiryDate,
+		ApplyPolicy:    defaultpolicy,
+		Elevations:     elevations,
 	}, nil
 }
 
+// GetPolicyByID get policy in its apply range whatever its schedule, tokens check the schedule themselves
 func (k *kontrolStorage) GetPolicyByID(c context.Context, id string) (*gokontrol.Policy, error) {
+	return k.getPolicy(c, id)
+}
+
+// getPolicy get policy in its apply range whatever its schedule, so that attachments outside the schedule are kept
+func (k *kontrolStorage) getPolicy(c context.Context, id string) (*gokontrol.Policy, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var policystore policystore
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES).Where("id = ? AND status = 'enable' AND apply_from <= ? AND apply_to >= ?", id, time.Now().Unix(), time.Now().Unix()).First(&policystore).Error
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES)).Where("id = ? AND status = 'enable' AND apply_from <= ? AND apply_to >= ?", id, time.Now().Unix(), time.Now().Unix()).First(&policystore).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	if err == gorm.ErrRecordNotFound {
 		return nil, gokontrol.CommonError.NOT_FOUND
 	}
+	return policystore.toPolicy()
+}
 
+func (policystore *policystore) toPolicy() (*gokontrol.Policy, error) {
 	perm := make(map[string]int)
-	err = json.Unmarshal([]byte(policystore.Permission), &perm)
+	err := json.Unmarshal([]byte(policystore.Permission), &perm)
+	if err != nil {
+		return nil, err
+	}
+	var schedule []*gokontrol.PolicySchedule
+	if policystore.Schedule != "" {
+		err = json.Unmarshal([]byte(policystore.Schedule), &schedule)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var params map[string]string
+	if policystore.TemplateParams != "" {
+		err = json.Unmarshal([]byte(policystore.TemplateParams), &params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	network, err := decodeNetwork(policystore.NetworkAllow, policystore.NetworkDeny)
 	if err != nil {
 		return nil, err
 	}
 
 	return &gokontrol.Policy{
-		ID:         policystore.ID,
-		Name:       policystore.Name,
-		ServiceID:  policystore.ServiceID,
-		Permission: perm,
-		Status:     policystore.Status,
-		ApplyFrom:  policystore.ApplyFrom,
-		ApplyTo:    policystore.ApplyTo,
+		ID:             policystore.ID,
+		Name:           policystore.Name,
+		ServiceID:      policystore.ServiceID,
+		OrganizationID: policystore.OrganizationID,
+		Permission:     perm,
+		Status:         policystore.Status,
+		ApplyFrom:      policystore.ApplyFrom,
+		ApplyTo:        policystore.ApplyTo,
+		MaxElevation:   policystore.MaxElevation,
+		RateLimit:      gokontrol.RateLimit{Rate: policystore.RateLimit, Burst: policystore.RateBurst},
+		Network:        network,
+		Schedule:       schedule,
+		TemplateID:     policystore.TemplateID,
+		Parameters:     params,
 	}, nil
 }
 
@@ -328,16 +635,41 @@ func (k *kontrolStorage) CreatePolicy(c context.Context, policy *gokontrol.Polic
 	if err != nil {
 		return err
 	}
+	schedule, err := json.Marshal(policy.Schedule)
+	if err != nil {
+		return err
+	}
+	params, err := templateParams(policy.Parameters)
+	if err != nil {
+		return err
+	}
+	networkAllow, err := encodeStrings(policy.Network.Allow)
+	if err != nil {
+		return err
+	}
+	networkDeny, err := encodeStrings(policy.Network.Deny)
+	if err != nil {
+		return err
+	}
 
 	// save DB
 	policystore := policystore{
-		ID:         policy.ID,
-		Name:       policy.Name,
-		ServiceID:  policy.ServiceID,
-		Permission: string(perm),
-		Status:     policy.Status,
-		ApplyFrom:  policy.ApplyFrom,
-		ApplyTo:    policy.ApplyTo,
+		ID:             policy.ID,
+		Name:           policy.Name,
+		ServiceID:      policy.ServiceID,
+		OrganizationID: policy.OrganizationID,
+		Permission:     string(perm),
+		Status:         policy.Status,
+		ApplyFrom:      policy.ApplyFrom,
+		ApplyTo:        policy.ApplyTo,
+		MaxElevation:   policy.MaxElevation,
+		RateLimit:      policy.RateLimit.Rate,
+		RateBurst:      policy.RateLimit.Burst,
+		NetworkAllow:   networkAllow,
+		NetworkDeny:    networkDeny,
+		Schedule:       string(schedule),
+		TemplateID:     policy.TemplateID,
+		TemplateParams: params,
 	}
 	err = tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES).Create(&policystore).Error
 	if err != nil {
@@ -354,18 +686,49 @@ func (k *kontrolStorage) UpdatePolicy(c context.Context, policy *gokontrol.Polic
 	if err != nil {
 		return err
 	}
+	schedule, err := json.Marshal(policy.Schedule)
+	if err != nil {
+		return err
+	}
+	params, err := templateParams(policy.Parameters)
+	if err != nil {
+		return err
+	}
+	networkAllow, err := encodeStrings(policy.Network.Allow)
+	if err != nil {
+		return err
+	}
+	networkDeny, err := encodeStrings(policy.Network.Deny)
+	if err != nil {
+		return err
+	}
 
 	// save DB
 	policystore := policystore{
-		ID:         policy.ID,
-		Name:       policy.Name,
-		ServiceID:  policy.ServiceID,
-		Permission: string(perm),
-		Status:     policy.Status,
-		ApplyFrom:  policy.ApplyFrom,
-		ApplyTo:    policy.ApplyTo,
+		ID:             policy.ID,
+		Name:           policy.Name,
+		ServiceID:      policy.ServiceID,
+		OrganizationID: policy.OrganizationID,
+		Permission:     string(perm),
+		Status:         policy.Status,
+		ApplyFrom:      policy.ApplyFrom,
+		ApplyTo:        policy.ApplyTo,
+		MaxElevation:   policy.MaxElevation,
+		RateLimit:      policy.RateLimit.Rate,
+		RateBurst:      policy.RateLimit.Burst,
+		NetworkAllow:   networkAllow,
+		NetworkDeny:    networkDeny,
+		Schedule:       string(schedule),
+		TemplateID:     policy.TemplateID,
+		TemplateParams: params,
+	}
+	err = scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES)).Where("id = ?", policy.ID).Updates(&policystore).Error
+	if err != nil {
+		return err
 	}
-	err = tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES).Updates(&policystore).Error
+	// Updates skips empty fields, a condition may be lifted
+	err = scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES)).Where("id = ?", policy.ID).
+		Updates(map[string]interface{}{"network_allow": networkAllow, "network_deny": networkDeny}).Error
 	if err != nil {
 		return err
 	}
@@ -407,26 +770,57 @@ func (k *kontrolStorage) ExpiredObjectsByPolicy(c context.Context, policyId stri
 		index++
 	}
 	//com
-	return tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS).Where("id in ?", objectIds).Update("expiry_date", 0).Error
+	return scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS)).Where("id in ?", objectIds).Update("expiry_date", 0).Error
+}
+
+//DeletePolicy remove a policy, its attachments to services and objects
+func (k *kontrolStorage) DeletePolicy(c context.Context, id string) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_POLICY_MESH).Where("policy_id = ?", id).Delete(&servicepolicymesh{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("policy_id = ?", id).Delete(&objectpolicymesh{}).Error
+	if err != nil {
+		return err
+	}
+	return scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES)).Where("id = ?", id).Delete(&policystore{}).Error
+}
+
+//GetPoliciesCrossingWindow get policies whose apply range opened or closed, or which were updated, in (from, to]
+func (k *kontrolStorage) GetPoliciesCrossingWindow(c context.Context, from int64, to int64) ([]*gokontrol.Policy, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var policystores []*policystore
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES).
+		Where("(apply_from > ? AND apply_from <= ?) OR (apply_to >= ? AND apply_to < ?) OR (updated_at > ? AND updated_at <= ?)", from, to, from, to, from, to).
+		Find(&policystores).Error
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*gokontrol.Policy, 0, len(policystores))
+	for _, ps := range policystores {
+		policy, err := ps.toPolicy()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, policy)
+	}
+	return rs, nil
 }
 
 func (k *kontrolStorage) GetServiceByID(c context.Context, id string) (*gokontrol.Service, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var servicestore serviceStore
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_SERVICES).Where("id = ? ", id).First(&servicestore).Error
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_SERVICES)).Where("id = ? ", id).First(&servicestore).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	if err == gorm.ErrRecordNotFound {
 		return nil, gokontrol.CommonError.NOT_FOUND
 	}
-	service := &gokontrol.Service{
-		ID:         servicestore.ID,
-		ServiceID:  servicestore.ServiceID,
-		Name:       servicestore.Name,
-		Key:        servicestore.Key,
-		Status:     servicestore.Status,
-		ExpiryDate: servicestore.ExpiryDate,
+	service, err := servicestore.toService()
+	if err != nil {
+		return nil, err
 	}
 
 	var defaultmesh []*servicepolicymesh
@@ -436,7 +830,7 @@ func (k *kontrolStorage) GetServiceByID(c context.Context, id string) (*gokontro
 	}
 	defaultpolicy := make([]*gokontrol.Policy, 0)
 	for _, m := range defaultmesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
@@ -451,7 +845,7 @@ func (k *kontrolStorage) GetServiceByID(c context.Context, id string) (*gokontro
 	}
 	enforcepolicy := make([]*gokontrol.Policy, 0)
 	for _, m := range enforcemesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
@@ -466,20 +860,16 @@ func (k *kontrolStorage) GetServiceByID(c context.Context, id string) (*gokontro
 func (k *kontrolStorage) GetServiceByExternalId(c context.Context, externalServiceId string) (*gokontrol.Service, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var servicestore serviceStore
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_SERVICES).Where("service_id = ? ", externalServiceId).First(&servicestore).Error
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_SERVICES)).Where("service_id = ? ", externalServiceId).First(&servicestore).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	if err == gorm.ErrRecordNotFound {
 		return nil, gokontrol.CommonError.NOT_FOUND
 	}
-	service := &gokontrol.Service{
-		ID:         servicestore.ID,
-		ServiceID:  servicestore.ServiceID,
-		Name:       servicestore.Name,
-		Key:        servicestore.Key,
-		Status:     servicestore.Status,
-		ExpiryDate: servicestore.ExpiryDate,
+	service, err := servicestore.toService()
+	if err != nil {
+		return nil, err
 	}
 
 	var defaultmesh []*servicepolicymesh
@@ -489,7 +879,7 @@ func (k *kontrolStorage) GetServiceByExternalId(c context.Context, externalServi
 	}
 	defaultpolicy := make([]*gokontrol.Policy, 0)
 	for _, m := range defaultmesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
@@ -504,7 +894,7 @@ func (k *kontrolStorage) GetServiceByExternalId(c context.Context, externalServi
 	}
 	enforcepolicy := make([]*gokontrol.Policy, 0)
 	for _, m := range enforcemesh {
-		policy, err := k.GetPolicyByID(c, m.PolicyID)
+		policy, err := k.getPolicy(c, m.PolicyID)
 		if err != nil {
 			return nil, err
 		}
@@ -514,10 +904,463 @@ func (k *kontrolStorage) GetServiceByExternalId(c context.Context, externalServi
 	return service, nil
 }
 
+//GetServices get the service rows, policies are not loaded
+func (k *kontrolStorage) GetServices(c context.Context) ([]*gokontrol.Service, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var servicestores []*serviceStore
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_SERVICES)).Find(&servicestores).Error
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*gokontrol.Service, 0, len(servicestores))
+	for _, ss := range servicestores {
+		service, err := ss.toService()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, service)
+	}
+	return rs, nil
+}
+
+//UpdateService update name, status, routes, identity attributes, routing, token sources, network, anonymous access
+//and CORS of a service
+func (k *kontrolStorage) UpdateService(c context.Context, service *gokontrol.Service) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	routes, err := encodeStrings(service.Routes)
+	if err != nil {
+		return err
+	}
+	identityAttributes, err := encodeStrings(service.IdentityAttributes)
+	if err != nil {
+		return err
+	}
+	tokenSources := ""
+	if len(service.TokenSources) > 0 {
+		b, err := json.Marshal(service.TokenSources)
+		if err != nil {
+			return err
+		}
+		tokenSources = string(b)
+	}
+	networkAllow, err := encodeStrings(service.Network.Allow)
+	if err != nil {
+		return err
+	}
+	networkDeny, err := encodeStrings(service.Network.Deny)
+	if err != nil {
+		return err
+	}
+	anonymousPermission, err := encodeStrings(service.Anonymous.Permission)
+	if err != nil {
+		return err
+	}
+	cors := ""
+	if service.CORS != nil {
+		b, err := json.Marshal(service.CORS)
+		if err != nil {
+			return err
+		}
+		cors = string(b)
+	}
+	return scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_SERVICES)).Where("id = ?", service.ID).
+		Updates(map[string]interface{}{
+			"name": service.Name, "status": service.Status, "routes": routes, "identity_attributes": identityAttributes,
+			"routing_host": service.Routing.Host, "routing_path_prefix": service.Routing.PathPrefix, "routing_upstream": service.Routing.Upstream,
+			"routing_regex": service.Routing.Regex, "routing_replacement": service.Routing.Replacement, "token_sources": tokenSources,
+			"network_allow": networkAllow, "network_deny": networkDeny,
+			"anonymous_permission": anonymousPermission, "anonymous_object_id": service.Anonymous.ObjectID, "cors": cors,
+		}).Error
+}
+
+// encodeStrings encode a list column, empty when there is none
+func encodeStrings(values []string) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(values)
+	return string(b), err
+}
+
+// decodeNetwork network condition of its list columns
+func decodeNetwork(allow string, deny string) (gokontrol.Network, error) {
+	var network gokontrol.Network
+	if allow != "" {
+		if err := json.Unmarshal([]byte(allow), &network.Allow); err != nil {
+			return network, err
+		}
+	}
+	if deny != "" {
+		if err := json.Unmarshal([]byte(deny), &network.Deny); err != nil {
+			return network, err
+		}
+	}
+	return network, nil
+}
+
+//GetServiceAttachments get default and enforce policy ids of a service whatever the policy status
+func (k *kontrolStorage) GetServiceAttachments(c context.Context, serviceID string) ([]*gokontrol.ServiceAttachment, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var mesh []*servicepolicymesh
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_POLICY_MESH).Where("service_id = ? ", serviceID).Scan(&mesh).Error
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*gokontrol.ServiceAttachment, 0, len(mesh))
+	for _, m := range mesh {
+		rs = append(rs, &gokontrol.ServiceAttachment{ServiceID: m.ServiceID, PolicyID: m.PolicyID, Type: m.Type})
+	}
+	return rs, nil
+}
+
+func (k *kontrolStorage) CreateServiceAttachment(c context.Context, attachment *gokontrol.ServiceAttachment) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	spm := servicepolicymesh{
+		ID:        uuid.NewString(),
+		ServiceID: attachment.ServiceID,
+		PolicyID:  attachment.PolicyID,
+		Type:      attachment.Type,
+	}
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_POLICY_MESH).Create(&spm).Error
+}
+
+func (k *kontrolStorage) DeleteServiceAttachment(c context.Context, attachment *gokontrol.ServiceAttachment) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_POLICY_MESH).
+		Where("service_id = ? AND policy_id = ? AND `type` = ? ", attachment.ServiceID, attachment.PolicyID, attachment.Type).
+		Delete(&servicepolicymesh{}).Error
+}
+
 //GetObjectServiceMesh Get list object service mesh and error (if have)
 func (k *kontrolStorage) GetObjectServiceMesh(c context.Context, objectId string) ([]*gokontrol.ObjectServiceMess, error) {
 	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
 	var rs []*gokontrol.ObjectServiceMess
-	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Where("object_id = ? ", objectId).Find(&rs).Error
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).
+		Where("object_id = ? AND status = ? AND (expires_at = 0 OR expires_at > ?)", objectId, gokontrol.GrantStatus.ENABLE, time.Now().Unix()).
+		Find(&rs).Error
 	return rs, err
 }
+
+//GetServiceGrant get the grant of an object on a service whatever its status
+func (k *kontrolStorage) GetServiceGrant(c context.Context, objectID string, serviceID string) (*gokontrol.ObjectServiceMess, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var rs gokontrol.ObjectServiceMess
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Where("object_id = ? AND service_id = ? ", objectID, serviceID).First(&rs).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, gokontrol.CommonError.NOT_FOUND
+	}
+	return &rs, nil
+}
+
+func (k *kontrolStorage) CreateServiceGrant(c context.Context, grant *gokontrol.ObjectServiceMess) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Create(grant).Error
+}
+
+func (k *kontrolStorage) UpdateServiceGrant(c context.Context, grant *gokontrol.ObjectServiceMess) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Where("id = ?", grant.ID).
+		Updates(map[string]interface{}{"status": grant.Status, "expires_at": grant.ExpiresAt}).Error
+}
+
+func (k *kontrolStorage) DeleteServiceGrant(c context.Context, grant *gokontrol.ObjectServiceMess) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Where("id = ?", grant.ID).Delete(&gokontrol.ObjectServiceMess{}).Error
+}
+
+//GetLapsedServiceGrants get grants expired at timestamp
+func (k *kontrolStorage) GetLapsedServiceGrants(c context.Context, timestamp int64) ([]*gokontrol.ObjectServiceMess, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var rs []*gokontrol.ObjectServiceMess
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Where("expires_at > 0 AND expires_at <= ?", timestamp).Find(&rs).Error
+	return rs, err
+}
+
+//GetServiceGrants get the grants on a service whatever their status
+func (k *kontrolStorage) GetServiceGrants(c context.Context, serviceID string) ([]*gokontrol.ObjectServiceMess, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var rs []*gokontrol.ObjectServiceMess
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_SERVICE_MESH).Where("service_id = ? ", serviceID).Find(&rs).Error
+	return rs, err
+}
+
+//GetOrganizationByID get organization by id
+func (k *kontrolStorage) GetOrganizationByID(c context.Context, id string) (*gokontrol.Organization, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var orgstore organizationStore
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_ORGANIZATIONS).Where("id = ? ", id).First(&orgstore).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, gokontrol.CommonError.NOT_FOUND
+	}
+	return &gokontrol.Organization{
+		ID:     orgstore.ID,
+		Name:   orgstore.Name,
+		Key:    orgstore.Key,
+		Status: orgstore.Status,
+	}, nil
+}
+
+//GetPoliciesByServiceID get every policy of a service whatever its status
+func (k *kontrolStorage) GetPoliciesByServiceID(c context.Context, serviceID string) ([]*gokontrol.Policy, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var policystores []*policystore
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES)).Where("service_id = ? ", serviceID).Find(&policystores).Error
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*gokontrol.Policy, 0, len(policystores))
+	for _, ps := range policystores {
+		policy, err := ps.toPolicy()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, policy)
+	}
+	return rs, nil
+}
+
+//GetServiceAdminRoles get the admin roles of an object on a service
+func (k *kontrolStorage) GetServiceAdminRoles(c context.Context, objectID string, serviceID string) ([]string, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	rs := make([]string, 0)
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_ADMINS).Where("object_id = ? AND service_id = ? ", objectID, serviceID).Pluck("role", &rs).Error
+	return rs, err
+}
+
+//CreateServiceAdmin grant the role, granting an already held role is a no-op
+func (k *kontrolStorage) CreateServiceAdmin(c context.Context, admin *gokontrol.ServiceAdmin) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	sa := serviceadmin{
+		ObjectID:  admin.ObjectID,
+		ServiceID: admin.ServiceID,
+		Role:      admin.Role,
+	}
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_ADMINS).Clauses(clause.OnConflict{DoNothing: true}).Create(&sa).Error
+}
+
+//DeleteServiceAdmin revoke the role
+func (k *kontrolStorage) DeleteServiceAdmin(c context.Context, admin *gokontrol.ServiceAdmin) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SERVICE_ADMINS).
+		Where("object_id = ? AND service_id = ? AND role = ?", admin.ObjectID, admin.ServiceID, admin.Role).
+		Delete(&serviceadmin{}).Error
+}
+
+//CreateElevation grant the policy until the elevation expiry, renewing a running elevation
+func (k *kontrolStorage) CreateElevation(c context.Context, elevation *gokontrol.Elevation) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	opm := objectpolicymesh{
+		ID:        uuid.NewString(),
+		ObjectID:  elevation.ObjectID,
+		PolicyID:  elevation.PolicyID,
+		ExpiresAt: elevation.ExpiresAt,
+		Reason:    elevation.Reason,
+	}
+	return tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at", "reason"}),
+	}).Create(&opm).Error
+}
+
+//GetLapsedElevations get elevations expired at timestamp
+func (k *kontrolStorage) GetLapsedElevations(c context.Context, timestamp int64) ([]*gokontrol.Elevation, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var mesh []*objectpolicymesh
+	err := tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).Where("expires_at > 0 AND expires_at <= ?", timestamp).Find(&mesh).Error
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*gokontrol.Elevation, 0, len(mesh))
+	for _, m := range mesh {
+		rs = append(rs, m.toElevation())
+	}
+	return rs, nil
+}
+
+//DeleteElevation remove the elevation, unless it was renewed or made permanent meanwhile
+func (k *kontrolStorage) DeleteElevation(c context.Context, elevation *gokontrol.Elevation) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_OBJECT_POLICY_MESH).
+		Delete(&objectpolicymesh{}, "object_id = ? AND policy_id = ? AND expires_at = ?", elevation.ObjectID, elevation.PolicyID, elevation.ExpiresAt).Error
+}
+
+//ExpireObject expire the current token of the object
+func (k *kontrolStorage) ExpireObject(c context.Context, objectID string) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_OBJECTS)).Where("id = ?", objectID).Update("expiry_date", 0).Error
+}
+
+//CreateAuditEvent record the event
+func (k *kontrolStorage) CreateAuditEvent(c context.Context, event *gokontrol.AuditEvent) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	ae := auditevent{
+		ID:        event.ID,
+		Type:      event.Type,
+		Actor:     event.Actor,
+		ServiceID: event.ServiceID,
+		Reason:    event.Reason,
+		ClientIP:  event.ClientIP,
+		Detail:    event.Detail,
+	}
+	return tx.WithContext(c).Table(constant.DBTableName.TB_AUDIT_EVENTS).Create(&ae).Error
+}
+
+//CreateSigningKey store the key with its sealed secret
+func (k *kontrolStorage) CreateSigningKey(c context.Context, key *gokontrol.SigningKey) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	sk := signingkey{
+		ID:             key.ID,
+		ObjectID:       key.ObjectID,
+		OrganizationID: key.OrganizationID,
+		SealedSecret:   key.Sealed,
+	}
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SIGNING_KEYS).Create(&sk).Error
+}
+
+//GetSigningKey get the key by id
+func (k *kontrolStorage) GetSigningKey(c context.Context, id string) (*gokontrol.SigningKey, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var sk signingkey
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_SIGNING_KEYS)).Where("id = ?", id).First(&sk).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, gokontrol.CommonError.NOT_FOUND
+	}
+	return &gokontrol.SigningKey{
+		ID:             sk.ID,
+		ObjectID:       sk.ObjectID,
+		OrganizationID: sk.OrganizationID,
+		Sealed:         sk.SealedSecret,
+	}, nil
+}
+
+//DeleteSigningKey remove the key and its nonces
+func (k *kontrolStorage) DeleteSigningKey(c context.Context, id string) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	if err := tx.WithContext(c).Table(constant.DBTableName.TB_SIGNATURE_NONCES).Delete(&signaturenonce{}, "key_id = ?", id).Error; err != nil {
+		return err
+	}
+	return scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_SIGNING_KEYS)).Delete(&signingkey{}, "id = ?", id).Error
+}
+
+//UseSignatureNonce record the nonce of the key, the primary key refuses it a second time
+func (k *kontrolStorage) UseSignatureNonce(c context.Context, keyID string, nonce string, expiresAt int64) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	rs := tx.WithContext(c).Table(constant.DBTableName.TB_SIGNATURE_NONCES).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&signaturenonce{KeyID: keyID, Nonce: nonce, ExpiresAt: expiresAt})
+	if rs.Error != nil {
+		return rs.Error
+	}
+	if rs.RowsAffected == 0 {
+		return gokontrol.CommonError.SIGNATURE_REPLAYED
+	}
+	return nil
+}
+
+//DeleteSignatureNonces remove nonces expired at timestamp
+func (k *kontrolStorage) DeleteSignatureNonces(c context.Context, timestamp int64) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	return tx.WithContext(c).Table(constant.DBTableName.TB_SIGNATURE_NONCES).Delete(&signaturenonce{}, "expires_at <= ?", timestamp).Error
+}
+
+// templateParams json of the parameters of a template instance, empty for plain policies
+func templateParams(params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return "", nil
+	}
+	rs, err := json.Marshal(params)
+	return string(rs), err
+}
+
+func (t *policytemplatestore) toPolicyTemplate() (*gokontrol.PolicyTemplate, error) {
+	tpl := &gokontrol.PolicyTemplate{
+		ID:             t.ID,
+		Name:           t.Name,
+		ServiceID:      t.ServiceID,
+		OrganizationID: t.OrganizationID,
+	}
+	if err := json.Unmarshal([]byte(t.Parameters), &tpl.Parameters); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(t.Permission), &tpl.Permission); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+func fromPolicyTemplate(tpl *gokontrol.PolicyTemplate) (*policytemplatestore, error) {
+	params, err := json.Marshal(tpl.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	perm, err := json.Marshal(tpl.Permission)
+	if err != nil {
+		return nil, err
+	}
+	return &policytemplatestore{
+		ID:             tpl.ID,
+		Name:           tpl.Name,
+		ServiceID:      tpl.ServiceID,
+		OrganizationID: tpl.OrganizationID,
+		Parameters:     string(params),
+		Permission:     string(perm),
+	}, nil
+}
+
+func (k *kontrolStorage) GetPolicyTemplateByID(c context.Context, id string) (*gokontrol.PolicyTemplate, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var tplstore policytemplatestore
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICY_TEMPLATES)).Where("id = ? ", id).First(&tplstore).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, gokontrol.CommonError.NOT_FOUND
+	}
+	return tplstore.toPolicyTemplate()
+}
+
+func (k *kontrolStorage) CreatePolicyTemplate(c context.Context, tpl *gokontrol.PolicyTemplate) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	tplstore, err := fromPolicyTemplate(tpl)
+	if err != nil {
+		return err
+	}
+	return tx.WithContext(c).Table(constant.DBTableName.TB_POLICY_TEMPLATES).Create(tplstore).Error
+}
+
+func (k *kontrolStorage) UpdatePolicyTemplate(c context.Context, tpl *gokontrol.PolicyTemplate) error {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	tplstore, err := fromPolicyTemplate(tpl)
+	if err != nil {
+		return err
+	}
+	return scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICY_TEMPLATES)).Where("id = ?", tpl.ID).Updates(tplstore).Error
+}
+
+//GetPoliciesByTemplateID get every instance of a template whatever its status
+func (k *kontrolStorage) GetPoliciesByTemplateID(c context.Context, templateID string) ([]*gokontrol.Policy, error) {
+	tx := c.Value(constant.ContextKeyTransaction).(*gorm.DB)
+	var policystores []*policystore
+	err := scoped(c, tx.WithContext(c).Table(constant.DBTableName.TB_POLICIES)).Where("template_id = ? ", templateID).Find(&policystores).Error
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*gokontrol.Policy, 0, len(policystores))
+	for _, ps := range policystores {
+		policy, err := ps.toPolicy()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, policy)
+	}
+	return rs, nil
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hungvtc/traefik-integrate/server/constant"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"gorm.io/gorm"
+)
+
+// LeasePolicyWindow lease guarding the policy window job
+const LeasePolicyWindow = "policy-window"
+
+//Scheduler run background jobs on the replica holding the job lease
+type Scheduler struct {
+	s      *wrapper.Service
+	holder string
+}
+
+//NewScheduler scheduler identified by host name, unique per process
+func NewScheduler(s *wrapper.Service) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{s: s, holder: fmt.Sprintf("%s/%s", host, uuid.NewString())}
+}
+
+//Run tick until ctx is done
+func (sc *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(sc.s.Config.Scheduler.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := sc.tick(ctx); err != nil {
+			sc.s.Logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick expire objects of policies crossing their apply range since the last checkpoint, remove lapsed elevations
+// and service grants, in one transaction
+func (sc *Scheduler) tick(ctx context.Context) error {
+	txi, err := sc.s.DB.Transaction()
+	if err != nil {
+		return err
+	}
+	tx := txi.(*gorm.DB)
+	ctx = context.WithValue(ctx, constant.ContextKeyTransaction, tx)
+
+	lease, err := sc.s.Storage.AcquireLease(ctx, LeasePolicyWindow, sc.holder, sc.s.Config.Scheduler.LeaseTTL)
+	if err != nil {
+		tx.Rollback()
+		if err == constant.CommonError.LEASE_HELD {
+			return nil
+		}
+		return err
+	}
+	// commit the lease on its own so other replicas see the holder while the job runs
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	txi, err = sc.s.DB.Transaction()
+	if err != nil {
+		return err
+	}
+	tx = txi.(*gorm.DB)
+	ctx = context.WithValue(ctx, constant.ContextKeyTransaction, tx)
+
+	now := time.Now().Unix()
+	from := lease.Checkpoint
+	if from == 0 {
+		from = now - sc.s.Config.Scheduler.Interval
+	}
+	policyIds, err := sc.s.Kontrol.ExpireObjectsByPolicyWindow(ctx, from, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	elevations, err := sc.s.Kontrol.ExpireElevations(ctx, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	grants, err := sc.s.Kontrol.ExpireServiceGrants(ctx, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := sc.s.Kontrol.ExpireSignatureNonces(ctx, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := sc.s.Storage.SaveLeaseCheckpoint(ctx, LeasePolicyWindow, sc.holder, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(policyIds) > 0 {
+		sc.s.Logger.Info(fmt.Sprintf("expired objects of policies crossing their window: %v", policyIds))
+	}
+	for _, e := range elevations {
+		sc.s.Logger.Info(fmt.Sprintf("elevation of object %s to policy %s lapsed", e.ObjectID, e.PolicyID))
+	}
+	for _, g := range grants {
+		sc.s.Logger.Info(fmt.Sprintf("grant of object %s on service %s lapsed", g.ObjectID, g.ServiceID))
+	}
+	return tx.Commit().Error
+}
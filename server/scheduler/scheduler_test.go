@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/constant"
+	"github.com/hungvtc/traefik-integrate/server/repository"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/neko-neko/echo-logrus/v2/log"
+	"gorm.io/gorm"
+)
+
+// fakeTx connection of a transaction, only its end is recorded
+type fakeTx struct {
+	gorm.ConnPool
+	committed  bool
+	rolledBack bool
+}
+
+func (f *fakeTx) Commit() error {
+	f.committed = true
+	return nil
+}
+
+func (f *fakeTx) Rollback() error {
+	f.rolledBack = true
+	return nil
+}
+
+func (f *fakeTx) db() *gorm.DB {
+	return &gorm.DB{Config: &gorm.Config{}, Statement: &gorm.Statement{ConnPool: f}}
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const interval, ttl = 60, 180
+	failed := errors.New("db down")
+	// expiry jobs of the tick, checking the period starts at from
+	expire := func(kontrol *gokontrol.MockKontrol, from func(int64) int64) {
+		kontrol.EXPECT().ExpireObjectsByPolicyWindow(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, f int64, to int64) ([]string, error) {
+			if f != from(to) {
+				t.Errorf("ExpireObjectsByPolicyWindow() from = %d, want %d", f, from(to))
+			}
+			return []string{"p-1"}, nil
+		})
+		kontrol.EXPECT().ExpireElevations(gomock.Any(), gomock.Any()).Return(nil, nil)
+		kontrol.EXPECT().ExpireServiceGrants(gomock.Any(), gomock.Any()).Return(nil, nil)
+		kontrol.EXPECT().ExpireSignatureNonces(gomock.Any(), gomock.Any()).Return(nil)
+	}
+	tests := []struct {
+		name          string
+		lease         *repository.Lease
+		leaseErr      error
+		jobs          func(kontrol *gokontrol.MockKontrol, storage *repository.MockStorage)
+		wantErr       error
+		wantTxs       int
+		wantCommitted bool // of the job transaction, or of the lease one when the job does not run
+	}{
+		{name: "#1: lease held by another replica --> nothing runs",
+			leaseErr: constant.CommonError.LEASE_HELD, wantTxs: 1},
+		{name: "#2: lease unavailable --> reported",
+			leaseErr: failed, wantErr: failed, wantTxs: 1},
+		{name: "#3: first tick --> period of one interval, checkpoint saved",
+			lease: &repository.Lease{Name: LeasePolicyWindow},
+			jobs: func(kontrol *gokontrol.MockKontrol, storage *repository.MockStorage) {
+				expire(kontrol, func(to int64) int64 { return to - interval })
+				storage.EXPECT().SaveLeaseCheckpoint(gomock.Any(), LeasePolicyWindow, gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantTxs: 2, wantCommitted: true},
+		{name: "#4: lease taken over --> period resumes at the checkpoint",
+			lease: &repository.Lease{Name: LeasePolicyWindow, Checkpoint: 1700000000},
+			jobs: func(kontrol *gokontrol.MockKontrol, storage *repository.MockStorage) {
+				expire(kontrol, func(int64) int64 { return 1700000000 })
+				storage.EXPECT().SaveLeaseCheckpoint(gomock.Any(), LeasePolicyWindow, gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantTxs: 2, wantCommitted: true},
+		{name: "#5: expiry fails --> rolled back, checkpoint kept",
+			lease: &repository.Lease{Name: LeasePolicyWindow, Checkpoint: 1700000000},
+			jobs: func(kontrol *gokontrol.MockKontrol, storage *repository.MockStorage) {
+				kontrol.EXPECT().ExpireObjectsByPolicyWindow(gomock.Any(), int64(1700000000), gomock.Any()).Return(nil, failed)
+			},
+			wantErr: failed, wantTxs: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := repository.NewMockDatabase(ctrl)
+			storage := repository.NewMockStorage(ctrl)
+			kontrol := gokontrol.NewMockKontrol(ctrl)
+			txs := make([]*fakeTx, 0, 2)
+			db.EXPECT().Transaction().DoAndReturn(func() (interface{}, error) {
+				tx := &fakeTx{}
+				txs = append(txs, tx)
+				return tx.db(), nil
+			}).Times(tt.wantTxs)
+			storage.EXPECT().AcquireLease(gomock.Any(), LeasePolicyWindow, gomock.Any(), int64(ttl)).Return(tt.lease, tt.leaseErr)
+			if tt.jobs != nil {
+				tt.jobs(kontrol, storage)
+			}
+
+			sc := NewScheduler(&wrapper.Service{
+				Config:  &config.Config{Scheduler: &config.Scheduler{Enable: true, Interval: interval, LeaseTTL: ttl}},
+				Logger:  log.Logger(),
+				DB:      db,
+				Storage: storage,
+				Kontrol: kontrol,
+			})
+			if err := sc.tick(context.Background()); err != tt.wantErr {
+				t.Fatalf("tick() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			last := txs[len(txs)-1]
+			if last.committed != tt.wantCommitted || last.rolledBack == tt.wantCommitted {
+				t.Errorf("transaction committed = %v, rolled back = %v, want committed %v", last.committed, last.rolledBack, tt.wantCommitted)
+			}
+			if tt.wantTxs == 2 && !txs[0].committed {
+				t.Errorf("lease transaction not committed before the job")
+			}
+		})
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := repository.NewMockDatabase(ctrl)
+	storage := repository.NewMockStorage(ctrl)
+	db.EXPECT().Transaction().DoAndReturn(func() (interface{}, error) {
+		return (&fakeTx{}).db(), nil
+	}).AnyTimes()
+	storage.EXPECT().AcquireLease(gomock.Any(), LeasePolicyWindow, gomock.Any(), gomock.Any()).Return(nil, constant.CommonError.LEASE_HELD).AnyTimes()
+	sc := NewScheduler(&wrapper.Service{
+		Config:  &config.Config{Scheduler: &config.Scheduler{Enable: true, Interval: 3600, LeaseTTL: 7200}},
+		Logger:  log.Logger(),
+		DB:      db,
+		Storage: storage,
+	})
+
+	// a cancelled context stops the loop after the tick in progress
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		sc.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() still ticking after its context was cancelled")
+	}
+}
@@ -0,0 +1,115 @@
+package gokontrol
+
+import (
+	"context"
+	"time"
+)
+
+const adminContextKey contextKey = "kontrol.admin"
+
+//WithAdmin act as the authenticated admin object, management calls are then authorized by its roles
+func WithAdmin(ctx context.Context, admin *Object) context.Context {
+	return context.WithValue(ctx, adminContextKey, admin)
+}
+
+//AdminFromContext admin object ctx acts as, if any
+func AdminFromContext(ctx context.Context) (*Object, bool) {
+	admin, ok := ctx.Value(adminContextKey).(*Object)
+	return admin, ok && admin != nil
+}
+
+//AuthenticateAdmin resolve the admin object of its own token
+func (k DefaultKontrol) AuthenticateAdmin(ctx context.Context, jwtToken string) (*Object, error) {
+	claims, err := k.parseToken(jwtToken)
+	if err != nil {
+		return nil, CommonError.INVALID_TOKEN
+	}
+	admin, err := k.store.GetObjectByToken(ctx, claims.Token, time.Now().Unix())
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if admin == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_TOKEN
+	}
+	if admin.Status != ObjectStatus.ENABLE {
+		return nil, CommonError.INVALID_TOKEN
+	}
+	return admin, nil
+}
+
+//GrantServiceAdmin give an object an admin role on a service, only the service key or organization holders can grant
+func (k DefaultKontrol) GrantServiceAdmin(ctx context.Context, servicekey string, admin *ServiceAdmin) error {
+	service, err := k.adminTarget(ctx, servicekey, admin)
+	if err != nil {
+		return err
+	}
+	obj, err := k.store.GetObjectByID(ctx, admin.ObjectID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if obj == nil || err == CommonError.NOT_FOUND {
+		return CommonError.OBJECT_NOT_FOUND
+	}
+	if obj.OrganizationID != service.OrganizationID {
+		return CommonError.CROSS_TENANT
+	}
+	return k.store.CreateServiceAdmin(ctx, admin)
+}
+
+//RevokeServiceAdmin remove an admin role of an object on a service
+func (k DefaultKontrol) RevokeServiceAdmin(ctx context.Context, servicekey string, admin *ServiceAdmin) error {
+	if _, err := k.adminTarget(ctx, servicekey, admin); err != nil {
+		return err
+	}
+	return k.store.DeleteServiceAdmin(ctx, admin)
+}
+
+//GetServicePolicies list the policies of a service, open to every admin role
+func (k DefaultKontrol) GetServicePolicies(ctx context.Context, servicekey string, serviceID string) ([]*Policy, error) {
+	service, err := k.store.GetServiceByID(ctx, serviceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if service == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_SERVICE
+	}
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.AUDITOR); err != nil {
+		return nil, err
+	}
+	return k.store.GetPoliciesByServiceID(ctx, serviceID)
+}
+
+// adminTarget check the role and the owner credentials of the service the role is on
+func (k DefaultKontrol) adminTarget(ctx context.Context, servicekey string, admin *ServiceAdmin) (*Service, error) {
+	if admin.Role != AdminRole.POLICY_ADMIN && admin.Role != AdminRole.USER_ADMIN && admin.Role != AdminRole.AUDITOR {
+		return nil, CommonError.INVALID_ROLE
+	}
+	service, err := k.store.GetServiceByID(ctx, admin.ServiceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if service == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_SERVICE
+	}
+	if err := k.authorizeService(ctx, service, servicekey, ""); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// authorizeAdmin check the admin holds role on service, any role grants auditor
+func (k DefaultKontrol) authorizeAdmin(ctx context.Context, admin *Object, service *Service, role string) error {
+	if admin.OrganizationID != service.OrganizationID {
+		return CommonError.CROSS_TENANT
+	}
+	roles, err := k.store.GetServiceAdminRoles(ctx, admin.ID, service.ID)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if r == role || role == AdminRole.AUDITOR {
+			return nil
+		}
+	}
+	return CommonError.INSUFFICIENT_ROLE
+}
@@ -0,0 +1,130 @@
+package gokontrol
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+//Empty whether the service allows no request without a token
+func (a AnonymousAccess) Empty() bool {
+	return len(a.Permission) == 0 && a.ObjectID == ""
+}
+
+//ValidateAnonymous check the anonymous keys of a service compile, its principal is checked against the store by
+//the callers
+func (s *Service) ValidateAnonymous() error {
+	for _, key := range s.Anonymous.Permission {
+		if _, err := regexp.Compile(key); err != nil {
+			return CommonError.INVALID_ANONYMOUS
+		}
+	}
+	return nil
+}
+
+// validateAnonymousPrincipal check the anonymous principal of service is one of its objects
+func (k DefaultKontrol) validateAnonymousPrincipal(ctx context.Context, service *Service) error {
+	if service.Anonymous.ObjectID == "" {
+		return nil
+	}
+	object, err := k.store.GetObjectByID(ctx, service.Anonymous.ObjectID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if err == CommonError.NOT_FOUND || object.ServiceID != service.ID {
+		return CommonError.INVALID_ANONYMOUS
+	}
+	return nil
+}
+
+// validateAnonymous principal allowed to make req, which has no token, on reqPath of reqService, nil when the service
+// has none. Denied with MISSING_TOKEN as without anonymous access, decisions of the principal are cached as the ones
+// of tokens
+func (k DefaultKontrol) validateAnonymous(c context.Context, serviceID string, reqService *Service, reqPath string, req *AccessRequest, clientIP string, t time.Time) (*Object, error) {
+	now := t.Unix()
+	key := decisionKey("", serviceID, req.Method, reqPath)
+	if e := k.cache.get(key, now); e != nil {
+		object, _, err := k.allow(e.object, e.service, e.network, e.rateLimit, clientIP, t)
+		return object, err
+	}
+	var object *Object
+	var policies []*Policy
+	expiry := now + k.Option.DefaultTimeout
+	if id := reqService.Anonymous.ObjectID; id != "" {
+		var err error
+		object, err = k.store.GetObjectByID(c, id)
+		if err != nil && err != CommonError.NOT_FOUND {
+			return nil, err
+		}
+		// a principal removed or moved denies as a missing token rather than widening to anyone
+		if err == CommonError.NOT_FOUND || object.ServiceID != reqService.ID || object.Status != ObjectStatus.ENABLE {
+			return nil, CommonError.MISSING_TOKEN
+		}
+		policies, expiry, _ = scheduledPolicies(t, object.ApplyPolicy, expiry, nil)
+	}
+	enforce, expiry, _ := scheduledPolicies(t, reqService.EnforcePolicy, expiry, nil)
+	if !anonymousAllowed(anonymousPermission(reqService, policies, enforce), fmt.Sprintf("%s@%s", req.Method, reqPath)) {
+		return nil, CommonError.MISSING_TOKEN
+	}
+
+	if object == nil {
+		// the network conditions of the enforce policies hold without a principal too
+		if err := checkNetwork(clientIP, reqService, policyNetworks(enforce)); err != nil {
+			return nil, err
+		}
+		// anonymous requests of the service share an object bucket
+		if err := k.limiter.allowObject("anonymous\x00"+reqService.ID, reqService.ID, nil, t); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	network, rateLimit := policyNetworks(policies, enforce), policyRateLimit(policies, enforce)
+	k.cache.putDecision(key, object, reqService, network, rateLimit, expiry, now)
+	object, _, err := k.allow(object, reqService, network, rateLimit, clientIP, t)
+	return object, err
+}
+
+// anonymousPermission keys of the service allowed without a token: its anonymous keys and the ones the policies of
+// the principal grant on it, less the ones the enforce policies deny. The value is whether the key is anchored, the
+// anonymous keys match the whole request while the granted ones match as in tokens
+func anonymousPermission(service *Service, policies []*Policy, enforce []*Policy) map[string]bool {
+	permission := make(map[string]bool, len(service.Anonymous.Permission))
+	for _, key := range service.Anonymous.Permission {
+		permission[key] = true
+	}
+	for _, p := range policies {
+		if p.ServiceID != service.ID {
+			continue
+		}
+		for key, v := range p.Permission {
+			switch v {
+			case PolicyPermission.TRUE:
+				permission[key] = permission[key]
+			case PolicyPermission.FALSE:
+				delete(permission, key)
+			}
+		}
+	}
+	for _, p := range enforce {
+		for key, v := range p.Permission {
+			if v == PolicyPermission.FALSE {
+				delete(permission, key)
+			}
+		}
+	}
+	return permission
+}
+
+// anonymousAllowed whether a key of permission matches request, an anchored key can not match a part of its path
+func anonymousAllowed(permission map[string]bool, request string) bool {
+	for key, anchored := range permission {
+		if anchored {
+			key = "^(?:" + key + ")$"
+		}
+		if match, _ := regexp.MatchString(key, request); match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package gokontrol
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_IdentifyRequest_Anonymous(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	k := NewBasicKontrol(store)
+	principal := &Object{ID: "anon", ServiceID: "sid-b", Status: ObjectStatus.ENABLE,
+		ApplyPolicy: []*Policy{{ID: "catalog", ServiceID: "sid-b", Permission: map[string]int{"GET@/catalog": 1}}}}
+	enforce := []*Policy{{ID: "no-debug", ServiceID: "sid-b", Permission: map[string]int{"GET@/debug": 2}}}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&Service{ID: "sid-b", ServiceID: "orders",
+		Anonymous: AnonymousAccess{Permission: []string{"GET@/healthz", "GET@/debug"}}, EnforcePolicy: enforce}, nil).AnyTimes()
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "shop").Return(&Service{ID: "sid-b", ServiceID: "shop",
+		Anonymous: AnonymousAccess{Permission: []string{"GET@/healthz"}, ObjectID: "anon"}}, nil).AnyTimes()
+	vpnOnly := []*Policy{{ID: "vpn", ServiceID: "sid-c", Network: Network{Allow: []string{"10.8.0.0/16"}}}}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "intranet").Return(&Service{ID: "sid-c", ServiceID: "intranet",
+		Anonymous: AnonymousAccess{Permission: []string{"GET@/healthz"}}, EnforcePolicy: vpnOnly}, nil).AnyTimes()
+	store.EXPECT().GetObjectByID(gomock.Any(), "anon").Return(principal, nil).AnyTimes()
+
+	tests := []struct {
+		name       string
+		path       string
+		clientIP   string
+		header     http.Header
+		wantObject string
+		wantErr    error
+	}{
+		{name: "#1: anonymous key", path: "/orders/healthz"},
+		{name: "#2: other path", path: "/orders/list", wantErr: CommonError.MISSING_TOKEN},
+		{name: "#3: invalid token on an anonymous key", path: "/orders/healthz", header: http.Header{"Authorization": {"Bearer not-a-jwt"}}, wantErr: CommonError.INVALID_TOKEN},
+		{name: "#4: malformed authorization on an anonymous key", path: "/orders/healthz", header: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}}, wantErr: CommonError.INVALID_TOKEN},
+		{name: "#5: denied by an enforce policy", path: "/orders/debug", wantErr: CommonError.MISSING_TOKEN},
+		{name: "#6: principal on an anonymous key", path: "/shop/healthz", wantObject: "anon"},
+		{name: "#7: principal policy", path: "/shop/catalog/42", wantObject: "anon"},
+		{name: "#8: outside the principal permission", path: "/shop/cart", wantErr: CommonError.MISSING_TOKEN},
+		{name: "#9: path extending an anonymous key", path: "/orders/healthz/admin", wantErr: CommonError.MISSING_TOKEN},
+		{name: "#10: path containing an anonymous key", path: "/orders/x/GET@/healthz", wantErr: CommonError.MISSING_TOKEN},
+		{name: "#11: enforce policy network", path: "/intranet/healthz", clientIP: "10.8.1.1"},
+		{name: "#12: outside the enforce policy network", path: "/intranet/healthz", clientIP: "203.0.113.7", wantErr: &NetworkError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := k.IdentifyRequest(context.Background(), &AccessRequest{Method: http.MethodGet, Path: tt.path, ClientIP: tt.clientIP, Header: tt.header})
+			if _, network := tt.wantErr.(*NetworkError); network {
+				if _, ok := err.(*NetworkError); !ok {
+					t.Fatalf("IdentifyRequest() error = %v, want a network denial", err)
+				}
+				return
+			}
+			if err != tt.wantErr {
+				t.Fatalf("IdentifyRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !identity.Anonymous || identity.ObjectID != tt.wantObject {
+				t.Errorf("IdentifyRequest() = %+v, want anonymous object %q", identity, tt.wantObject)
+			}
+			if got := identity.Header().Get(IdentityHeader.ANONYMOUS); got != "true" {
+				t.Errorf("%s = %q, want true", IdentityHeader.ANONYMOUS, got)
+			}
+		})
+	}
+}
+
+func TestService_ValidateAnonymous(t *testing.T) {
+	tests := []struct {
+		name      string
+		anonymous AnonymousAccess
+		wantErr   bool
+	}{
+		{name: "#1: none", anonymous: AnonymousAccess{}},
+		{name: "#2: keys", anonymous: AnonymousAccess{Permission: []string{"GET@/healthz$", "GET@/public/.*"}}},
+		{name: "#3: malformed key", anonymous: AnonymousAccess{Permission: []string{"GET@/public/(.*"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{Anonymous: tt.anonymous}
+			if err := s.ValidateAnonymous(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAnonymous() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
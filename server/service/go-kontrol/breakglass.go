@@ -0,0 +1,120 @@
+package gokontrol
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// breakGlassPermission every path of the service
+const breakGlassPermission = ".*"
+
+//BreakGlass issue a short lived all permissions token on one service to an emergency account.
+//Every attempt, granted or not, is recorded as an audit event. Failed attempts are throttled per client IP
+func (k DefaultKontrol) BreakGlass(ctx context.Context, req *BreakGlassRequest) (*ObjectPermission, error) {
+	event := &AuditEvent{
+		ID:        uuid.NewString(),
+		Type:      AuditEventType.BREAK_GLASS,
+		Actor:     req.Account,
+		ServiceID: req.ServiceID,
+		Reason:    req.Reason,
+		ClientIP:  req.ClientIP,
+	}
+	var perm *ObjectPermission
+	err := k.limiter.allowBreakGlass(req.ClientIP, time.Now())
+	if err == nil {
+		if perm, err = k.breakGlass(ctx, req); err == nil {
+			k.limiter.giveBackBreakGlass(req.ClientIP)
+		}
+	}
+	if err != nil {
+		event.Type = AuditEventType.BREAK_GLASS_DENIED
+		event.Detail = err.Error()
+	}
+	if aerr := k.store.CreateAuditEvent(ctx, event); aerr != nil {
+		return nil, aerr
+	}
+	return perm, err
+}
+
+func (k DefaultKontrol) breakGlass(ctx context.Context, req *BreakGlassRequest) (*ObjectPermission, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, CommonError.REASON_REQUIRED
+	}
+	account := k.breakGlassAccount(req.Account, req.Secret)
+	if account == nil {
+		return nil, CommonError.BREAK_GLASS_DENIED
+	}
+	if len(account.Services) > 0 && !containsString(account.Services, req.ServiceID) {
+		return nil, CommonError.BREAK_GLASS_DENIED
+	}
+	service, err := k.store.GetServiceByID(ctx, req.ServiceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if service == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_SERVICE
+	}
+
+	// the account acts through an object of its own in the service
+	externalID := "break-glass:" + account.Name
+	obj, err := k.store.GetObjectByExternalID(ctx, externalID, service.ID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	create := err == CommonError.NOT_FOUND
+	if create {
+		obj = &Object{
+			ID:             uuid.New().String(),
+			GlobalID:       uuid.New().String(),
+			ExternalID:     externalID,
+			ServiceID:      service.ID,
+			OrganizationID: service.OrganizationID,
+		}
+	}
+	obj.Status = ObjectStatus.ENABLE
+	obj.ApplyPolicy = nil
+	obj.Elevations = nil
+	obj.ExpiryDate = time.Now().Unix() + k.Option.BreakGlassTTL
+
+	all := &Policy{
+		ID:             "break-glass",
+		ServiceID:      service.ID,
+		OrganizationID: service.OrganizationID,
+		Permission:     map[string]int{breakGlassPermission: PolicyPermission.TRUE},
+	}
+	_, sign, jwtToken, err := k.CreateCert(obj, []*Policy{all}, nil, []string{})
+	if err != nil {
+		return nil, err
+	}
+	obj.Token = sign
+	if create {
+		err = k.store.CreateObject(ctx, obj)
+	} else {
+		err = k.updateObjectToken(ctx, obj)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectPermission{
+		ObjectId: obj.ID,
+		Token:    jwtToken,
+	}, nil
+}
+
+// breakGlassAccount account matching name and secret, nil otherwise
+func (k DefaultKontrol) breakGlassAccount(name string, secret string) *BreakGlassAccount {
+	if name == "" || secret == "" {
+		return nil
+	}
+	sealed := []byte(k.serviceKeySign(secret))
+	for _, a := range k.Option.BreakGlass {
+		if a.Name == name && subtle.ConstantTimeCompare(sealed, []byte(a.Secret)) == 1 {
+			return a
+		}
+	}
+	return nil
+}
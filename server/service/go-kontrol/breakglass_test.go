@@ -0,0 +1,125 @@
+package gokontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_BreakGlass(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	option := DefaultKontrolOption
+	option.BreakGlass = []*BreakGlassAccount{
+		{Name: "oncall", Secret: DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("glass")},
+		{Name: "payroll-oncall", Secret: DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("glass"), Services: []string{"payroll"}},
+	}
+	service := &Service{ID: "sid", OrganizationID: "org-a"}
+	audited := func(kontrolStore *MockKontrolStore, eventType string) {
+		kontrolStore.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e *AuditEvent) error {
+			if e.Type != eventType {
+				t.Errorf("CreateAuditEvent() type = %v, want %v", e.Type, eventType)
+			}
+			return nil
+		})
+	}
+	tests := []struct {
+		name    string
+		store   func() KontrolStore
+		req     *BreakGlassRequest
+		wantErr error
+	}{
+		{name: "#1: valid account --> short lived token on a new break glass object",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByExternalID(gomock.Any(), "break-glass:oncall", "sid").Return(nil, CommonError.NOT_FOUND)
+				kontrolStore.EXPECT().CreateObject(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, obj *Object) error {
+					if obj.ExpiryDate > time.Now().Unix()+option.BreakGlassTTL || obj.OrganizationID != "org-a" {
+						t.Errorf("CreateObject() got = %v", obj)
+					}
+					return nil
+				})
+				audited(kontrolStore, AuditEventType.BREAK_GLASS)
+				return kontrolStore
+			},
+			req:     &BreakGlassRequest{Account: "oncall", Secret: "glass", ServiceID: "sid", Reason: "policies locked admins out"},
+			wantErr: nil,
+		},
+		{name: "#2: reason is mandatory, attempt still audited",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				audited(kontrolStore, AuditEventType.BREAK_GLASS_DENIED)
+				return kontrolStore
+			},
+			req:     &BreakGlassRequest{Account: "oncall", Secret: "glass", ServiceID: "sid"},
+			wantErr: CommonError.REASON_REQUIRED,
+		},
+		{name: "#3: wrong secret",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				audited(kontrolStore, AuditEventType.BREAK_GLASS_DENIED)
+				return kontrolStore
+			},
+			req:     &BreakGlassRequest{Account: "oncall", Secret: "rock", ServiceID: "sid", Reason: "outage"},
+			wantErr: CommonError.BREAK_GLASS_DENIED,
+		},
+		{name: "#4: service out of the account services",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				audited(kontrolStore, AuditEventType.BREAK_GLASS_DENIED)
+				return kontrolStore
+			},
+			req:     &BreakGlassRequest{Account: "payroll-oncall", Secret: "glass", ServiceID: "sid", Reason: "outage"},
+			wantErr: CommonError.BREAK_GLASS_DENIED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewKontrol(tt.store(), option)
+			perm, err := k.BreakGlass(context.Background(), tt.req)
+			if err != tt.wantErr {
+				t.Errorf("BreakGlass() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && perm.Token == "" {
+				t.Errorf("BreakGlass() token is empty")
+			}
+		})
+	}
+
+	t.Run("#5: failed attempts throttled per client IP", func(t *testing.T) {
+		kontrolStore := NewMockKontrolStore(ctrl)
+		kontrolStore.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil).AnyTimes()
+		kontrolStore.EXPECT().GetObjectByExternalID(gomock.Any(), "break-glass:oncall", "sid").Return(&Object{ID: "bg", ServiceID: "sid"}, nil).AnyTimes()
+		kontrolStore.EXPECT().UpdateObject(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		throttled := option
+		throttled.RateLimit = RateLimitOption{BreakGlass: RateLimit{Rate: 1.0 / 60, Burst: 2}}
+		k := NewKontrol(kontrolStore, throttled)
+		attempt := func(secret string, clientIP string) error {
+			_, err := k.BreakGlass(context.Background(), &BreakGlassRequest{Account: "oncall", Secret: secret, ServiceID: "sid", Reason: "outage", ClientIP: clientIP})
+			return err
+		}
+		// granted attempts give their token back
+		for i := 0; i < 3; i++ {
+			if err := attempt("glass", "203.0.113.7"); err != nil {
+				t.Fatalf("BreakGlass() error = %v", err)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			if err := attempt("rock", "203.0.113.7"); err != CommonError.BREAK_GLASS_DENIED {
+				t.Fatalf("BreakGlass() error = %v, want %v", err, CommonError.BREAK_GLASS_DENIED)
+			}
+		}
+		if _, ok := attempt("glass", "203.0.113.7").(*RateLimitError); !ok {
+			t.Errorf("BreakGlass() after the failed attempts not throttled")
+		}
+		if err := attempt("glass", "198.51.100.1"); err != nil {
+			t.Errorf("BreakGlass() of another client IP error = %v", err)
+		}
+	})
+}
@@ -13,6 +13,33 @@ type commonerror struct {
 	POLICY_NOT_FOUND     error
 	SERVICE_NOT_FOUND    error
 	MALFORM_PERMISSION   error
+	INVALID_SCHEDULE     error
+	CROSS_TENANT         error
+	INVALID_ORGANIZATION error
+	INVALID_ROLE         error
+	INSUFFICIENT_ROLE    error
+	INVALID_ELEVATION    error
+	BREAK_GLASS_DENIED   error
+	REASON_REQUIRED      error
+	SERVICE_UNRESOLVED   error
+	INVALID_RESOLVER     error
+	INVALID_GRANT        error
+	GRANT_NOT_FOUND      error
+	INVALID_TEMPLATE     error
+	TEMPLATE_NOT_FOUND   error
+	INVALID_PARAMETER    error
+	MISSING_TOKEN        error
+	INVALID_TOKEN_SOURCE error
+	INVALID_RETURN_URL   error
+	RATE_LIMITED         error
+	INVALID_RATE_LIMIT   error
+	NETWORK_DENIED       error
+	INVALID_NETWORK      error
+	INVALID_ANONYMOUS    error
+	INVALID_CORS         error
+	INVALID_SIGNATURE    error
+	SIGNATURE_REPLAYED   error
+	KEY_NOT_FOUND        error
 }
 
 var CommonError = commonerror{
@@ -26,6 +53,33 @@ var CommonError = commonerror{
 	INVALID_POLICY:       errors.New("invalid policy"),
 	INVALID_OBJECT:       errors.New("invalid object"),
 	MALFORM_PERMISSION:   errors.New("policy permission malform"),
+	INVALID_SCHEDULE:     errors.New("invalid policy schedule"),
+	CROSS_TENANT:         errors.New("resource belongs to another organization"),
+	INVALID_ORGANIZATION: errors.New("invalid organization"),
+	INVALID_ROLE:         errors.New("invalid admin role"),
+	INSUFFICIENT_ROLE:    errors.New("admin role does not allow this operation"),
+	INVALID_ELEVATION:    errors.New("invalid elevation"),
+	BREAK_GLASS_DENIED:   errors.New("break glass access denied"),
+	REASON_REQUIRED:      errors.New("reason is mandatory"),
+	SERVICE_UNRESOLVED:   errors.New("no service matches the request"),
+	INVALID_RESOLVER:     errors.New("invalid service resolver settings"),
+	INVALID_GRANT:        errors.New("invalid service grant"),
+	GRANT_NOT_FOUND:      errors.New("service grant not found"),
+	INVALID_TEMPLATE:     errors.New("invalid policy template"),
+	TEMPLATE_NOT_FOUND:   errors.New("policy template not found"),
+	INVALID_PARAMETER:    errors.New("invalid policy template parameter"),
+	MISSING_TOKEN:        errors.New("no token in the request"),
+	INVALID_TOKEN_SOURCE: errors.New("invalid token source"),
+	INVALID_RETURN_URL:   errors.New("invalid or expired return url"),
+	RATE_LIMITED:         errors.New("rate limit exceeded"),
+	INVALID_RATE_LIMIT:   errors.New("invalid rate limit"),
+	NETWORK_DENIED:       errors.New("client network not allowed"),
+	INVALID_NETWORK:      errors.New("invalid network condition"),
+	INVALID_ANONYMOUS:    errors.New("invalid anonymous access"),
+	INVALID_CORS:         errors.New("invalid cors configuration"),
+	INVALID_SIGNATURE:    errors.New("invalid or expired request signature"),
+	SIGNATURE_REPLAYED:   errors.New("request signature nonce already used"),
+	KEY_NOT_FOUND:        errors.New("signing key not found"),
 }
 
 type objectstatus struct {
@@ -49,6 +103,11 @@ var ServiceStatus = objectstatus{
 	ENABLE:  "enable",
 	DISABLE: "disable",
 }
+var OrganizationStatus = objectstatus{
+	INIT:    "",
+	ENABLE:  "enable",
+	DISABLE: "disable",
+}
 
 type objectpolicystatus struct {
 	INIT    string
@@ -64,6 +123,34 @@ var ObjectPolicyStatus = objectpolicystatus{
 	DEFAULT: "default", // set as default settings
 }
 
+var GrantStatus = objectstatus{
+	INIT:    "pending",
+	ENABLE:  "enable",
+	DISABLE: "disable",
+}
+
+type adminrole struct {
+	POLICY_ADMIN string
+	USER_ADMIN   string
+	AUDITOR      string
+}
+
+var AdminRole = adminrole{
+	POLICY_ADMIN: "policy_admin", // manage the service policies
+	USER_ADMIN:   "user_admin",   // manage the service objects
+	AUDITOR:      "auditor",      // read only, implied by the other roles
+}
+
+type auditeventtype struct {
+	BREAK_GLASS        string
+	BREAK_GLASS_DENIED string
+}
+
+var AuditEventType = auditeventtype{
+	BREAK_GLASS:        "break_glass",
+	BREAK_GLASS_DENIED: "break_glass_denied",
+}
+
 type policypermission struct {
 	ANY   int
 	TRUE  int
@@ -75,3 +162,91 @@ var PolicyPermission = policypermission{
 	TRUE:  1,
 	FALSE: 2,
 }
+
+type planaction struct {
+	CREATE string
+	UPDATE string
+	DELETE string
+}
+
+var PlanAction = planaction{
+	CREATE: "create",
+	UPDATE: "update",
+	DELETE: "delete",
+}
+
+type plankind struct {
+	SERVICE string
+	POLICY  string
+	DEFAULT string
+	ENFORCE string
+	GRANT   string
+}
+
+var PlanKind = plankind{
+	SERVICE: "service",
+	POLICY:  "policy",
+	DEFAULT: "default", // attachment of a default policy, same as its service policy mesh type
+	ENFORCE: "enforce", // attachment of an enforce policy
+	GRANT:   "grant",
+}
+
+type lintrule struct {
+	INVALID_PATTERN string
+	INVALID_VALUE   string
+	SHADOWED        string
+	ENFORCE_REMOVED string
+	UNROUTED        string
+	BROAD           string
+}
+
+var LintRule = lintrule{
+	INVALID_PATTERN: "invalid_pattern",
+	INVALID_VALUE:   "invalid_value",
+	SHADOWED:        "shadowed",        // key covered by another key of the policy
+	ENFORCE_REMOVED: "enforce_removed", // grant deleted by an enforce policy of the service
+	UNROUTED:        "unrouted",        // key matching none of the service routes
+	BROAD:           "broad",           // key matching every request
+}
+
+type lintseverity struct {
+	ERROR   string
+	WARNING string
+}
+
+var LintSeverity = lintseverity{
+	ERROR:   "error", // the policy is rejected
+	WARNING: "warning",
+}
+
+type identityheader struct {
+	OBJECT_ID        string
+	GLOBAL_ID        string
+	EXTERNAL_ID      string
+	SERVICE          string
+	ANONYMOUS        string
+	ATTRIBUTE_PREFIX string
+}
+
+var IdentityHeader = identityheader{
+	OBJECT_ID:        "X-Auth-Object-Id",
+	GLOBAL_ID:        "X-Auth-Global-Id",
+	EXTERNAL_ID:      "X-Auth-External-Id",
+	SERVICE:          "X-Auth-Service",
+	ANONYMOUS:        "X-Auth-Anonymous",  // true on requests allowed without a token
+	ATTRIBUTE_PREFIX: "X-Auth-Attribute-", // followed by the attribute name
+}
+
+type tokensourcetype struct {
+	BEARER string
+	COOKIE string
+	HEADER string
+	QUERY  string
+}
+
+var TokenSourceType = tokensourcetype{
+	BEARER: "bearer", // Authorization: Bearer <token>
+	COOKIE: "cookie",
+	HEADER: "header", // raw token in a custom header
+	QUERY:  "query",  // query parameter, only on the listed paths
+}
@@ -4,14 +4,45 @@ import "context"
 
 type Kontrol interface {
 	ValidateToken(c context.Context, token string, reqPath string, reqMethod string) (*Object, error)                                        // validate if token existed, for tighter check, use IssueCertForService
+	IdentifyAccess(c context.Context, token string, req *AccessRequest) (*Identity, error)                                                   // validate as ValidateAccess, identity headers of the object for the requested service
+	IdentifyRequest(c context.Context, req *AccessRequest) (*Identity, error)                                                                // as IdentifyAccess, the token read from req by the token sources of the requested service
+	ValidateAccess(c context.Context, token string, req *AccessRequest) (*Object, error)                                                     // validate token for the request, its service is found by the configured resolver
 	IssueCertForService(ctx context.Context, objID string, externalid string) (*ObjectPermission, error)                                     // get client cert for service to store
 	AddSimpleObjectWithDefaultPolicy(ctx context.Context, externalid string, serviceid string, servicekey string) (*ObjectPermission, error) //service create new object
 	UpdateObject(ctx context.Context, obj *Object, servicekey string) error                                                                  //service update object
 	CreateCert(obj *Object, policy []*Policy, enforce []*Policy, objectExtendServiceIds []string) (*CertForSign, string, string, error)      // internal use, centralise function to issue permission
 	CreatePolicy(ctx context.Context, servicekey string, policy *Policy) error
 	UpdatePolicy(ctx context.Context, servicekey string, policy *Policy) error
-	IssueCertForClient(ctx context.Context, externalID string, serID string) (*ObjectPermission, error) // issue cert for client when login success
-	GetObjectExtendServiceIds(ctx context.Context, objId string) ([]string, error)                      // GET LIST EXTEND SERVICE THAT OBJECT CAN ACCESS
+	IssueCertForClient(ctx context.Context, externalID string, serID string) (*ObjectPermission, error)     // issue cert for client when login success
+	GetObjectExtendServiceIds(ctx context.Context, objId string) ([]string, error)                          // GET LIST EXTEND SERVICE THAT OBJECT CAN ACCESS
+	ExpireObjectsByPolicyWindow(ctx context.Context, from int64, to int64) ([]string, error)                // expire objects of policies opening, closing or changing in (from, to]
+	AuthenticateOrganization(ctx context.Context, organizationID string, key string) (*Organization, error) // check organization admin credentials, use WithOrganization to scope the calls
+	AuthenticateAdmin(ctx context.Context, jwtToken string) (*Object, error)                                // resolve admin object of its token, use WithAdmin to authorize the calls by its roles
+	GrantServiceAdmin(ctx context.Context, servicekey string, admin *ServiceAdmin) error
+	RevokeServiceAdmin(ctx context.Context, servicekey string, admin *ServiceAdmin) error
+	GetServicePolicies(ctx context.Context, servicekey string, serviceID string) ([]*Policy, error)
+	LintPolicies(ctx context.Context, servicekey string, serviceID string) ([]*LintIssue, error)                                                // policy linter findings on every policy of the service
+	ElevateObject(ctx context.Context, policyID string, reason string, duration int64) (*Elevation, error)                                      // user admin authenticated by WithAdmin grants itself an elevatable policy for duration seconds
+	ExpireElevations(ctx context.Context, timestamp int64) ([]*Elevation, error)                                                                // remove elevations lapsed at timestamp and expire the tokens of their objects
+	RequestServiceGrant(ctx context.Context, servicekey string, objectID string, serviceID string, expiresAt int64) (*ObjectServiceMess, error) // object access to another service, pending until approved
+	ApproveServiceGrant(ctx context.Context, servicekey string, objectID string, serviceID string) error                                        // consent of the target service
+	RevokeServiceGrant(ctx context.Context, servicekey string, objectID string, serviceID string) error                                         // remove the grant and expire the object tokens
+	ExpireServiceGrants(ctx context.Context, timestamp int64) ([]*ObjectServiceMess, error)                                                     // remove grants lapsed at timestamp and expire the tokens of their objects
+	CreatePolicyTemplate(ctx context.Context, servicekey string, tpl *PolicyTemplate) error
+	UpdatePolicyTemplate(ctx context.Context, servicekey string, tpl *PolicyTemplate) ([]string, error)                                  // rebind every instance and expire their objects
+	InstantiatePolicyTemplate(ctx context.Context, servicekey string, templateID string, policy *Policy, params map[string]string) error // create policy bound from the template
+	ExportManifest(ctx context.Context) (*Manifest, error)                                                                               // services of the organization ctx is scoped to, every service otherwise
+	PlanManifest(ctx context.Context, m *Manifest) ([]*PlanChange, error)                                                                // changes reconciling the manifest services, nothing is written
+	ApplyManifest(ctx context.Context, m *Manifest) ([]*PlanChange, error)                                                               // plan and make the changes, callers are trusted operators
+	GatewayServices(ctx context.Context) ([]*Service, error)                                                                             // enabled services with an upstream, rendered by the gateway provider
+	BreakGlass(ctx context.Context, req *BreakGlassRequest) (*ObjectPermission, error)                                                   // emergency all permissions token on one service, audited
+	ResolveService(c context.Context, req *AccessRequest) (*Service, error)                                                              // service of the request, as IdentifyRequest finds it
+	SignLoginReturn(r *LoginReturn) string                                                                                               // signature of the return url handed to the login page
+	VerifyLoginReturn(r *LoginReturn, sig string) error                                                                                  // INVALID_RETURN_URL unless signed, unexpired and http(s)
+	OriginCORS(ctx context.Context, origin string) (*CORS, error)                                                                        // CORS the enabled services allow origin, merged, nil if none
+	CreateSigningKey(ctx context.Context, servicekey string, objectID string) (*SigningKey, error)                                       // key a machine client signs its requests with as the object, with its secret
+	RevokeSigningKey(ctx context.Context, servicekey string, keyID string) error                                                         // requests signed with the key are denied from now
+	ExpireSignatureNonces(ctx context.Context, timestamp int64) error                                                                    // forget nonces whose signature date is out of the skew at timestamp
 }
 
 type KontrolStore interface {
@@ -24,7 +55,39 @@ type KontrolStore interface {
 	CreatePolicy(c context.Context, policy *Policy) error
 	UpdatePolicy(c context.Context, policy *Policy) error
 	ExpiredObjectsByPolicy(c context.Context, policyId string) error
+	DeletePolicy(c context.Context, id string) error // remove the policy and its attachments
+	GetPoliciesCrossingWindow(c context.Context, from int64, to int64) ([]*Policy, error)
 	GetServiceByID(c context.Context, id string) (*Service, error)
 	GetServiceByExternalId(c context.Context, externalId string) (*Service, error)
-	GetObjectServiceMesh(c context.Context, objectId string) ([]*ObjectServiceMess, error)
+	GetServices(c context.Context) ([]*Service, error)       // service rows without their policies
+	UpdateService(c context.Context, service *Service) error // name, status, routes, identity attributes, routing and token sources
+	GetServiceAttachments(c context.Context, serviceID string) ([]*ServiceAttachment, error)
+	CreateServiceAttachment(c context.Context, attachment *ServiceAttachment) error
+	DeleteServiceAttachment(c context.Context, attachment *ServiceAttachment) error
+	GetObjectServiceMesh(c context.Context, objectId string) ([]*ObjectServiceMess, error) // approved grants in force
+	GetServiceGrant(c context.Context, objectID string, serviceID string) (*ObjectServiceMess, error)
+	CreateServiceGrant(c context.Context, grant *ObjectServiceMess) error
+	UpdateServiceGrant(c context.Context, grant *ObjectServiceMess) error
+	DeleteServiceGrant(c context.Context, grant *ObjectServiceMess) error
+	GetLapsedServiceGrants(c context.Context, timestamp int64) ([]*ObjectServiceMess, error)
+	GetServiceGrants(c context.Context, serviceID string) ([]*ObjectServiceMess, error) // grants on a service whatever their status
+	GetPolicyTemplateByID(c context.Context, id string) (*PolicyTemplate, error)
+	CreatePolicyTemplate(c context.Context, tpl *PolicyTemplate) error
+	UpdatePolicyTemplate(c context.Context, tpl *PolicyTemplate) error
+	GetPoliciesByTemplateID(c context.Context, templateID string) ([]*Policy, error)
+	GetOrganizationByID(c context.Context, id string) (*Organization, error)
+	GetPoliciesByServiceID(c context.Context, serviceID string) ([]*Policy, error)
+	GetServiceAdminRoles(c context.Context, objectID string, serviceID string) ([]string, error)
+	CreateServiceAdmin(c context.Context, admin *ServiceAdmin) error
+	DeleteServiceAdmin(c context.Context, admin *ServiceAdmin) error
+	CreateElevation(c context.Context, elevation *Elevation) error
+	GetLapsedElevations(c context.Context, timestamp int64) ([]*Elevation, error)
+	DeleteElevation(c context.Context, elevation *Elevation) error
+	ExpireObject(c context.Context, objectID string) error
+	CreateAuditEvent(c context.Context, event *AuditEvent) error
+	CreateSigningKey(c context.Context, key *SigningKey) error
+	GetSigningKey(c context.Context, id string) (*SigningKey, error)
+	DeleteSigningKey(c context.Context, id string) error
+	UseSignatureNonce(c context.Context, keyID string, nonce string, expiresAt int64) error // SIGNATURE_REPLAYED if the key already used it
+	DeleteSignatureNonces(c context.Context, timestamp int64) error                         // nonces expired at timestamp
 }
@@ -0,0 +1,129 @@
+package gokontrol
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// corsToken methods and header names, as the http token grammar
+var corsToken = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+//CORS browser origins allowed to call a service with its tokens, rendered into the Traefik provider and applied by
+//the SSO endpoints
+type CORS struct {
+	AllowOrigins     []string `json:"allow_origins,omitempty" yaml:"allow_origins,omitempty"` // scheme://host[:port], * for any without credentials
+	AllowMethods     []string `json:"allow_methods,omitempty" yaml:"allow_methods,omitempty"` // GET, HEAD and POST if empty
+	AllowHeaders     []string `json:"allow_headers,omitempty" yaml:"allow_headers,omitempty"`
+	ExposeHeaders    []string `json:"expose_headers,omitempty" yaml:"expose_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty" yaml:"allow_credentials,omitempty"`
+	MaxAge           int      `json:"max_age,omitempty" yaml:"max_age,omitempty"` // second preflights are cached, browser default if 0
+}
+
+//Empty whether no origin is allowed
+func (c *CORS) Empty() bool {
+	return c == nil || len(c.AllowOrigins) == 0
+}
+
+//Validate check origins are bare http(s) origins, methods and headers tokens. Browsers refuse credentials with the
+//* origin
+func (c *CORS) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return CommonError.INVALID_CORS
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return CommonError.INVALID_CORS
+		}
+	}
+	for _, names := range [][]string{c.AllowMethods, c.AllowHeaders, c.ExposeHeaders} {
+		for _, name := range names {
+			if !corsToken.MatchString(name) {
+				return CommonError.INVALID_CORS
+			}
+		}
+	}
+	if c.MaxAge < 0 {
+		return CommonError.INVALID_CORS
+	}
+	return nil
+}
+
+//AllowsOrigin whether origin may call the service
+func (c *CORS) AllowsOrigin(origin string) bool {
+	if c.Empty() || origin == "" {
+		return false
+	}
+	for _, o := range c.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+//Methods allowed methods, the CORS safelisted ones if none is set
+func (c *CORS) Methods() []string {
+	if len(c.AllowMethods) == 0 {
+		return []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	return c.AllowMethods
+}
+
+//OriginCORS CORS of the enabled services allowing origin merged, the SSO endpoints serve them all. Nil when none does
+func (k DefaultKontrol) OriginCORS(ctx context.Context, origin string) (*CORS, error) {
+	now := time.Now().Unix()
+	key := corsKey(origin)
+	if e := k.cache.get(key, now); e != nil {
+		return e.cors, nil
+	}
+	services, err := k.store.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var merged *CORS
+	for _, service := range services {
+		if service.Status != ServiceStatus.ENABLE || !service.CORS.AllowsOrigin(origin) {
+			continue
+		}
+		if merged == nil {
+			merged = &CORS{AllowOrigins: []string{origin}}
+		}
+		merged.AllowMethods = appendMissing(merged.AllowMethods, service.CORS.Methods()...)
+		merged.AllowHeaders = appendMissing(merged.AllowHeaders, service.CORS.AllowHeaders...)
+		merged.ExposeHeaders = appendMissing(merged.ExposeHeaders, service.CORS.ExposeHeaders...)
+		merged.AllowCredentials = merged.AllowCredentials || service.CORS.AllowCredentials
+		if service.CORS.MaxAge > 0 && (merged.MaxAge == 0 || service.CORS.MaxAge < merged.MaxAge) {
+			merged.MaxAge = service.CORS.MaxAge
+		}
+	}
+	k.cache.putCORS(key, merged, now)
+	return merged, nil
+}
+
+// appendMissing append the values list lacks, case insensitively as header names and methods
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, l := range list {
+			if strings.EqualFold(l, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
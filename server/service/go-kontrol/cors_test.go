@@ -0,0 +1,70 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestCORS_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    *CORS
+		wantErr bool
+	}{
+		{name: "#1: none", cors: nil},
+		{name: "#2: origins", cors: &CORS{AllowOrigins: []string{"https://app.example.com", "http://localhost:3000"}, AllowMethods: []string{"GET", "PUT"},
+			AllowHeaders: []string{"Authorization", "Content-Type"}, AllowCredentials: true, MaxAge: 600}},
+		{name: "#3: any origin", cors: &CORS{AllowOrigins: []string{"*"}}},
+		{name: "#4: any origin with credentials", cors: &CORS{AllowOrigins: []string{"*"}, AllowCredentials: true}, wantErr: true},
+		{name: "#5: origin with a path", cors: &CORS{AllowOrigins: []string{"https://app.example.com/"}}, wantErr: true},
+		{name: "#6: not http", cors: &CORS{AllowOrigins: []string{"ftp://app.example.com"}}, wantErr: true},
+		{name: "#7: malformed header", cors: &CORS{AllowOrigins: []string{"https://app.example.com"}, AllowHeaders: []string{"X-A, X-B"}}, wantErr: true},
+		{name: "#8: negative max age", cors: &CORS{AllowOrigins: []string{"https://app.example.com"}, MaxAge: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cors.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_OriginCORS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	option := DefaultKontrolOption
+	option.CacheSize, option.CacheTTL = 10, 60
+	k := NewKontrol(store, option)
+	store.EXPECT().GetServices(gomock.Any()).Return([]*Service{
+		{ID: "1", ServiceID: "orders", Status: ServiceStatus.ENABLE, CORS: &CORS{AllowOrigins: []string{"https://app.example.com"},
+			AllowMethods: []string{"GET", "POST"}, AllowHeaders: []string{"Authorization"}, MaxAge: 600}},
+		{ID: "2", ServiceID: "billing", Status: ServiceStatus.ENABLE, CORS: &CORS{AllowOrigins: []string{"https://app.example.com"},
+			AllowMethods: []string{"get", "DELETE"}, AllowHeaders: []string{"authorization", "Content-Type"}, AllowCredentials: true, MaxAge: 60}},
+		{ID: "3", ServiceID: "legacy", Status: ServiceStatus.DISABLE, CORS: &CORS{AllowOrigins: []string{"*"}}},
+		{ID: "4", ServiceID: "idt"},
+	}, nil).Times(2)
+
+	got, err := k.OriginCORS(context.Background(), "https://app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &CORS{AllowOrigins: []string{"https://app.example.com"}, AllowMethods: []string{"GET", "POST", "DELETE"},
+		AllowHeaders: []string{"Authorization", "Content-Type"}, AllowCredentials: true, MaxAge: 60}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("#1: OriginCORS() = %+v, want %+v", got, want)
+	}
+	// cached
+	if again, _ := k.OriginCORS(context.Background(), "https://app.example.com"); !reflect.DeepEqual(again, want) {
+		t.Errorf("#2: OriginCORS() = %+v, want %+v", again, want)
+	}
+	// only allowed by the disabled service
+	if got, err := k.OriginCORS(context.Background(), "https://evil.example.com"); err != nil || got != nil {
+		t.Errorf("#3: OriginCORS() = %+v, %v, want nil", got, err)
+	}
+}
@@ -0,0 +1,201 @@
+package gokontrol
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// decisionCache in-process LRU of the services requests resolve to and of the allowed validation decisions, keyed
+// by token and normalized request. Entries live up to ttl, decisions never past the token or object expiry, and are
+// dropped when their object, the policies or the services change. Each replica has its own, changes made through
+// another one are seen after ttl at worst
+type decisionCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     int64                          // second
+	order   *list.List                     // *decisionEntry, most recently used first
+	entries map[string]*list.Element       // key --> order element
+	objects map[string]map[string]struct{} // object id --> keys of its decisions
+}
+
+// decisionEntry cached service, origin CORS, or allowed decision when object is set
+type decisionEntry struct {
+	key       string
+	object    *Object
+	service   *Service
+	cors      *CORS            // merged CORS of an origin, nil when no service allows it
+	network   []*PolicyNetwork // network conditions of the token policies
+	rateLimit *RateLimit       // object bucket of the token policies
+	expires   int64            // unix second
+}
+
+// newDecisionCache cache of size entries kept ttl seconds, nil, caching nothing, if either is not positive
+func newDecisionCache(size int, ttl int64) *decisionCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &decisionCache{size: size, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}, objects: map[string]map[string]struct{}{}}
+}
+
+// decisionKey key of the decision of jwtToken on reqPath of the service, the token is hashed to bound the key size
+func decisionKey(jwtToken string, serviceID string, method string, reqPath string) string {
+	sum := sha256.Sum256([]byte(jwtToken))
+	return "decision\x00" + hex.EncodeToString(sum[:]) + "\x00" + serviceID + "\x00" + method + "\x00" + reqPath
+}
+
+// serviceKey key of the service a resolver found by its id
+func serviceKey(serviceID string) string {
+	return "service\x00" + serviceID
+}
+
+// corsKey key of the CORS the services allow origin
+func corsKey(origin string) string {
+	return "cors\x00" + origin
+}
+
+// get unexpired entry of key, nil if none
+func (d *decisionCache) get(key string, now int64) *decisionEntry {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	el, ok := d.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*decisionEntry)
+	if e.expires <= now {
+		d.remove(el)
+		return nil
+	}
+	d.order.MoveToFront(el)
+	return e
+}
+
+// putService cache the service of serviceID for ttl
+func (d *decisionCache) putService(serviceID string, service *Service, now int64) {
+	if d == nil {
+		return
+	}
+	d.put(&decisionEntry{key: serviceKey(serviceID), service: service, expires: now + d.ttl})
+}
+
+// putCORS cache the CORS of the origin of key for ttl
+func (d *decisionCache) putCORS(key string, cors *CORS, now int64) {
+	if d == nil {
+		return
+	}
+	d.put(&decisionEntry{key: key, cors: cors, expires: now + d.ttl})
+}
+
+// putDecision cache object allowed on key for ttl, capped at expires, the token expiry
+func (d *decisionCache) putDecision(key string, object *Object, service *Service, network []*PolicyNetwork, rateLimit *RateLimit, expires int64, now int64) {
+	if d == nil {
+		return
+	}
+	if expires <= 0 || expires > now+d.ttl {
+		expires = now + d.ttl
+	}
+	if object.ExpiryDate > 0 && object.ExpiryDate < expires {
+		expires = object.ExpiryDate
+	}
+	if expires <= now {
+		return
+	}
+	d.put(&decisionEntry{key: key, object: object, service: service, network: network, rateLimit: rateLimit, expires: expires})
+}
+
+func (d *decisionCache) put(e *decisionEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if el, ok := d.entries[e.key]; ok {
+		d.remove(el)
+	}
+	d.entries[e.key] = d.order.PushFront(e)
+	if e.object != nil {
+		if d.objects[e.object.ID] == nil {
+			d.objects[e.object.ID] = map[string]struct{}{}
+		}
+		d.objects[e.object.ID][e.key] = struct{}{}
+	}
+	for d.order.Len() > d.size {
+		d.remove(d.order.Back())
+	}
+}
+
+// remove entry of el, the lock is held
+func (d *decisionCache) remove(el *list.Element) {
+	e := d.order.Remove(el).(*decisionEntry)
+	delete(d.entries, e.key)
+	if e.object != nil {
+		delete(d.objects[e.object.ID], e.key)
+		if len(d.objects[e.object.ID]) == 0 {
+			delete(d.objects, e.object.ID)
+		}
+	}
+}
+
+// invalidateObject drop the decisions of the object
+func (d *decisionCache) invalidateObject(objectID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key := range d.objects[objectID] {
+		d.remove(d.entries[key])
+	}
+}
+
+// purge drop every entry, policies and services change the decisions of objects not indexed by them
+func (d *decisionCache) purge() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.order.Init()
+	d.entries = map[string]*list.Element{}
+	d.objects = map[string]map[string]struct{}{}
+}
+
+// len number of entries
+func (d *decisionCache) len() int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len()
+}
+
+// expireObject expire the tokens of the object and drop its cached decisions
+func (k DefaultKontrol) expireObject(ctx context.Context, objectID string) error {
+	if err := k.store.ExpireObject(ctx, objectID); err != nil {
+		return err
+	}
+	k.cache.invalidateObject(objectID)
+	return nil
+}
+
+// updateObjectToken save obj with its new token sign and drop the decisions taken on the previous one
+func (k DefaultKontrol) updateObjectToken(ctx context.Context, obj *Object) error {
+	if err := k.store.UpdateObject(ctx, obj); err != nil {
+		return err
+	}
+	k.cache.invalidateObject(obj.ID)
+	return nil
+}
+
+// expireObjectsByPolicy expire the tokens of the objects of the policy and drop every cached decision
+func (k DefaultKontrol) expireObjectsByPolicy(ctx context.Context, policyID string) error {
+	if err := k.store.ExpiredObjectsByPolicy(ctx, policyID); err != nil {
+		return err
+	}
+	k.cache.purge()
+	return nil
+}
@@ -0,0 +1,140 @@
+package gokontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDecisionCache(t *testing.T) {
+	now := time.Now().Unix()
+	service := &Service{ID: "sid"}
+
+	t.Run("#1: disabled", func(t *testing.T) {
+		d := newDecisionCache(0, 30)
+		d.putDecision("k", &Object{ID: "oid"}, service, nil, nil, 0, now)
+		if d.get("k", now) != nil || d.len() != 0 {
+			t.Errorf("disabled cache kept an entry")
+		}
+	})
+	t.Run("#2: least recently used evicted", func(t *testing.T) {
+		d := newDecisionCache(2, 30)
+		d.putDecision("a", &Object{ID: "oa"}, service, nil, nil, 0, now)
+		d.putDecision("b", &Object{ID: "ob"}, service, nil, nil, 0, now)
+		d.get("a", now)
+		d.putDecision("c", &Object{ID: "oc"}, service, nil, nil, 0, now)
+		if d.get("a", now) == nil || d.get("b", now) != nil || d.get("c", now) == nil || d.len() != 2 {
+			t.Errorf("got a %v b %v c %v len %d, want a and c", d.get("a", now), d.get("b", now), d.get("c", now), d.len())
+		}
+	})
+	t.Run("#3: ttl capped at the token expiry", func(t *testing.T) {
+		d := newDecisionCache(10, 30)
+		d.putDecision("k", &Object{ID: "oid"}, service, nil, nil, now+5, now)
+		if d.get("k", now+4) == nil || d.get("k", now+5) != nil {
+			t.Errorf("decision not expired with its token")
+		}
+		d.putDecision("k", &Object{ID: "oid"}, service, nil, nil, now+3600, now)
+		if d.get("k", now+29) == nil || d.get("k", now+30) != nil {
+			t.Errorf("decision kept past the cache ttl")
+		}
+	})
+	t.Run("#4: ttl capped at the object expiry", func(t *testing.T) {
+		d := newDecisionCache(10, 30)
+		d.putDecision("k", &Object{ID: "oid", ExpiryDate: now + 2}, service, nil, nil, now+3600, now)
+		if d.get("k", now+2) != nil {
+			t.Errorf("decision kept past the object expiry")
+		}
+	})
+	t.Run("#5: invalidate object", func(t *testing.T) {
+		d := newDecisionCache(10, 30)
+		d.putDecision("a1", &Object{ID: "oa"}, service, nil, nil, 0, now)
+		d.putDecision("a2", &Object{ID: "oa"}, service, nil, nil, 0, now)
+		d.putDecision("b", &Object{ID: "ob"}, service, nil, nil, 0, now)
+		d.putService("orders", service, now)
+		d.invalidateObject("oa")
+		if d.get("a1", now) != nil || d.get("a2", now) != nil || d.get("b", now) == nil || d.get(serviceKey("orders"), now) == nil {
+			t.Errorf("invalidateObject dropped the wrong entries")
+		}
+		d.purge()
+		if d.len() != 0 {
+			t.Errorf("purge kept %d entries", d.len())
+		}
+	})
+}
+
+func TestDefaultKontrol_ValidateAccess_DecisionCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	option := DefaultKontrolOption
+	option.CacheSize, option.CacheTTL = 100, 30
+	k := NewKontrol(store, option).(*DefaultKontrol)
+	obj := &Object{ID: "oid", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := k.CreateCert(obj, []*Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	validate := func(method string) error {
+		_, err := k.ValidateAccess(ctx, jwtToken, &AccessRequest{Method: method, Path: "/orders/list"})
+		return err
+	}
+
+	// the service lookup and the token check happen once
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&Service{ID: "sid-b", ServiceID: "orders"}, nil).Times(1)
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).Times(1)
+	for i := 0; i < 3; i++ {
+		if err := validate("GET"); err != nil {
+			t.Fatalf("#1: ValidateAccess() error = %v", err)
+		}
+	}
+
+	// denials are not cached
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		if err := validate("POST"); err != CommonError.INVALID_SERVICE {
+			t.Fatalf("#2: ValidateAccess() error = %v, want %v", err, CommonError.INVALID_SERVICE)
+		}
+	}
+
+	// revoking the object drops its decisions
+	store.EXPECT().ExpireObject(gomock.Any(), "oid").Return(nil)
+	if err := k.expireObject(ctx, "oid"); err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(nil, CommonError.NOT_FOUND).Times(1)
+	if err := validate("GET"); err != CommonError.INVALID_TOKEN {
+		t.Fatalf("#3: ValidateAccess() error = %v, want %v", err, CommonError.INVALID_TOKEN)
+	}
+
+	// policy changes drop everything, the service included
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).Times(1)
+	if err := validate("GET"); err != nil {
+		t.Fatalf("#4: ValidateAccess() error = %v", err)
+	}
+	store.EXPECT().ExpiredObjectsByPolicy(gomock.Any(), "p").Return(nil)
+	if err := k.expireObjectsByPolicy(ctx, "p"); err != nil {
+		t.Fatal(err)
+	}
+	if n := k.cache.len(); n != 0 {
+		t.Errorf("#5: %d entries after a policy change, want 0", n)
+	}
+
+	// a new token for the object drops the decisions of the previous one
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&Service{ID: "sid-b", ServiceID: "orders"}, nil).Times(1)
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).Times(1)
+	if err := validate("GET"); err != nil {
+		t.Fatalf("#6: ValidateAccess() error = %v", err)
+	}
+	store.EXPECT().UpdateObject(gomock.Any(), obj).Return(nil)
+	if err := k.updateObjectToken(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(nil, CommonError.NOT_FOUND).Times(1)
+	if err := validate("GET"); err != CommonError.INVALID_TOKEN {
+		t.Fatalf("#6: ValidateAccess() error = %v, want %v", err, CommonError.INVALID_TOKEN)
+	}
+}
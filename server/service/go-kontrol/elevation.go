@@ -0,0 +1,95 @@
+package gokontrol
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+//ElevateObject grant the object of the context a policy of its service for duration seconds, bounded by the policy max elevation.
+//The object must be a user admin of the service
+func (k DefaultKontrol) ElevateObject(ctx context.Context, policyID string, reason string, duration int64) (*Elevation, error) {
+	obj, ok := AdminFromContext(ctx)
+	if !ok {
+		return nil, CommonError.INVALID_TOKEN
+	}
+	if strings.TrimSpace(reason) == "" || duration <= 0 {
+		return nil, CommonError.INVALID_ELEVATION
+	}
+	policy, err := k.store.GetPolicyByID(ctx, policyID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if policy == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.POLICY_NOT_FOUND
+	}
+	if policy.OrganizationID != obj.OrganizationID {
+		return nil, CommonError.CROSS_TENANT
+	}
+	if policy.ServiceID != obj.ServiceID || duration > policy.MaxElevation {
+		return nil, CommonError.INVALID_ELEVATION
+	}
+	// a policy applied permanently has nothing to elevate
+	for _, p := range obj.ApplyPolicy {
+		if p.ID == policy.ID && !obj.Elevated(p.ID) {
+			return nil, CommonError.INVALID_ELEVATION
+		}
+	}
+	service, err := k.serviceByID(ctx, policy.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.authorizeAdmin(ctx, obj, service, AdminRole.USER_ADMIN); err != nil {
+		return nil, err
+	}
+
+	elevation := &Elevation{
+		ObjectID:  obj.ID,
+		PolicyID:  policy.ID,
+		Reason:    reason,
+		ExpiresAt: time.Now().Unix() + duration,
+	}
+	if err := k.store.CreateElevation(ctx, elevation); err != nil {
+		return nil, err
+	}
+	return elevation, nil
+}
+
+//ExpireElevations remove elevations lapsed at timestamp and expire the tokens of their objects
+func (k DefaultKontrol) ExpireElevations(ctx context.Context, timestamp int64) ([]*Elevation, error) {
+	elevations, err := k.store.GetLapsedElevations(ctx, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*Elevation, 0, len(elevations))
+	for _, e := range elevations {
+		if err := k.store.DeleteElevation(ctx, e); err != nil {
+			return rs, err
+		}
+		if err := k.expireObject(ctx, e.ObjectID); err != nil {
+			return rs, err
+		}
+		rs = append(rs, e)
+	}
+	return rs, nil
+}
+
+//Elevated report if the policy is applied to the object by one of its elevations
+func (o *Object) Elevated(policyID string) bool {
+	for _, e := range o.Elevations {
+		if e.PolicyID == policyID {
+			return true
+		}
+	}
+	return false
+}
+
+// elevationExpiry cap expiry at the closest elevation expiry of obj
+func elevationExpiry(obj *Object, expiry int64) int64 {
+	for _, e := range obj.Elevations {
+		if e.ExpiresAt < expiry {
+			expiry = e.ExpiresAt
+		}
+	}
+	return expiry
+}
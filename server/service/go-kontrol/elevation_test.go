@@ -0,0 +1,160 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_ElevateObject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	prodAdmin := &Policy{ID: "prod-admin", ServiceID: "sid", OrganizationID: "org-a", MaxElevation: 3600}
+	obj := &Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-a"}
+	service := &Service{ID: "sid", OrganizationID: "org-a"}
+	type args struct {
+		ctx      context.Context
+		policyID string
+		reason   string
+		duration int64
+	}
+	tests := []struct {
+		name    string
+		store   func() KontrolStore
+		args    args
+		wantErr error
+	}{
+		{name: "#1: elevation granted until now + duration",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "prod-admin").Return(prodAdmin, nil)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetServiceAdminRoles(gomock.Any(), "obj-1", "sid").Return([]string{AdminRole.USER_ADMIN}, nil)
+				kontrolStore.EXPECT().CreateElevation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e *Elevation) error {
+					if e.ObjectID != "obj-1" || e.Reason != "incident 42" || e.ExpiresAt > time.Now().Unix()+1800 {
+						t.Errorf("CreateElevation() got = %v", e)
+					}
+					return nil
+				})
+				return kontrolStore
+			},
+			args:    args{ctx: WithAdmin(context.Background(), obj), policyID: "prod-admin", reason: "incident 42", duration: 1800},
+			wantErr: nil,
+		},
+		{name: "#2: no authenticated object",
+			store: func() KontrolStore {
+				return NewMockKontrolStore(ctrl)
+			},
+			args:    args{ctx: context.Background(), policyID: "prod-admin", reason: "incident 42", duration: 1800},
+			wantErr: CommonError.INVALID_TOKEN,
+		},
+		{name: "#3: reason is mandatory",
+			store: func() KontrolStore {
+				return NewMockKontrolStore(ctrl)
+			},
+			args:    args{ctx: WithAdmin(context.Background(), obj), policyID: "prod-admin", reason: " ", duration: 1800},
+			wantErr: CommonError.INVALID_ELEVATION,
+		},
+		{name: "#4: longer than the policy max elevation",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "prod-admin").Return(prodAdmin, nil)
+				return kontrolStore
+			},
+			args:    args{ctx: WithAdmin(context.Background(), obj), policyID: "prod-admin", reason: "incident 42", duration: 7200},
+			wantErr: CommonError.INVALID_ELEVATION,
+		},
+		{name: "#5: policy not elevatable",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "view").Return(&Policy{ID: "view", ServiceID: "sid", OrganizationID: "org-a"}, nil)
+				return kontrolStore
+			},
+			args:    args{ctx: WithAdmin(context.Background(), obj), policyID: "view", reason: "incident 42", duration: 60},
+			wantErr: CommonError.INVALID_ELEVATION,
+		},
+		{name: "#6: policy of another service",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "other").Return(&Policy{ID: "other", ServiceID: "other-sid", OrganizationID: "org-a", MaxElevation: 3600}, nil)
+				return kontrolStore
+			},
+			args:    args{ctx: WithAdmin(context.Background(), obj), policyID: "other", reason: "incident 42", duration: 60},
+			wantErr: CommonError.INVALID_ELEVATION,
+		},
+		{name: "#7: policy already applied permanently",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "prod-admin").Return(prodAdmin, nil)
+				return kontrolStore
+			},
+			args:    args{ctx: WithAdmin(context.Background(), &Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-a", ApplyPolicy: []*Policy{prodAdmin}}), policyID: "prod-admin", reason: "incident 42", duration: 60},
+			wantErr: CommonError.INVALID_ELEVATION,
+		},
+		{name: "#8: object without an admin role on the service",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "prod-admin").Return(prodAdmin, nil)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetServiceAdminRoles(gomock.Any(), "obj-1", "sid").Return(nil, nil)
+				return kontrolStore
+			},
+			args:    args{ctx: WithAdmin(context.Background(), obj), policyID: "prod-admin", reason: "incident 42", duration: 60},
+			wantErr: CommonError.INSUFFICIENT_ROLE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewBasicKontrol(tt.store())
+			if _, err := k.ElevateObject(tt.args.ctx, tt.args.policyID, tt.args.reason, tt.args.duration); err != tt.wantErr {
+				t.Errorf("ElevateObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_ExpireElevations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	lapsed := []*Elevation{{ObjectID: "obj-1", PolicyID: "prod-admin", ExpiresAt: 1654000000}}
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetLapsedElevations(gomock.Any(), int64(1654000030)).Return(lapsed, nil)
+	kontrolStore.EXPECT().DeleteElevation(gomock.Any(), lapsed[0]).Return(nil)
+	kontrolStore.EXPECT().ExpireObject(gomock.Any(), "obj-1").Return(nil)
+
+	got, err := NewBasicKontrol(kontrolStore).ExpireElevations(context.Background(), 1654000030)
+	if err != nil {
+		t.Fatalf("ExpireElevations() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, lapsed) {
+		t.Errorf("ExpireElevations() got = %v, want %v", got, lapsed)
+	}
+}
+
+func TestDefaultKontrol_CreateCert_Elevation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now().Unix()
+	prodAdmin := &Policy{ID: "prod-admin", ServiceID: "sid", Permission: map[string]int{"deploy": PolicyPermission.TRUE}}
+	obj := &Object{ID: "obj-1", ServiceID: "sid", ExpiryDate: now + 24*3600,
+		ApplyPolicy: []*Policy{prodAdmin},
+		Elevations:  []*Elevation{{ObjectID: "obj-1", PolicyID: "prod-admin", ExpiresAt: now + 600}},
+	}
+	k := DefaultKontrol{store: NewMockKontrolStore(ctrl), Option: DefaultKontrolOption}
+	cert, _, _, err := k.CreateCert(obj, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateCert() error = %v", err)
+	}
+	if !cert.Permission["sid"]["deploy"] {
+		t.Errorf("CreateCert() permission = %v, want deploy", cert.Permission)
+	}
+	if cert.ExpiryDate != now+600 {
+		t.Errorf("CreateCert() expiry = %v, want capped at elevation expiry %v", cert.ExpiryDate, now+600)
+	}
+}
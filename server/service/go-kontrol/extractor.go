@@ -0,0 +1,102 @@
+package gokontrol
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultTokenSources sources of services without any
+var defaultTokenSources = []*TokenSource{{Type: TokenSourceType.BEARER}}
+
+//BearerToken token of an Authorization header value, the scheme is case insensitive and must be followed by one
+//space and a token without spaces
+func BearerToken(authorization string) (string, bool) {
+	const scheme = "bearer "
+	if len(authorization) <= len(scheme) || !strings.EqualFold(authorization[:len(scheme)], scheme) {
+		return "", false
+	}
+	token := authorization[len(scheme):]
+	if strings.ContainsAny(token, " \t") {
+		return "", false
+	}
+	return token, true
+}
+
+//ExtractToken token of req from the first source holding one, path is the path inside the service. A source
+//holding a malformed token fails rather than falling through, a request with none is MISSING_TOKEN
+func ExtractToken(sources []*TokenSource, req *AccessRequest, path string) (string, error) {
+	if len(sources) == 0 {
+		sources = defaultTokenSources
+	}
+	for _, source := range sources {
+		switch source.Type {
+		case TokenSourceType.BEARER:
+			authorization := req.Header.Get("Authorization")
+			if authorization == "" {
+				continue
+			}
+			token, ok := BearerToken(authorization)
+			if !ok {
+				return "", CommonError.INVALID_TOKEN
+			}
+			return token, nil
+		case TokenSourceType.COOKIE:
+			cookie, err := (&http.Request{Header: req.Header}).Cookie(source.Name)
+			if err == nil && cookie.Value != "" {
+				return cookie.Value, nil
+			}
+		case TokenSourceType.HEADER:
+			if token := strings.TrimSpace(req.Header.Get(source.Name)); token != "" {
+				return token, nil
+			}
+		case TokenSourceType.QUERY:
+			if !hasPathPrefix(path, source.Paths) {
+				continue
+			}
+			query, err := url.ParseQuery(req.Query)
+			if err != nil {
+				return "", CommonError.INVALID_TOKEN
+			}
+			if token := query.Get(source.Name); token != "" {
+				return token, nil
+			}
+		}
+	}
+	return "", CommonError.MISSING_TOKEN
+}
+
+//ValidateTokenSources check the token sources of a service, named sources need a name and query ones paths, as
+//tokens in urls end up in logs
+func (s *Service) ValidateTokenSources() error {
+	for _, source := range s.TokenSources {
+		switch source.Type {
+		case TokenSourceType.BEARER:
+		case TokenSourceType.COOKIE, TokenSourceType.HEADER:
+			if source.Name == "" {
+				return CommonError.INVALID_TOKEN_SOURCE
+			}
+		case TokenSourceType.QUERY:
+			if source.Name == "" || len(source.Paths) == 0 {
+				return CommonError.INVALID_TOKEN_SOURCE
+			}
+			for _, p := range source.Paths {
+				if !strings.HasPrefix(p, "/") {
+					return CommonError.INVALID_TOKEN_SOURCE
+				}
+			}
+		default:
+			return CommonError.INVALID_TOKEN_SOURCE
+		}
+	}
+	return nil
+}
+
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package gokontrol
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+		wantOk        bool
+	}{
+		{name: "#1: bearer", authorization: "Bearer abc.def", want: "abc.def", wantOk: true},
+		{name: "#2: scheme case insensitive", authorization: "bearer abc.def", want: "abc.def", wantOk: true},
+		{name: "#3: no scheme", authorization: "abc.def"},
+		{name: "#4: other scheme", authorization: "Basic YWxpY2U6c2VjcmV0"},
+		{name: "#5: scheme only", authorization: "Bearer "},
+		{name: "#6: space in token", authorization: "Bearer abc def"},
+		{name: "#7: scheme not separated", authorization: "Bearerabc.def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := BearerToken(tt.authorization)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("BearerToken() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestExtractToken(t *testing.T) {
+	sources := []*TokenSource{
+		{Type: TokenSourceType.BEARER},
+		{Type: TokenSourceType.COOKIE, Name: "session"},
+		{Type: TokenSourceType.HEADER, Name: "X-Api-Token"},
+		{Type: TokenSourceType.QUERY, Name: "access_token", Paths: []string{"/download/"}},
+	}
+	tests := []struct {
+		name    string
+		sources []*TokenSource
+		header  http.Header
+		query   string
+		path    string
+		want    string
+		wantErr error
+	}{
+		{name: "#1: bearer by default", header: http.Header{"Authorization": {"Bearer a"}}, want: "a"},
+		{name: "#2: cookie not a default source", header: http.Header{"Cookie": {"session=b"}}, wantErr: CommonError.MISSING_TOKEN},
+		{name: "#3: sources in order", sources: sources, header: http.Header{"Authorization": {"Bearer a"}, "Cookie": {"session=b"}}, want: "a"},
+		{name: "#4: cookie", sources: sources, header: http.Header{"Cookie": {"theme=dark; session=b"}}, want: "b"},
+		{name: "#5: header", sources: sources, header: http.Header{"X-Api-Token": {" c "}}, want: "c"},
+		{name: "#6: query on listed path", sources: sources, query: "access_token=d", path: "/download/report.csv", want: "d"},
+		{name: "#7: query on other path", sources: sources, query: "access_token=d", path: "/list", wantErr: CommonError.MISSING_TOKEN},
+		{name: "#8: malformed bearer does not fall through", sources: sources, header: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}, "Cookie": {"session=b"}}, wantErr: CommonError.INVALID_TOKEN},
+		{name: "#9: none", sources: sources, wantErr: CommonError.MISSING_TOKEN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &AccessRequest{Method: http.MethodGet, Path: "/svc" + tt.path, Query: tt.query, Header: tt.header}
+			if req.Header == nil {
+				req.Header = http.Header{}
+			}
+			got, err := ExtractToken(tt.sources, req, tt.path)
+			if got != tt.want || err != tt.wantErr {
+				t.Errorf("ExtractToken() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_ValidateTokenSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []*TokenSource
+		wantErr bool
+	}{
+		{name: "#1: default", sources: nil},
+		{name: "#2: all sources", sources: []*TokenSource{{Type: TokenSourceType.BEARER}, {Type: TokenSourceType.COOKIE, Name: "session"},
+			{Type: TokenSourceType.HEADER, Name: "X-Api-Token"}, {Type: TokenSourceType.QUERY, Name: "access_token", Paths: []string{"/download/"}}}},
+		{name: "#3: cookie without name", sources: []*TokenSource{{Type: TokenSourceType.COOKIE}}, wantErr: true},
+		{name: "#4: query without paths", sources: []*TokenSource{{Type: TokenSourceType.QUERY, Name: "access_token"}}, wantErr: true},
+		{name: "#5: relative query path", sources: []*TokenSource{{Type: TokenSourceType.QUERY, Name: "access_token", Paths: []string{"download"}}}, wantErr: true},
+		{name: "#6: unknown type", sources: []*TokenSource{{Type: "basic"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{ServiceID: "orders", TokenSources: tt.sources}
+			if err := s.ValidateTokenSources(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTokenSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+//Manifest declarative authorization model, services it does not list are left untouched by apply
+type Manifest struct {
+	Services []*ManifestService `json:"services" yaml:"services"`
+}
+
+//ManifestService service with its policies, attachments and the grants of objects of other services on it.
+//Services are registered with their key out of band, a manifest only updates them
+type ManifestService struct {
+	ID                 string            `json:"id" yaml:"id"`
+	ServiceID          string            `json:"service_id" yaml:"service_id"`
+	Name               string            `json:"name" yaml:"name"`
+	Status             string            `json:"status" yaml:"status"`
+	Default            []string          `json:"default,omitempty" yaml:"default,omitempty"`                         // ids of the default policies
+	Enforce            []string          `json:"enforce,omitempty" yaml:"enforce,omitempty"`                         // ids of the enforce policies
+	Routes             []string          `json:"routes,omitempty" yaml:"routes,omitempty"`                           // request samples the policy linter checks keys against
+	IdentityAttributes []string          `json:"identity_attributes,omitempty" yaml:"identity_attributes,omitempty"` // object attributes forwarded with the identity headers
+	Routing            *ServiceRouting   `json:"routing,omitempty" yaml:"routing,omitempty"`                         // gateway route, not routed if absent
+	TokenSources       []*TokenSource    `json:"token_sources,omitempty" yaml:"token_sources,omitempty"`             // bearer header if absent
+	Network            *Network          `json:"network,omitempty" yaml:"network,omitempty"`                         // client IP conditions, any client if absent
+	Anonymous          *AnonymousAccess  `json:"anonymous,omitempty" yaml:"anonymous,omitempty"`                     // requests allowed without a token, none if absent
+	CORS               *CORS             `json:"cors,omitempty" yaml:"cors,omitempty"`                               // browser origins allowed, none if absent
+	Policies           []*ManifestPolicy `json:"policies,omitempty" yaml:"policies,omitempty"`
+	Grants             []*ManifestGrant  `json:"grants,omitempty" yaml:"grants,omitempty"`
+}
+
+//ManifestPolicy policy of a manifest service, the permission of template instances is informative
+type ManifestPolicy struct {
+	ID           string            `json:"id" yaml:"id"`
+	Name         string            `json:"name" yaml:"name"`
+	Status       string            `json:"status" yaml:"status"`
+	Permission   map[string]int    `json:"permission,omitempty" yaml:"permission,omitempty"`
+	ApplyFrom    int64             `json:"apply_from" yaml:"apply_from"`
+	ApplyTo      int64             `json:"apply_to" yaml:"apply_to"`
+	Schedule     []*PolicySchedule `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	MaxElevation int64             `json:"max_elevation,omitempty" yaml:"max_elevation,omitempty"`
+	TemplateID   string            `json:"template_id,omitempty" yaml:"template_id,omitempty"`
+	Parameters   map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RateLimit    *RateLimit        `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"` // object bucket override, the configured one if absent
+	Network      *Network          `json:"network,omitempty" yaml:"network,omitempty"`       // client IP conditions of its tokens, any client if absent
+}
+
+//ManifestGrant access of an object of another service to the manifest service
+type ManifestGrant struct {
+	ObjectID  string `json:"object_id" yaml:"object_id"`
+	Status    string `json:"status" yaml:"status"`
+	ExpiresAt int64  `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+//PlanChange one create, update or delete apply makes to reach the manifest
+type PlanChange struct {
+	Action    string `json:"action" yaml:"action"`
+	Kind      string `json:"kind" yaml:"kind"`
+	ServiceID string `json:"service_id" yaml:"service_id"`
+	ID        string `json:"id" yaml:"id"` // policy id of policies and attachments, object id of grants
+
+	service *Service
+	policy  *Policy
+	old     *Policy
+	grant   *ObjectServiceMess
+}
+
+// rank order of the changes, policies exist before they are attached and are detached before they are deleted
+func (c *PlanChange) rank() int {
+	switch c.Kind {
+	case PlanKind.SERVICE:
+		return 0
+	case PlanKind.POLICY:
+		if c.Action == PlanAction.DELETE {
+			return 4
+		}
+		return 1
+	case PlanKind.DEFAULT, PlanKind.ENFORCE:
+		if c.Action == PlanAction.DELETE {
+			return 3
+		}
+		return 2
+	}
+	return 5
+}
+
+//ExportManifest dump services, policies, attachments and grants of the organization ctx is scoped to, every one otherwise
+func (k DefaultKontrol) ExportManifest(ctx context.Context) (*Manifest, error) {
+	services, err := k.store.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].ID < services[j].ID })
+	m := &Manifest{Services: make([]*ManifestService, 0, len(services))}
+	for _, service := range services {
+		ms := &ManifestService{ID: service.ID, ServiceID: service.ServiceID, Name: service.Name, Status: service.Status, Routes: service.Routes, IdentityAttributes: service.IdentityAttributes, TokenSources: service.TokenSources, CORS: service.CORS}
+		if service.Routing != (ServiceRouting{}) {
+			routing := service.Routing
+			ms.Routing = &routing
+		}
+		if !service.Network.Empty() {
+			network := service.Network
+			ms.Network = &network
+		}
+		if !service.Anonymous.Empty() {
+			anonymous := service.Anonymous
+			ms.Anonymous = &anonymous
+		}
+		policies, err := k.store.GetPoliciesByServiceID(ctx, service.ID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+		for _, p := range policies {
+			ms.Policies = append(ms.Policies, manifestPolicy(p))
+		}
+		attachments, err := k.store.GetServiceAttachments(ctx, service.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attachments {
+			if a.Type == PlanKind.ENFORCE {
+				ms.Enforce = append(ms.Enforce, a.PolicyID)
+			} else {
+				ms.Default = append(ms.Default, a.PolicyID)
+			}
+		}
+		sort.Strings(ms.Default)
+		sort.Strings(ms.Enforce)
+		grants, err := k.store.GetServiceGrants(ctx, service.ID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(grants, func(i, j int) bool { return grants[i].ObjectID < grants[j].ObjectID })
+		for _, g := range grants {
+			ms.Grants = append(ms.Grants, &ManifestGrant{ObjectID: g.ObjectID, Status: g.Status, ExpiresAt: g.ExpiresAt})
+		}
+		m.Services = append(m.Services, ms)
+	}
+	return m, nil
+}
+
+//PlanManifest changes reconciling the services of the manifest with it, nothing is written
+func (k DefaultKontrol) PlanManifest(ctx context.Context, m *Manifest) ([]*PlanChange, error) {
+	services, err := k.store.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Service, len(services))
+	for _, s := range services {
+		byID[s.ID] = s
+	}
+	// attachments may refer to the policies of any manifest service of the organization
+	owners := make(map[string]*Service)
+	for _, ms := range m.Services {
+		service, ok := byID[ms.ID]
+		if !ok {
+			return nil, CommonError.SERVICE_NOT_FOUND
+		}
+		for _, p := range ms.Policies {
+			if _, ok := owners[p.ID]; ok || p.ID == "" {
+				return nil, CommonError.INVALID_POLICY
+			}
+			owners[p.ID] = service
+		}
+	}
+
+	changes := make([]*PlanChange, 0)
+	planned := make(map[string]bool, len(m.Services))
+	for _, ms := range m.Services {
+		service := byID[ms.ID]
+		if planned[ms.ID] || ms.ServiceID != service.ServiceID {
+			return nil, CommonError.INVALID_SERVICE
+		}
+		planned[ms.ID] = true
+		routing := ServiceRouting{}
+		if ms.Routing != nil {
+			routing = *ms.Routing
+		}
+		network := Network{}
+		if ms.Network != nil {
+			network = *ms.Network
+		}
+		anonymous := AnonymousAccess{}
+		if ms.Anonymous != nil {
+			anonymous = *ms.Anonymous
+		}
+		if ms.Name != service.Name || ms.Status != service.Status || !sameStrings(ms.Routes, service.Routes) ||
+			!sameStrings(ms.IdentityAttributes, service.IdentityAttributes) || routing != service.Routing ||
+			!sameTokenSources(ms.TokenSources, service.TokenSources) || !sameNetwork(network, service.Network) ||
+			!sameAnonymous(anonymous, service.Anonymous) || !reflect.DeepEqual(ms.CORS, service.CORS) {
+			updated := *service
+			updated.Name, updated.Status, updated.Routes, updated.IdentityAttributes = ms.Name, ms.Status, ms.Routes, ms.IdentityAttributes
+			updated.Routing, updated.TokenSources, updated.Network, updated.Anonymous = routing, ms.TokenSources, network, anonymous
+			updated.CORS = ms.CORS
+			if err := updated.ValidateIdentityAttributes(); err != nil {
+				return nil, err
+			}
+			if err := updated.ValidateRouting(); err != nil {
+				return nil, err
+			}
+			if err := updated.ValidateTokenSources(); err != nil {
+				return nil, err
+			}
+			if err := updated.Network.Validate(); err != nil {
+				return nil, err
+			}
+			if err := updated.ValidateAnonymous(); err != nil {
+				return nil, err
+			}
+			if err := updated.CORS.Validate(); err != nil {
+				return nil, err
+			}
+			if err := k.validateAnonymousPrincipal(ctx, &updated); err != nil {
+				return nil, err
+			}
+			changes = append(changes, &PlanChange{Action: PlanAction.UPDATE, Kind: PlanKind.SERVICE, ServiceID: service.ID, ID: service.ID, service: &updated})
+		}
+		cs, err := k.planPolicies(ctx, service, ms)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, cs...)
+		if cs, err = k.planAttachments(ctx, service, ms, owners); err != nil {
+			return nil, err
+		}
+		changes = append(changes, cs...)
+		if cs, err = k.planGrants(ctx, service, ms); err != nil {
+			return nil, err
+		}
+		changes = append(changes, cs...)
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].rank() < changes[j].rank() })
+	return changes, nil
+}
+
+//ApplyManifest plan the manifest and make its changes, each one as the organization of its service
+func (k DefaultKontrol) ApplyManifest(ctx context.Context, m *Manifest) ([]*PlanChange, error) {
+	changes, err := k.PlanManifest(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if err := k.applyChange(WithOrganization(ctx, c.service.OrganizationID), c); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func (k DefaultKontrol) planPolicies(ctx context.Context, service *Service, ms *ManifestService) ([]*PlanChange, error) {
+	existing, err := k.store.GetPoliciesByServiceID(ctx, service.ID)
+	if err != nil {
+		return nil, err
+	}
+	olds := make(map[string]*Policy, len(existing))
+	for _, p := range existing {
+		olds[p.ID] = p
+	}
+	changes := make([]*PlanChange, 0)
+	for _, mp := range ms.Policies {
+		policy := mp.toPolicy(service)
+		if err := policy.ValidateSchedule(); err != nil {
+			return nil, err
+		}
+		if err := policy.RateLimit.Validate(); err != nil {
+			return nil, err
+		}
+		if err := policy.Network.Validate(); err != nil {
+			return nil, err
+		}
+		old, ok := olds[mp.ID]
+		delete(olds, mp.ID)
+		if !ok {
+			changes = append(changes, &PlanChange{Action: PlanAction.CREATE, Kind: PlanKind.POLICY, ServiceID: service.ID, ID: mp.ID, service: service, policy: policy})
+			continue
+		}
+		// the binding of an instance is set when it is created
+		if old.TemplateID != policy.TemplateID || (old.TemplateID != "" && !reflect.DeepEqual(old.Parameters, policy.Parameters)) {
+			return nil, CommonError.INVALID_TEMPLATE
+		}
+		if !samePolicy(old, policy) {
+			changes = append(changes, &PlanChange{Action: PlanAction.UPDATE, Kind: PlanKind.POLICY, ServiceID: service.ID, ID: mp.ID, service: service, policy: policy, old: old})
+		}
+	}
+	for _, old := range existing {
+		if _, ok := olds[old.ID]; ok {
+			changes = append(changes, &PlanChange{Action: PlanAction.DELETE, Kind: PlanKind.POLICY, ServiceID: service.ID, ID: old.ID, service: service, old: old})
+		}
+	}
+	return changes, nil
+}
+
+func (k DefaultKontrol) planAttachments(ctx context.Context, service *Service, ms *ManifestService, owners map[string]*Service) ([]*PlanChange, error) {
+	existing, err := k.store.GetServiceAttachments(ctx, service.ID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[ServiceAttachment]bool, len(existing))
+	for _, a := range existing {
+		current[*a] = true
+	}
+	desired := make([]ServiceAttachment, 0, len(ms.Default)+len(ms.Enforce))
+	for _, id := range ms.Default {
+		desired = append(desired, ServiceAttachment{ServiceID: service.ID, PolicyID: id, Type: PlanKind.DEFAULT})
+	}
+	for _, id := range ms.Enforce {
+		desired = append(desired, ServiceAttachment{ServiceID: service.ID, PolicyID: id, Type: PlanKind.ENFORCE})
+	}
+
+	changes := make([]*PlanChange, 0)
+	for _, a := range desired {
+		owner, ok := owners[a.PolicyID]
+		if !ok {
+			return nil, CommonError.POLICY_NOT_FOUND
+		}
+		if owner.OrganizationID != service.OrganizationID {
+			return nil, CommonError.CROSS_TENANT
+		}
+		if !current[a] {
+			changes = append(changes, &PlanChange{Action: PlanAction.CREATE, Kind: a.Type, ServiceID: service.ID, ID: a.PolicyID, service: service})
+		}
+		delete(current, a)
+	}
+	for _, a := range existing {
+		if current[*a] {
+			changes = append(changes, &PlanChange{Action: PlanAction.DELETE, Kind: a.Type, ServiceID: service.ID, ID: a.PolicyID, service: service})
+		}
+	}
+	return changes, nil
+}
+
+func (k DefaultKontrol) planGrants(ctx context.Context, service *Service, ms *ManifestService) ([]*PlanChange, error) {
+	existing, err := k.store.GetServiceGrants(ctx, service.ID)
+	if err != nil {
+		return nil, err
+	}
+	olds := make(map[string]*ObjectServiceMess, len(existing))
+	for _, g := range existing {
+		olds[g.ObjectID] = g
+	}
+	changes := make([]*PlanChange, 0)
+	planned := make(map[string]bool, len(ms.Grants))
+	for _, mg := range ms.Grants {
+		if planned[mg.ObjectID] || (mg.Status != GrantStatus.INIT && mg.Status != GrantStatus.ENABLE && mg.Status != GrantStatus.DISABLE) {
+			return nil, CommonError.INVALID_GRANT
+		}
+		planned[mg.ObjectID] = true
+		grant := &ObjectServiceMess{ServiceID: service.ID, ObjectID: mg.ObjectID, Status: mg.Status, ExpiresAt: mg.ExpiresAt}
+		old, ok := olds[mg.ObjectID]
+		if !ok {
+			grant.ID = uuid.NewString()
+			changes = append(changes, &PlanChange{Action: PlanAction.CREATE, Kind: PlanKind.GRANT, ServiceID: service.ID, ID: mg.ObjectID, service: service, grant: grant})
+			continue
+		}
+		if old.Status != grant.Status || old.ExpiresAt != grant.ExpiresAt {
+			grant.ID = old.ID
+			changes = append(changes, &PlanChange{Action: PlanAction.UPDATE, Kind: PlanKind.GRANT, ServiceID: service.ID, ID: mg.ObjectID, service: service, grant: grant})
+		}
+	}
+	for _, old := range existing {
+		if !planned[old.ObjectID] {
+			changes = append(changes, &PlanChange{Action: PlanAction.DELETE, Kind: PlanKind.GRANT, ServiceID: service.ID, ID: old.ObjectID, service: service, grant: old})
+		}
+	}
+	return changes, nil
+}
+
+// applyChange make one change of the plan, ctx is scoped to the organization of the change service
+func (k DefaultKontrol) applyChange(ctx context.Context, c *PlanChange) error {
+	switch c.Kind {
+	case PlanKind.SERVICE:
+		if err := k.store.UpdateService(ctx, c.service); err != nil {
+			return err
+		}
+		k.cache.purge()
+		return nil
+	case PlanKind.POLICY:
+		switch c.Action {
+		case PlanAction.CREATE:
+			if c.policy.TemplateID != "" {
+				return k.InstantiatePolicyTemplate(ctx, "", c.policy.TemplateID, c.policy, c.policy.Parameters)
+			}
+			return k.CreatePolicy(ctx, "", c.policy)
+		case PlanAction.UPDATE:
+			return k.updatePolicy(ctx, c.service, c.old, c.policy)
+		}
+		if err := k.expireObjectsByPolicy(ctx, c.ID); err != nil {
+			return err
+		}
+		return k.store.DeletePolicy(ctx, c.ID)
+	case PlanKind.DEFAULT, PlanKind.ENFORCE:
+		attachment := &ServiceAttachment{ServiceID: c.ServiceID, PolicyID: c.ID, Type: c.Kind}
+		if c.Action == PlanAction.CREATE {
+			if err := k.store.CreateServiceAttachment(ctx, attachment); err != nil {
+				return err
+			}
+			return k.expireObjectsByPolicy(ctx, c.ID)
+		}
+		if err := k.expireObjectsByPolicy(ctx, c.ID); err != nil {
+			return err
+		}
+		return k.store.DeleteServiceAttachment(ctx, attachment)
+	}
+	switch c.Action {
+	case PlanAction.CREATE:
+		obj, err := k.store.GetObjectByID(ctx, c.ID)
+		if err != nil && err != CommonError.NOT_FOUND {
+			return err
+		}
+		if obj == nil || err == CommonError.NOT_FOUND {
+			return CommonError.OBJECT_NOT_FOUND
+		}
+		if obj.ServiceID == c.ServiceID {
+			return CommonError.INVALID_GRANT
+		}
+		return k.store.CreateServiceGrant(ctx, c.grant)
+	case PlanAction.UPDATE:
+		if err := k.store.UpdateServiceGrant(ctx, c.grant); err != nil {
+			return err
+		}
+	default:
+		if err := k.store.DeleteServiceGrant(ctx, c.grant); err != nil {
+			return err
+		}
+	}
+	return k.expireObject(ctx, c.ID)
+}
+
+func (p *ManifestPolicy) toPolicy(service *Service) *Policy {
+	policy := &Policy{
+		ID:             p.ID,
+		Name:           p.Name,
+		ServiceID:      service.ID,
+		OrganizationID: service.OrganizationID,
+		Permission:     p.Permission,
+		Status:         p.Status,
+		ApplyFrom:      p.ApplyFrom,
+		ApplyTo:        p.ApplyTo,
+		Schedule:       p.Schedule,
+		MaxElevation:   p.MaxElevation,
+		TemplateID:     p.TemplateID,
+		Parameters:     p.Parameters,
+	}
+	if p.RateLimit != nil {
+		policy.RateLimit = *p.RateLimit
+	}
+	if p.Network != nil {
+		policy.Network = *p.Network
+	}
+	return policy
+}
+
+func manifestPolicy(p *Policy) *ManifestPolicy {
+	mp := &ManifestPolicy{
+		ID:           p.ID,
+		Name:         p.Name,
+		Status:       p.Status,
+		Permission:   p.Permission,
+		ApplyFrom:    p.ApplyFrom,
+		ApplyTo:      p.ApplyTo,
+		Schedule:     p.Schedule,
+		MaxElevation: p.MaxElevation,
+		TemplateID:   p.TemplateID,
+		Parameters:   p.Parameters,
+	}
+	if p.RateLimit != (RateLimit{}) {
+		limit := p.RateLimit
+		mp.RateLimit = &limit
+	}
+	if !p.Network.Empty() {
+		network := p.Network
+		mp.Network = &network
+	}
+	return mp
+}
+
+// samePolicy compare the fields a manifest sets, the permission of instances comes from their template
+func samePolicy(old *Policy, policy *Policy) bool {
+	if old.Name != policy.Name || old.Status != policy.Status || old.ApplyFrom != policy.ApplyFrom ||
+		old.ApplyTo != policy.ApplyTo || old.MaxElevation != policy.MaxElevation || old.RateLimit != policy.RateLimit ||
+		!sameNetwork(old.Network, policy.Network) {
+		return false
+	}
+	if len(old.Schedule) != len(policy.Schedule) || (len(old.Schedule) > 0 && !reflect.DeepEqual(old.Schedule, policy.Schedule)) {
+		return false
+	}
+	if old.TemplateID != "" {
+		return true
+	}
+	return len(old.Permission) == len(policy.Permission) && (len(old.Permission) == 0 || reflect.DeepEqual(old.Permission, policy.Permission))
+}
+
+func sameStrings(a []string, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func sameTokenSources(a []*TokenSource, b []*TokenSource) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func sameNetwork(a Network, b Network) bool {
+	return sameStrings(a.Allow, b.Allow) && sameStrings(a.Deny, b.Deny)
+}
+
+func sameAnonymous(a AnonymousAccess, b AnonymousAccess) bool {
+	return sameStrings(a.Permission, b.Permission) && a.ObjectID == b.ObjectID
+}
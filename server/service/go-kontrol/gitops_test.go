@@ -0,0 +1,117 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_PlanManifest(t *testing.T) {
+	service := &Service{ID: "sid", ServiceID: "ext-sid", OrganizationID: "org-a", Name: "orders", Status: ServiceStatus.ENABLE}
+	other := &Service{ID: "other", ServiceID: "ext-other", OrganizationID: "org-b", Name: "billing", Status: ServiceStatus.ENABLE}
+	read := &Policy{ID: "read", Name: "read", ServiceID: "sid", OrganizationID: "org-a", Status: "enable", Permission: map[string]int{"GET@/.*": 1}, ApplyTo: 100}
+	write := &Policy{ID: "write", Name: "write", ServiceID: "sid", OrganizationID: "org-a", Status: "enable", Permission: map[string]int{"POST@/.*": 1}, ApplyTo: 100}
+	tests := []struct {
+		name     string
+		manifest *Manifest
+		want     []string
+		wantErr  error
+	}{
+		{name: "#1: in sync",
+			manifest: &Manifest{Services: []*ManifestService{{ID: "sid", ServiceID: "ext-sid", Name: "orders", Status: "enable", Default: []string{"read"},
+				Policies: []*ManifestPolicy{manifestPolicy(read), manifestPolicy(write)},
+				Grants:   []*ManifestGrant{{ObjectID: "o-1", Status: GrantStatus.ENABLE}}}}},
+			want: []string{},
+		},
+		{name: "#2: changes ordered by kind",
+			manifest: &Manifest{Services: []*ManifestService{{ID: "sid", ServiceID: "ext-sid", Name: "orders v2", Status: "enable", Enforce: []string{"admin"},
+				Policies: []*ManifestPolicy{
+					{ID: "read", Name: "read", Status: "enable", Permission: map[string]int{"(GET|HEAD)@/.*": 1}, ApplyTo: 100},
+					{ID: "admin", Name: "admin", Status: "enable", Permission: map[string]int{".*": 1}, ApplyTo: 100},
+				},
+				Grants: []*ManifestGrant{{ObjectID: "o-1", Status: GrantStatus.DISABLE}, {ObjectID: "o-2", Status: GrantStatus.INIT}}}}},
+			want: []string{"update service sid", "update policy read", "create policy admin", "create enforce admin", "delete default read", "delete policy write", "update grant o-1", "create grant o-2"},
+		},
+		{name: "#3: unknown service", manifest: &Manifest{Services: []*ManifestService{{ID: "missing"}}}, wantErr: CommonError.SERVICE_NOT_FOUND},
+		{name: "#4: attached policy not declared",
+			manifest: &Manifest{Services: []*ManifestService{{ID: "sid", ServiceID: "ext-sid", Name: "orders", Status: "enable", Default: []string{"unknown"}}}},
+			wantErr:  CommonError.POLICY_NOT_FOUND,
+		},
+		{name: "#5: policy of another organization attached",
+			manifest: &Manifest{Services: []*ManifestService{
+				{ID: "sid", ServiceID: "ext-sid", Name: "orders", Status: "enable", Default: []string{"invoice"}},
+				{ID: "other", ServiceID: "ext-other", Name: "billing", Status: "enable", Policies: []*ManifestPolicy{{ID: "invoice", Permission: map[string]int{".*": 1}}}},
+			}},
+			wantErr: CommonError.CROSS_TENANT,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			kontrolStore := NewMockKontrolStore(ctrl)
+			kontrolStore.EXPECT().GetServices(gomock.Any()).Return([]*Service{service, other}, nil)
+			kontrolStore.EXPECT().GetPoliciesByServiceID(gomock.Any(), "sid").Return([]*Policy{read, write}, nil).AnyTimes()
+			kontrolStore.EXPECT().GetPoliciesByServiceID(gomock.Any(), "other").Return([]*Policy{}, nil).AnyTimes()
+			kontrolStore.EXPECT().GetServiceAttachments(gomock.Any(), "sid").Return([]*ServiceAttachment{{ServiceID: "sid", PolicyID: "read", Type: PlanKind.DEFAULT}}, nil).AnyTimes()
+			kontrolStore.EXPECT().GetServiceGrants(gomock.Any(), "sid").Return([]*ObjectServiceMess{{ID: "g-1", ServiceID: "sid", ObjectID: "o-1", Status: GrantStatus.ENABLE}}, nil).AnyTimes()
+
+			k := NewBasicKontrol(kontrolStore)
+			changes, err := k.PlanManifest(context.Background(), tt.manifest)
+			if err != tt.wantErr {
+				t.Fatalf("PlanManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := make([]string, 0, len(changes))
+			for _, c := range changes {
+				got = append(got, c.Action+" "+c.Kind+" "+c.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanManifest() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_ApplyManifest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := &Service{ID: "sid", ServiceID: "ext-sid", OrganizationID: "org-a", Name: "orders", Status: ServiceStatus.ENABLE}
+	old := &Policy{ID: "old", Name: "old", ServiceID: "sid", OrganizationID: "org-a", Status: "enable", Permission: map[string]int{".*": 1}}
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetServices(gomock.Any()).Return([]*Service{service}, nil)
+	kontrolStore.EXPECT().GetPoliciesByServiceID(gomock.Any(), "sid").Return([]*Policy{old}, nil)
+	kontrolStore.EXPECT().GetServiceAttachments(gomock.Any(), "sid").Return([]*ServiceAttachment{{ServiceID: "sid", PolicyID: "old", Type: PlanKind.DEFAULT}}, nil)
+	kontrolStore.EXPECT().GetServiceGrants(gomock.Any(), "sid").Return([]*ObjectServiceMess{}, nil)
+
+	created := kontrolStore.EXPECT().CreatePolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(c context.Context, p *Policy) error {
+		if orgID, _ := OrganizationFromContext(c); orgID != "org-a" || p.ID != "read" || p.OrganizationID != "org-a" {
+			t.Errorf("CreatePolicy() got = %+v in organization %s", p, orgID)
+		}
+		return nil
+	})
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+	kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "read").Return(nil, CommonError.NOT_FOUND)
+	attached := kontrolStore.EXPECT().CreateServiceAttachment(gomock.Any(), &ServiceAttachment{ServiceID: "sid", PolicyID: "read", Type: PlanKind.DEFAULT}).Return(nil).After(created)
+	kontrolStore.EXPECT().ExpiredObjectsByPolicy(gomock.Any(), "read").Return(nil).After(attached)
+	detached := kontrolStore.EXPECT().DeleteServiceAttachment(gomock.Any(), &ServiceAttachment{ServiceID: "sid", PolicyID: "old", Type: PlanKind.DEFAULT}).Return(nil).After(attached)
+	kontrolStore.EXPECT().ExpiredObjectsByPolicy(gomock.Any(), "old").Return(nil).Times(2)
+	kontrolStore.EXPECT().DeletePolicy(gomock.Any(), "old").Return(nil).After(detached)
+
+	k := NewBasicKontrol(kontrolStore)
+	changes, err := k.ApplyManifest(context.Background(), &Manifest{Services: []*ManifestService{{
+		ID: "sid", ServiceID: "ext-sid", Name: "orders", Status: "enable", Default: []string{"read"},
+		Policies: []*ManifestPolicy{{ID: "read", Name: "read", Status: "enable", Permission: map[string]int{"GET@/.*": 1}}},
+	}}})
+	if err != nil {
+		t.Fatalf("ApplyManifest() error = %v", err)
+	}
+	if len(changes) != 4 {
+		t.Errorf("ApplyManifest() got %d changes, want 4", len(changes))
+	}
+}
@@ -0,0 +1,144 @@
+package gokontrol
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//RequestServiceGrant ask access of an object to another service, authorized on the object service.
+//The grant stays pending until ApproveServiceGrant
+func (k DefaultKontrol) RequestServiceGrant(ctx context.Context, servicekey string, objectID string, serviceID string, expiresAt int64) (*ObjectServiceMess, error) {
+	if expiresAt != 0 && expiresAt <= time.Now().Unix() {
+		return nil, CommonError.INVALID_GRANT
+	}
+	obj, err := k.store.GetObjectByID(ctx, objectID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if obj == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.OBJECT_NOT_FOUND
+	}
+	if obj.ServiceID == serviceID {
+		return nil, CommonError.INVALID_GRANT
+	}
+	home, err := k.serviceByID(ctx, obj.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.authorizeService(ctx, home, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return nil, err
+	}
+	target, err := k.serviceByID(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if target.OrganizationID != obj.OrganizationID {
+		return nil, CommonError.CROSS_TENANT
+	}
+
+	old, err := k.store.GetServiceGrant(ctx, objectID, serviceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if old != nil {
+		return nil, CommonError.INVALID_GRANT
+	}
+	grant := &ObjectServiceMess{
+		ID:        uuid.NewString(),
+		ObjectID:  objectID,
+		ServiceID: serviceID,
+		Status:    GrantStatus.INIT,
+		ExpiresAt: expiresAt,
+	}
+	if err := k.store.CreateServiceGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+//ApproveServiceGrant consent of the target service admin, the object gets the access on its next token
+func (k DefaultKontrol) ApproveServiceGrant(ctx context.Context, servicekey string, objectID string, serviceID string) error {
+	target, err := k.serviceByID(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	if err := k.authorizeService(ctx, target, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return err
+	}
+	grant, err := k.store.GetServiceGrant(ctx, objectID, serviceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if grant == nil || err == CommonError.NOT_FOUND {
+		return CommonError.GRANT_NOT_FOUND
+	}
+	if grant.Status != GrantStatus.INIT {
+		return CommonError.INVALID_GRANT
+	}
+	grant.Status = GrantStatus.ENABLE
+	return k.store.UpdateServiceGrant(ctx, grant)
+}
+
+//RevokeServiceGrant remove the grant and expire the object tokens, authorized on the target service
+func (k DefaultKontrol) RevokeServiceGrant(ctx context.Context, servicekey string, objectID string, serviceID string) error {
+	target, err := k.serviceByID(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	if err := k.authorizeService(ctx, target, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return err
+	}
+	grant, err := k.store.GetServiceGrant(ctx, objectID, serviceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if grant == nil || err == CommonError.NOT_FOUND {
+		return CommonError.GRANT_NOT_FOUND
+	}
+	if err := k.store.DeleteServiceGrant(ctx, grant); err != nil {
+		return err
+	}
+	return k.expireObject(ctx, objectID)
+}
+
+//ExpireServiceGrants remove grants lapsed at timestamp and expire the tokens of their objects
+func (k DefaultKontrol) ExpireServiceGrants(ctx context.Context, timestamp int64) ([]*ObjectServiceMess, error) {
+	grants, err := k.store.GetLapsedServiceGrants(ctx, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]*ObjectServiceMess, 0, len(grants))
+	for _, g := range grants {
+		if err := k.store.DeleteServiceGrant(ctx, g); err != nil {
+			return rs, err
+		}
+		if err := k.expireObject(ctx, g.ObjectID); err != nil {
+			return rs, err
+		}
+		rs = append(rs, g)
+	}
+	return rs, nil
+}
+
+func (k DefaultKontrol) serviceByID(ctx context.Context, serviceID string) (*Service, error) {
+	service, err := k.store.GetServiceByID(ctx, serviceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if service == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_SERVICE
+	}
+	return service, nil
+}
+
+// grantExpiry cap expiry at the closest grant expiry
+func grantExpiry(grants []*ObjectServiceMess, expiry int64) int64 {
+	for _, g := range grants {
+		if g.ExpiresAt > 0 && g.ExpiresAt < expiry {
+			expiry = g.ExpiresAt
+		}
+	}
+	return expiry
+}
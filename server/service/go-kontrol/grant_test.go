@@ -0,0 +1,190 @@
+package gokontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_ServiceGrant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sign := DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign
+	home := &Service{ID: "home", OrganizationID: "org-a", Key: sign("home-key")}
+	target := &Service{ID: "target", OrganizationID: "org-a", Key: sign("target-key")}
+	foreign := &Service{ID: "foreign", OrganizationID: "org-b", Key: sign("foreign-key")}
+	obj := &Object{ID: "obj-1", ServiceID: "home", OrganizationID: "org-a"}
+	services := func(kontrolStore *MockKontrolStore) {
+		kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "home").Return(home, nil).AnyTimes()
+		kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "target").Return(target, nil).AnyTimes()
+		kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "foreign").Return(foreign, nil).AnyTimes()
+		kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(obj, nil).AnyTimes()
+	}
+	tests := []struct {
+		name    string
+		store   func() KontrolStore
+		call    func(k Kontrol) error
+		wantErr error
+	}{
+		{name: "#1: home service requests a pending grant",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				services(kontrolStore)
+				kontrolStore.EXPECT().GetServiceGrant(gomock.Any(), "obj-1", "target").Return(nil, CommonError.NOT_FOUND)
+				kontrolStore.EXPECT().CreateServiceGrant(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, g *ObjectServiceMess) error {
+					if g.Status != GrantStatus.INIT {
+						t.Errorf("CreateServiceGrant() status = %v, want pending", g.Status)
+					}
+					return nil
+				})
+				return kontrolStore
+			},
+			call: func(k Kontrol) error {
+				_, err := k.RequestServiceGrant(context.Background(), "home-key", "obj-1", "target", time.Now().Unix()+3600)
+				return err
+			},
+			wantErr: nil,
+		},
+		{name: "#2: request with the target key only --> invalid token",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				services(kontrolStore)
+				return kontrolStore
+			},
+			call: func(k Kontrol) error {
+				_, err := k.RequestServiceGrant(context.Background(), "target-key", "obj-1", "target", 0)
+				return err
+			},
+			wantErr: CommonError.INVALID_TOKEN,
+		},
+		{name: "#3: grant on a service of another organization",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				services(kontrolStore)
+				return kontrolStore
+			},
+			call: func(k Kontrol) error {
+				_, err := k.RequestServiceGrant(context.Background(), "home-key", "obj-1", "foreign", 0)
+				return err
+			},
+			wantErr: CommonError.CROSS_TENANT,
+		},
+		{name: "#4: expiry in the past",
+			store: func() KontrolStore {
+				return NewMockKontrolStore(ctrl)
+			},
+			call: func(k Kontrol) error {
+				_, err := k.RequestServiceGrant(context.Background(), "home-key", "obj-1", "target", 1654000000)
+				return err
+			},
+			wantErr: CommonError.INVALID_GRANT,
+		},
+		{name: "#5: target service consents",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				services(kontrolStore)
+				kontrolStore.EXPECT().GetServiceGrant(gomock.Any(), "obj-1", "target").Return(&ObjectServiceMess{ID: "g-1", ObjectID: "obj-1", ServiceID: "target", Status: GrantStatus.INIT}, nil)
+				kontrolStore.EXPECT().UpdateServiceGrant(gomock.Any(), &ObjectServiceMess{ID: "g-1", ObjectID: "obj-1", ServiceID: "target", Status: GrantStatus.ENABLE}).Return(nil)
+				return kontrolStore
+			},
+			call: func(k Kontrol) error {
+				return k.ApproveServiceGrant(context.Background(), "target-key", "obj-1", "target")
+			},
+			wantErr: nil,
+		},
+		{name: "#6: home service can not consent for the target",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				services(kontrolStore)
+				return kontrolStore
+			},
+			call: func(k Kontrol) error {
+				return k.ApproveServiceGrant(context.Background(), "home-key", "obj-1", "target")
+			},
+			wantErr: CommonError.INVALID_TOKEN,
+		},
+		{name: "#7: revoke expires the object tokens",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				services(kontrolStore)
+				grant := &ObjectServiceMess{ID: "g-1", ObjectID: "obj-1", ServiceID: "target", Status: GrantStatus.ENABLE}
+				kontrolStore.EXPECT().GetServiceGrant(gomock.Any(), "obj-1", "target").Return(grant, nil)
+				kontrolStore.EXPECT().DeleteServiceGrant(gomock.Any(), grant).Return(nil)
+				kontrolStore.EXPECT().ExpireObject(gomock.Any(), "obj-1").Return(nil)
+				return kontrolStore
+			},
+			call: func(k Kontrol) error {
+				return k.RevokeServiceGrant(context.Background(), "target-key", "obj-1", "target")
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(NewBasicKontrol(tt.store())); err != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_IssueCertForClient_GrantExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expiresAt := time.Now().Unix() + 60
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetObjectByExternalID(gomock.Any(), "user-1", "home").Return(&Object{ID: "obj-1", ServiceID: "home"}, nil)
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "home").Return(&Service{ID: "home"}, nil)
+	kontrolStore.EXPECT().GetObjectServiceMesh(gomock.Any(), "obj-1").Return([]*ObjectServiceMess{{ObjectID: "obj-1", ServiceID: "target", Status: GrantStatus.ENABLE, ExpiresAt: expiresAt}}, nil)
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "target").Return(&Service{ID: "target", Status: ServiceStatus.ENABLE, ExpiryDate: expiresAt + 3600}, nil)
+	kontrolStore.EXPECT().UpdateObject(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, obj *Object) error {
+		if obj.ExpiryDate != expiresAt {
+			t.Errorf("UpdateObject() expiry = %v, want capped at grant expiry %v", obj.ExpiryDate, expiresAt)
+		}
+		return nil
+	})
+	if _, err := NewBasicKontrol(kontrolStore).IssueCertForClient(context.Background(), "user-1", "home"); err != nil {
+		t.Errorf("IssueCertForClient() error = %v", err)
+	}
+}
+
+func TestDefaultKontrol_IssueCertForService_GrantExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expiresAt := time.Now().Unix() + 60
+	// object whose token was issued until expiry
+	issued := func(expiry int64) *Object {
+		obj := &Object{ID: "obj-1", ServiceID: "home", ExpiryDate: expiry}
+		_, sign, _, err := NewBasicKontrol(nil).CreateCert(&Object{ID: "obj-1", ServiceID: "home", ExpiryDate: expiry}, nil, nil, []string{"target"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj.Token = sign
+		return obj
+	}
+	tests := []struct {
+		name    string
+		obj     *Object
+		wantErr error
+	}{
+		{name: "#1: token issued within the grant", obj: issued(expiresAt), wantErr: nil},
+		{name: "#2: token outliving the grant", obj: issued(expiresAt + 600), wantErr: CommonError.INVALID_TOKEN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kontrolStore := NewMockKontrolStore(ctrl)
+			kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(tt.obj, nil)
+			kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "home").Return(&Service{ID: "home"}, nil)
+			kontrolStore.EXPECT().GetObjectServiceMesh(gomock.Any(), "obj-1").Return([]*ObjectServiceMess{{ObjectID: "obj-1", ServiceID: "target", Status: GrantStatus.ENABLE, ExpiresAt: expiresAt}}, nil)
+			kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "target").Return(&Service{ID: "target", Status: ServiceStatus.ENABLE, ExpiryDate: expiresAt + 3600}, nil)
+			if _, err := NewBasicKontrol(kontrolStore).IssueCertForService(context.Background(), "obj-1", "home"); err != tt.wantErr {
+				t.Errorf("IssueCertForService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
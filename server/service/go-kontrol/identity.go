@@ -0,0 +1,113 @@
+package gokontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// identityAttributeName attribute names usable in a header name
+var identityAttributeName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+//Identity caller of an allowed request, forwarded to the requested service as headers
+type Identity struct {
+	ObjectID   string
+	GlobalID   string
+	ExternalID string
+	ServiceID  string            // service of the object, not the requested one
+	Attributes map[string]string // object attributes the requested service selected
+	Anonymous  bool              // allowed without a token, as the anonymous principal if ObjectID is set
+}
+
+//IdentifyAccess validate the token for the request as ValidateAccess does, and the identity of its object
+func (k DefaultKontrol) IdentifyAccess(c context.Context, jwtToken string, req *AccessRequest) (*Identity, error) {
+	object, service, err := k.validateAccess(c, jwtToken, req)
+	if err != nil {
+		return nil, err
+	}
+	return newIdentity(object, service)
+}
+
+//IdentifyRequest as IdentifyAccess, the token is read from req by the token sources of the requested service.
+//Requests without a token the service allows anonymously get an anonymous identity
+func (k DefaultKontrol) IdentifyRequest(c context.Context, req *AccessRequest) (*Identity, error) {
+	object, service, anonymous, err := k.validateRequest(c, req)
+	if err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return &Identity{Attributes: map[string]string{}, Anonymous: true}, nil
+	}
+	identity, err := newIdentity(object, service)
+	if err != nil {
+		return nil, err
+	}
+	identity.Anonymous = anonymous
+	return identity, nil
+}
+
+// newIdentity identity of object for the requested service, with the attributes it selected
+func newIdentity(object *Object, service *Service) (*Identity, error) {
+	identity := &Identity{
+		ObjectID:   object.ID,
+		GlobalID:   object.GlobalID,
+		ExternalID: object.ExternalID,
+		ServiceID:  object.ServiceID,
+		Attributes: make(map[string]string, len(service.IdentityAttributes)),
+	}
+	for _, name := range service.IdentityAttributes {
+		v, ok := object.Attributes[name]
+		if !ok || v == nil || !identityAttributeName.MatchString(name) {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			s = string(b)
+		}
+		identity.Attributes[name] = s
+	}
+	return identity, nil
+}
+
+//Header identity as headers, attributes are prefixed by IdentityHeader.ATTRIBUTE_PREFIX
+func (i *Identity) Header() http.Header {
+	h := http.Header{}
+	h.Set(IdentityHeader.OBJECT_ID, i.ObjectID)
+	h.Set(IdentityHeader.GLOBAL_ID, i.GlobalID)
+	h.Set(IdentityHeader.EXTERNAL_ID, i.ExternalID)
+	h.Set(IdentityHeader.SERVICE, i.ServiceID)
+	if i.Anonymous {
+		h.Set(IdentityHeader.ANONYMOUS, "true")
+	}
+	for name, v := range i.Attributes {
+		h.Set(fmt.Sprintf("%s%s", IdentityHeader.ATTRIBUTE_PREFIX, name), headerValue(v))
+	}
+	return h
+}
+
+// headerValue drop control characters, values must not split the header
+func headerValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, v)
+}
+
+//ValidateIdentityAttributes check the attribute names a service selects are usable in header names
+func (s *Service) ValidateIdentityAttributes() error {
+	for _, name := range s.IdentityAttributes {
+		if !identityAttributeName.MatchString(name) {
+			return CommonError.INVALID_SERVICE
+		}
+	}
+	return nil
+}
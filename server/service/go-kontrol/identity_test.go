@@ -0,0 +1,71 @@
+package gokontrol
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_IdentifyAccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	obj := &Object{
+		ID:         "oid",
+		GlobalID:   "gid",
+		ExternalID: "alice",
+		ServiceID:  "sid",
+		ExpiryDate: time.Now().Unix() + 60,
+		Attributes: map[string]interface{}{"department": "ops\r\nX-Auth-Object-Id: root", "level": 3, "secret": "s3cr3t"},
+	}
+	k := DefaultKontrol{Option: DefaultKontrolOption}
+	_, sign, jwtToken, err := k.CreateCert(obj, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &Service{ID: "sid", ServiceID: "orders", IdentityAttributes: []string{"department", "level", "missing"}}
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(service, nil)
+	kontrolStore.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil)
+	k.store = kontrolStore
+
+	identity, err := k.IdentifyAccess(context.Background(), jwtToken, &AccessRequest{Method: "GET", Path: "/orders/list"})
+	if err != nil {
+		t.Fatalf("IdentifyAccess() error = %v", err)
+	}
+	want := http.Header{
+		"X-Auth-Object-Id":            {"oid"},
+		"X-Auth-Global-Id":            {"gid"},
+		"X-Auth-External-Id":          {"alice"},
+		"X-Auth-Service":              {"sid"},
+		"X-Auth-Attribute-Department": {"opsX-Auth-Object-Id: root"},
+		"X-Auth-Attribute-Level":      {"3"},
+	}
+	if got := identity.Header(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Header() got = %v, want %v", got, want)
+	}
+}
+
+func TestService_ValidateIdentityAttributes(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []string
+		wantErr    bool
+	}{
+		{name: "#1: header safe names", attributes: []string{"department", "cost-center", "Level2"}, wantErr: false},
+		{name: "#2: space", attributes: []string{"cost center"}, wantErr: true},
+		{name: "#3: underscore dropped by proxies", attributes: []string{"cost_center"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{IdentityAttributes: tt.attributes}
+			if err := s.ValidateIdentityAttributes(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIdentityAttributes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"gorm.io/gorm"
+	"net"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
@@ -20,18 +21,32 @@ import (
 type KontrolOption struct {
 	DefaultTimeout int64
 	SecretKey      string
+	BreakGlass     []*BreakGlassAccount
+	BreakGlassTTL  int64           // second
+	Resolver       ServiceResolver // service of incoming requests, path resolver if nil
+	SessionCookie  string          // SSO session cookie accepted by every service, none if empty
+	CacheSize      int             // decision cache entries, no cache if 0
+	CacheTTL       int64           // second, decisions are also kept no longer than their token
+	RateLimit      RateLimitOption // token buckets of the validate flow, none if zero
+	TrustedProxies []*net.IPNet    // proxies whose X-Forwarded-For hop is skipped to find the client IP
+	SignatureSkew  int64           // second, clock skew tolerated on the Date of signed requests
 }
 
 //Default config for kontrol
 var DefaultKontrolOption = KontrolOption{
 	DefaultTimeout: 1800, // second
 	SecretKey:      "secret",
+	BreakGlassTTL:  900,
+	RateLimit:      RateLimitOption{BreakGlass: RateLimit{Rate: 1.0 / 60, Burst: 5}},
+	SignatureSkew:  300,
 }
 
 //DefaultKontrol simple Kontrol
 type DefaultKontrol struct {
-	store  KontrolStore
-	Option KontrolOption
+	store   KontrolStore
+	Option  KontrolOption
+	cache   *decisionCache
+	limiter *rateLimiter
 }
 
 //NewBasicKontrol simple Kontrol with default option, stores still have to be provided
@@ -39,38 +54,144 @@ func NewBasicKontrol(store KontrolStore) Kontrol {
 	return &DefaultKontrol{store: store, Option: DefaultKontrolOption}
 }
 
+//NewKontrol Kontrol with option
+func NewKontrol(store KontrolStore, option KontrolOption) Kontrol {
+	return &DefaultKontrol{store: store, Option: option, cache: newDecisionCache(option.CacheSize, option.CacheTTL), limiter: newRateLimiter(option.RateLimit)}
+}
+
 //Claims -- JWT claim use for specific customize
 type Claims struct {
 	Permission map[string]map[string]bool `json:"permission"`
 	Token      string                     `json:"token"`
+	Scheduled  []string                   `json:"scheduled,omitempty"`  // scheduled policies the permission relies on
+	RateLimit  *RateLimit                 `json:"rate_limit,omitempty"` // object bucket of its policies, the configured one if nil
+	Network    []*PolicyNetwork           `json:"network,omitempty"`    // network conditions of its policies
 	jwt.StandardClaims
 }
 
 //ValidateToken validate the given token
 func (k DefaultKontrol) ValidateToken(c context.Context, jwtToken string, reqPath string, reqMethod string) (*Object, error) {
-	customizeClaim := &Claims{}
-	tkn, err := jwt.ParseWithClaims(jwtToken, customizeClaim, func(token *jwt.Token) (interface{}, error) {
-		return []byte(k.Option.SecretKey), nil
-	})
-	if err != nil || jwtToken == "" || tkn == nil {
-		if err == jwt.ErrSignatureInvalid {
-			return nil, err
+	return k.ValidateAccess(c, jwtToken, &AccessRequest{Method: reqMethod, Path: reqPath})
+}
+
+//ValidateAccess validate the given token for the request, fail closed when the request resolves to no service
+func (k DefaultKontrol) ValidateAccess(c context.Context, jwtToken string, req *AccessRequest) (*Object, error) {
+	object, _, err := k.validateAccess(c, jwtToken, req)
+	return object, err
+}
+
+// validateAccess object of the token allowed to make req, and the service req resolves to. Allowed decisions are
+// cached by token and resolved request, hits skip the token parse and the store. The network conditions and the
+// rate limits apply to hits too
+func (k DefaultKontrol) validateAccess(c context.Context, jwtToken string, req *AccessRequest) (*Object, *Service, error) {
+	t := time.Now()
+	now := t.Unix()
+	clientIP := k.clientIP(req)
+	if err := k.limiter.allowClientIP(clientIP, t); err != nil {
+		return nil, nil, err
+	}
+	serviceID, reqPath, resolveErr := k.resolve(req)
+	key := decisionKey(jwtToken, serviceID, req.Method, reqPath)
+	if resolveErr == nil {
+		if e := k.cache.get(key, now); e != nil {
+			return k.allow(e.object, e.service, e.network, e.rateLimit, clientIP, t)
 		}
-		if tkn == nil {
-			return nil, errors.New("Token is invalid ")
+	}
+	customizeClaim, err := k.parseToken(jwtToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// verify service of the request
+	if resolveErr != nil {
+		return nil, nil, resolveErr
+	}
+	reqService, err := k.lookupService(c, serviceID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkNetwork(clientIP, reqService, nil); err != nil {
+		return nil, nil, err
+	}
+	object, err := k.authorizeClaim(c, customizeClaim, reqService, reqPath, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	k.cache.putDecision(key, object, reqService, customizeClaim.Network, customizeClaim.RateLimit, customizeClaim.ExpiresAt, now)
+	return k.allow(object, reqService, customizeClaim.Network, customizeClaim.RateLimit, clientIP, t)
+}
+
+// validateRequest as validateAccess, the token read from req by the token sources of its service. Requests without
+// a token the service allows anonymously are allowed, anonymous, as its anonymous principal or a nil object. Signed
+// requests are allowed as the object of their signing key
+func (k DefaultKontrol) validateRequest(c context.Context, req *AccessRequest) (object *Object, reqService *Service, anonymous bool, err error) {
+	t := time.Now()
+	now := t.Unix()
+	clientIP := k.clientIP(req)
+	if err := k.limiter.allowClientIP(clientIP, t); err != nil {
+		return nil, nil, false, err
+	}
+	serviceID, reqPath, err := k.resolve(req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	reqService, err = k.lookupService(c, serviceID, now)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if err := checkNetwork(clientIP, reqService, nil); err != nil {
+		return nil, nil, false, err
+	}
+	if SignedRequest(req) {
+		object, err = k.validateSignature(c, reqService, reqPath, req, clientIP, t)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return object, reqService, false, nil
+	}
+	jwtToken, err := ExtractToken(k.tokenSources(reqService), req, reqPath)
+	if err == CommonError.MISSING_TOKEN && !reqService.Anonymous.Empty() {
+		object, err = k.validateAnonymous(c, serviceID, reqService, reqPath, req, clientIP, t)
+		if err != nil {
+			return nil, nil, false, err
 		}
+		return object, reqService, true, nil
 	}
-	if !tkn.Valid {
-		return nil, errors.New("Token is invalid ")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	key := decisionKey(jwtToken, serviceID, req.Method, reqPath)
+	if e := k.cache.get(key, now); e != nil {
+		object, reqService, err = k.allow(e.object, e.service, e.network, e.rateLimit, clientIP, t)
+		return object, reqService, false, err
+	}
+	customizeClaim, err := k.parseToken(jwtToken)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	object, err = k.authorizeClaim(c, customizeClaim, reqService, reqPath, req)
+	if err != nil {
+		return nil, nil, false, err
 	}
+	k.cache.putDecision(key, object, reqService, customizeClaim.Network, customizeClaim.RateLimit, customizeClaim.ExpiresAt, now)
+	object, reqService, err = k.allow(object, reqService, customizeClaim.Network, customizeClaim.RateLimit, clientIP, t)
+	return object, reqService, false, err
+}
 
-	// verify service follow path
-	splitPaths := strings.SplitN(reqPath, "/", 3)
-	reqService, err := k.store.GetServiceByExternalId(c, splitPaths[1])
-	if err != nil && err != CommonError.SERVICE_NOT_FOUND {
-		return nil, err
+// allow check the network conditions of the service and of the token policies for the allowed object, then take a
+// token of the service and object buckets, override is the limit of its policies
+func (k DefaultKontrol) allow(object *Object, service *Service, network []*PolicyNetwork, override *RateLimit, clientIP string, t time.Time) (*Object, *Service, error) {
+	if err := checkNetwork(clientIP, service, network); err != nil {
+		return nil, nil, err
+	}
+	if err := k.limiter.allowObject(object.ID, service.ID, override, t); err != nil {
+		return nil, nil, err
 	}
+	return object, service, nil
+}
 
+// authorizeClaim object of the token claim allowed to make req on reqPath of reqService
+func (k DefaultKontrol) authorizeClaim(c context.Context, customizeClaim *Claims, reqService *Service, reqPath string, req *AccessRequest) (*Object, error) {
 	//verify token
 	object, err := k.store.GetObjectByToken(c, customizeClaim.Token, time.Now().Unix())
 	if err != nil && err != CommonError.NOT_FOUND {
@@ -79,23 +200,94 @@ func (k DefaultKontrol) ValidateToken(c context.Context, jwtToken string, reqPat
 	if err == CommonError.NOT_FOUND {
 		return nil, CommonError.INVALID_TOKEN
 	}
+	// scheduled policies the token was issued with must still be in their window
+	for _, pid := range customizeClaim.Scheduled {
+		policy, err := k.store.GetPolicyByID(c, pid)
+		if err != nil && err != CommonError.NOT_FOUND {
+			return nil, err
+		}
+		if policy == nil || err == CommonError.NOT_FOUND || !policy.InSchedule(time.Now()) {
+			return nil, CommonError.INVALID_TOKEN
+		}
+	}
+	if err := claimPermits(object, customizeClaim, reqService, reqPath, req); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// claimPermits whether the claim of object allows req on reqPath of reqService, objects are allowed on their service
+func claimPermits(object *Object, customizeClaim *Claims, reqService *Service, reqPath string, req *AccessRequest) error {
 	// Verify permission access path by permission verified from JWT
 	if object.ServiceID != reqService.ID {
 		for jwtsid, servicePermissions := range customizeClaim.Permission {
 			if jwtsid == reqService.ID {
 				for permissionStr, enable := range servicePermissions {
-					match, _ := regexp.MatchString(permissionStr, fmt.Sprintf("%s@/%s", reqMethod, splitPaths[2]))
+					match, _ := regexp.MatchString(permissionStr, fmt.Sprintf("%s@%s", req.Method, reqPath))
 					if match && enable {
-						return object, nil
+						return nil
 					}
 				}
 
 			}
 		}
-		return nil, CommonError.INVALID_SERVICE
+		return CommonError.INVALID_SERVICE
 	}
+	return nil
+}
 
-	return object, nil
+// resolveService service targeted by req and the path inside it
+func (k DefaultKontrol) resolveService(c context.Context, req *AccessRequest) (*Service, string, error) {
+	serviceID, reqPath, err := k.resolve(req)
+	if err != nil {
+		return nil, "", err
+	}
+	reqService, err := k.lookupService(c, serviceID, time.Now().Unix())
+	if err != nil {
+		return nil, "", err
+	}
+	return reqService, reqPath, nil
+}
+
+// resolve id of the service targeted by req and the path inside it, as the configured resolver finds them
+func (k DefaultKontrol) resolve(req *AccessRequest) (string, string, error) {
+	resolver := k.Option.Resolver
+	if resolver == nil {
+		resolver = PathResolver{}
+	}
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	return resolver.Resolve(req)
+}
+
+// lookupService service of serviceID, from the decision cache when set
+func (k DefaultKontrol) lookupService(c context.Context, serviceID string, now int64) (*Service, error) {
+	if e := k.cache.get(serviceKey(serviceID), now); e != nil {
+		return e.service, nil
+	}
+	reqService, err := k.store.GetServiceByExternalId(c, serviceID)
+	if err != nil && err != CommonError.NOT_FOUND && err != CommonError.SERVICE_NOT_FOUND {
+		return nil, err
+	}
+	if reqService == nil || err != nil {
+		return nil, CommonError.SERVICE_NOT_FOUND
+	}
+	k.cache.putService(serviceID, reqService, now)
+	return reqService, nil
+}
+
+// parseToken verify the jwt signature and expiry
+func (k DefaultKontrol) parseToken(jwtToken string) (*Claims, error) {
+	customizeClaim := &Claims{}
+	tkn, err := jwt.ParseWithClaims(jwtToken, customizeClaim, func(token *jwt.Token) (interface{}, error) {
+		return []byte(k.Option.SecretKey), nil
+	})
+	// malformed, badly signed and expired tokens alike, callers answer 401
+	if err != nil || jwtToken == "" || tkn == nil || !tkn.Valid {
+		return nil, CommonError.INVALID_TOKEN
+	}
+	return customizeClaim, nil
 }
 
 //IssueCertForService issue cert for issued time, does not authn, must be authn-ed beforehand
@@ -119,12 +311,12 @@ func (k DefaultKontrol) IssueCertForService(ctx context.Context, objID string, s
 	if service == nil || err == CommonError.NOT_FOUND {
 		return nil, CommonError.INVALID_SERVICE
 	}
-	// generate cert
-	extendSerivceIds, err := k.GetObjectExtendServiceIds(ctx, objID)
+	// generate cert, the token was issued with the expiry of the object
+	policy, enforce, extendServiceIds, err := k.clientPolicies(ctx, obj, service, obj.ExpiryDate)
 	if err != nil {
-		return nil, CommonError.INVALID_POLICY
+		return nil, err
 	}
-	_, sign, jwtToken, err := k.CreateCert(obj, service.DefaultPolicy, service.EnforcePolicy, extendSerivceIds)
+	_, sign, jwtToken, err := k.CreateCert(obj, policy, enforce, extendServiceIds)
 	if err != nil {
 		return nil, err
 	}
@@ -160,33 +352,17 @@ func (k DefaultKontrol) IssueCertForClient(ctx context.Context, externalID strin
 		return nil, CommonError.INVALID_SERVICE
 	}
 
-	obj.ExpiryDate = time.Now().Unix() + k.Option.DefaultTimeout
-	objectExtendServiceIds, err := k.GetObjectExtendServiceIds(ctx, obj.ID)
-	if err != nil && err != gorm.ErrRecordNotFound {
+	policy, enforce, objectExtendServiceIds, err := k.clientPolicies(ctx, obj, service, time.Now().Unix()+k.Option.DefaultTimeout)
+	if err != nil {
 		return nil, err
 	}
-	for _, extendServiceId := range objectExtendServiceIds {
-		extendService, err := k.store.GetServiceByID(ctx, extendServiceId)
-		if err != nil { // wont accept case delete but missing cascade. We should disable service that hard delete it
-			return nil, err
-		}
-		if extendService.Status == ServiceStatus.ENABLE && extendService.ExpiryDate >= time.Now().Unix() {
-			for _, extPolicy := range extendService.DefaultPolicy {
-				service.DefaultPolicy = append(service.DefaultPolicy, extPolicy)
-			}
-			for _, extEnforcePolicy := range extendService.EnforcePolicy {
-				service.EnforcePolicy = append(service.EnforcePolicy, extEnforcePolicy)
-			}
-		}
-	}
 	// generate cert
-	_, sign, jwtToken, err := k.CreateCert(obj, service.DefaultPolicy, service.EnforcePolicy, objectExtendServiceIds)
+	_, sign, jwtToken, err := k.CreateCert(obj, policy, enforce, objectExtendServiceIds)
 	if err != nil {
 		return nil, err
 	}
 	obj.Token = sign
-	err = k.store.UpdateObject(ctx, obj)
-	if err != nil {
+	if err := k.updateObjectToken(ctx, obj); err != nil {
 		return nil, err
 	}
 	return &ObjectPermission{
@@ -195,6 +371,37 @@ func (k DefaultKontrol) IssueCertForClient(ctx context.Context, externalID strin
 	}, nil
 }
 
+// clientPolicies default and enforce policies of the certs of obj on service, with the ones of the services it is
+// granted on in the same organization, and the ids of these services. The cert must not outlive a grant, obj.ExpiryDate
+// is set to expiry or the closest grant expiry before it
+func (k DefaultKontrol) clientPolicies(ctx context.Context, obj *Object, service *Service, expiry int64) ([]*Policy, []*Policy, []string, error) {
+	grants, err := k.store.GetObjectServiceMesh(ctx, obj.ID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, nil, nil, err
+	}
+	objectExtendServiceIds := make([]string, len(grants))
+	for i := range grants {
+		objectExtendServiceIds[i] = grants[i].ServiceID
+	}
+	obj.ExpiryDate = grantExpiry(grants, expiry)
+	policy := append([]*Policy{}, service.DefaultPolicy...)
+	enforce := append([]*Policy{}, service.EnforcePolicy...)
+	for _, extendServiceId := range objectExtendServiceIds {
+		extendService, err := k.store.GetServiceByID(ctx, extendServiceId)
+		if err != nil { // wont accept case delete but missing cascade. We should disable service that hard delete it
+			return nil, nil, nil, err
+		}
+		if extendService.OrganizationID != service.OrganizationID {
+			continue
+		}
+		if extendService.Status == ServiceStatus.ENABLE && extendService.ExpiryDate >= time.Now().Unix() {
+			policy = append(policy, extendService.DefaultPolicy...)
+			enforce = append(enforce, extendService.EnforcePolicy...)
+		}
+	}
+	return policy, enforce, objectExtendServiceIds, nil
+}
+
 //AddSimpleObjectWithDefaultPolicy add object with default service schema
 func (k DefaultKontrol) AddSimpleObjectWithDefaultPolicy(ctx context.Context, externalid string, serviceid string, servicekey string) (*ObjectPermission, error) {
 	// check service/policy
@@ -207,11 +414,8 @@ func (k DefaultKontrol) AddSimpleObjectWithDefaultPolicy(ctx context.Context, ex
 	}
 
 	// check service key
-	scert := append([]byte(k.Option.SecretKey), []byte(servicekey)...)
-	hash := sha256.Sum256(scert)
-	sign := base64.URLEncoding.EncodeToString(hash[:])
-	if strings.Compare(sign, service.Key) != 0 {
-		return nil, CommonError.INVALID_TOKEN
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return nil, err
 	}
 
 	testobj, err := k.store.GetObjectByExternalID(ctx, externalid, serviceid)
@@ -223,15 +427,16 @@ func (k DefaultKontrol) AddSimpleObjectWithDefaultPolicy(ctx context.Context, ex
 	}
 
 	obj := &Object{
-		ID:          uuid.New().String(),
-		ExternalID:  externalid,
-		ServiceID:   serviceid,
-		Status:      ObjectStatus.ENABLE,
-		Attributes:  nil,
-		Token:       "",
-		GlobalID:    uuid.New().String(),
-		ExpiryDate:  time.Now().Unix() + k.Option.DefaultTimeout,
-		ApplyPolicy: nil,
+		ID:             uuid.New().String(),
+		ExternalID:     externalid,
+		ServiceID:      serviceid,
+		OrganizationID: service.OrganizationID,
+		Status:         ObjectStatus.ENABLE,
+		Attributes:     nil,
+		Token:          "",
+		GlobalID:       uuid.New().String(),
+		ExpiryDate:     time.Now().Unix() + k.Option.DefaultTimeout,
+		ApplyPolicy:    nil,
 	}
 
 	_, sign, jwtToken, err := k.CreateCert(obj, service.DefaultPolicy, service.EnforcePolicy, []string{})
@@ -261,11 +466,8 @@ func (k DefaultKontrol) UpdateObject(ctx context.Context, obj *Object, serviceke
 	}
 
 	// check service key
-	scert := append([]byte(k.Option.SecretKey), []byte(servicekey)...)
-	hash := sha256.Sum256(scert)
-	sign := base64.URLEncoding.EncodeToString(hash[:])
-	if strings.Compare(sign, service.Key) != 0 {
-		return CommonError.INVALID_TOKEN
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return err
 	}
 
 	// check duplicate
@@ -276,8 +478,29 @@ func (k DefaultKontrol) UpdateObject(ctx context.Context, obj *Object, serviceke
 	if old == nil || err == CommonError.NOT_FOUND {
 		return CommonError.OBJECT_NOT_FOUND
 	}
+	// objects stay in their service, policies never cross tenants
+	if old.OrganizationID != service.OrganizationID {
+		return CommonError.CROSS_TENANT
+	}
+	if old.ServiceID != obj.ServiceID {
+		return CommonError.INVALID_OBJECT
+	}
+	// other services are reached through grants, their policies are theirs to apply
+	for _, p := range obj.ApplyPolicy {
+		if p.OrganizationID != service.OrganizationID {
+			return CommonError.CROSS_TENANT
+		}
+		if p.ServiceID != service.ID {
+			return CommonError.INVALID_POLICY
+		}
+	}
+	obj.OrganizationID = service.OrganizationID
 
-	return k.store.UpdateObject(ctx, obj)
+	if err := k.store.UpdateObject(ctx, obj); err != nil {
+		return err
+	}
+	k.cache.invalidateObject(obj.ID)
+	return nil
 }
 func (k DefaultKontrol) GetObjectExtendServiceIds(ctx context.Context, objId string) ([]string, error) {
 
@@ -294,6 +517,32 @@ func (k DefaultKontrol) GetObjectExtendServiceIds(ctx context.Context, objId str
 
 //CreateCert create final cert then sign
 func (k DefaultKontrol) CreateCert(obj *Object, policy []*Policy, enforce []*Policy, extendServiceIds []string) (*CertForSign, string, string, error) {
+	tempcert, claims, err := k.certClaims(obj, policy, enforce, extendServiceIds)
+	if err != nil {
+		return nil, "", "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Sign and get the complete encoded token as a string using the secret
+	jwtString, err := token.SignedString([]byte(k.Option.SecretKey))
+	if err != nil {
+		return nil, "", "", err
+	}
+	return tempcert, claims.Token, jwtString, nil
+}
+
+// certClaims final cert of obj and the claims of its token, signed requests are authorized by the claims unsigned
+func (k DefaultKontrol) certClaims(obj *Object, policy []*Policy, enforce []*Policy, extendServiceIds []string) (*CertForSign, *Claims, error) {
+	// drop policies out of their schedule, the cert must not outlive the next schedule boundary
+	now := time.Now()
+	scheduled := make([]string, 0)
+	expiry := obj.ExpiryDate
+	policy, expiry, scheduled = scheduledPolicies(now, policy, expiry, scheduled)
+	applyPolicy, expiry, scheduled := scheduledPolicies(now, obj.ApplyPolicy, expiry, scheduled)
+	enforce, expiry, scheduled = scheduledPolicies(now, enforce, expiry, scheduled)
+	// nor outlive an elevation
+	expiry = elevationExpiry(obj, expiry)
+	obj.ExpiryDate = expiry
+
 	tempcert := &CertForSign{
 		ID:         obj.ID,
 		GlobalID:   obj.GlobalID,
@@ -321,13 +570,13 @@ func (k DefaultKontrol) CreateCert(obj *Object, policy []*Policy, enforce []*Pol
 			case PolicyPermission.FALSE:
 			case PolicyPermission.ANY:
 			default:
-				return nil, "", "", CommonError.MALFORM_PERMISSION
+				return nil, nil, CommonError.MALFORM_PERMISSION
 			}
 		}
 		tempperm[dp.ServiceID] = ts
 	}
 	// apply custom policies
-	for _, cp := range obj.ApplyPolicy {
+	for _, cp := range applyPolicy {
 		ts, exist := tempperm[cp.ServiceID]
 		if !exist {
 			ts = make(map[string]bool)
@@ -340,7 +589,7 @@ func (k DefaultKontrol) CreateCert(obj *Object, policy []*Policy, enforce []*Pol
 				delete(ts, k)
 			case PolicyPermission.ANY:
 			default:
-				return nil, "", "", CommonError.MALFORM_PERMISSION
+				return nil, nil, CommonError.MALFORM_PERMISSION
 			}
 		}
 		tempperm[cp.ServiceID] = ts
@@ -359,7 +608,7 @@ func (k DefaultKontrol) CreateCert(obj *Object, policy []*Policy, enforce []*Pol
 				delete(ts, k)
 			case PolicyPermission.ANY:
 			default:
-				return nil, "", "", CommonError.MALFORM_PERMISSION
+				return nil, nil, CommonError.MALFORM_PERMISSION
 			}
 		}
 		tempperm[cp.ServiceID] = ts
@@ -368,7 +617,7 @@ func (k DefaultKontrol) CreateCert(obj *Object, policy []*Policy, enforce []*Pol
 	tempcert.Permission = tempperm
 	certstr, err := json.Marshal(tempcert)
 	if err != nil {
-		return nil, "", "", err
+		return nil, nil, err
 	}
 	scert := append([]byte(k.Option.SecretKey), certstr...)
 	hash := sha256.Sum256(scert)
@@ -376,17 +625,14 @@ func (k DefaultKontrol) CreateCert(obj *Object, policy []*Policy, enforce []*Pol
 	claims := &Claims{
 		Permission: tempperm,
 		Token:      sign,
+		Scheduled:  scheduled,
+		RateLimit:  policyRateLimit(policy, applyPolicy, enforce),
+		Network:    policyNetworks(policy, applyPolicy, enforce),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: obj.ExpiryDate,
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Sign and get the complete encoded token as a string using the secret
-	jwtString, err := token.SignedString([]byte(k.Option.SecretKey))
-	if err != nil {
-		return nil, "", "", err
-	}
-	return tempcert, sign, jwtString, nil
+	return tempcert, claims, nil
 }
 
 //CreatePolicy create a policy
@@ -401,12 +647,18 @@ func (k DefaultKontrol) CreatePolicy(ctx context.Context, servicekey string, pol
 	}
 
 	// check service key
-	scert := append([]byte(k.Option.SecretKey), []byte(servicekey)...)
-	hash := sha256.Sum256(scert)
-	sign := base64.URLEncoding.EncodeToString(hash[:])
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.POLICY_ADMIN); err != nil {
+		return err
+	}
 
-	if strings.Compare(sign, service.Key) != 0 {
-		return CommonError.INVALID_TOKEN
+	if err := policy.ValidateSchedule(); err != nil {
+		return err
+	}
+	if err := policy.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if err := policy.Network.Validate(); err != nil {
+		return err
 	}
 
 	// check duplicate policy
@@ -417,6 +669,10 @@ func (k DefaultKontrol) CreatePolicy(ctx context.Context, servicekey string, pol
 	if testpolicy != nil || err != CommonError.NOT_FOUND {
 		return CommonError.INVALID_POLICY
 	}
+	policy.OrganizationID = service.OrganizationID
+	if err := lintErrors(LintPolicy(policy, service.EnforcePolicy, service.Routes)); err != nil {
+		return err
+	}
 
 	return k.store.CreatePolicy(ctx, policy)
 }
@@ -431,24 +687,103 @@ func (k DefaultKontrol) UpdatePolicy(ctx context.Context, servicekey string, pol
 	}
 
 	// check service key
-	scert := append([]byte(k.Option.SecretKey), []byte(servicekey)...)
-	hash := sha256.Sum256(scert)
-	sign := base64.URLEncoding.EncodeToString(hash[:])
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.POLICY_ADMIN); err != nil {
+		return err
+	}
 
-	if strings.Compare(sign, service.Key) != 0 {
-		return CommonError.INVALID_TOKEN
+	if err := policy.ValidateSchedule(); err != nil {
+		return err
+	}
+	if err := policy.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if err := policy.Network.Validate(); err != nil {
+		return err
 	}
 
 	// check  policy exist
-	_, err = k.store.GetPolicyByID(ctx, policy.ID)
+	old, err := k.store.GetPolicyByID(ctx, policy.ID)
 	if err != nil {
 		return err
 	}
+	return k.updatePolicy(ctx, service, old, policy)
+}
+
+// updatePolicy save policy over old, authorized on service, and expire the related objects
+func (k DefaultKontrol) updatePolicy(ctx context.Context, service *Service, old *Policy, policy *Policy) error {
+	if old.OrganizationID != service.OrganizationID {
+		return CommonError.CROSS_TENANT
+	}
+	// policies stay on their service, the store would move it
+	if old.ServiceID != service.ID {
+		return CommonError.INVALID_POLICY
+	}
+	policy.OrganizationID = service.OrganizationID
+	// instances keep their binding, their permission comes from the template
+	if old.TemplateID != "" {
+		tpl, err := k.store.GetPolicyTemplateByID(ctx, old.TemplateID)
+		if err != nil {
+			return err
+		}
+		policy.TemplateID = old.TemplateID
+		policy.Parameters = old.Parameters
+		if policy.Permission, err = tpl.Bind(old.Parameters); err != nil {
+			return err
+		}
+	}
+	if err := lintErrors(LintPolicy(policy, service.EnforcePolicy, service.Routes)); err != nil {
+		return err
+	}
 
 	if err := k.store.UpdatePolicy(ctx, policy); err != nil {
 		return err
 	}
 	//Expired related object
-	return k.store.ExpiredObjectsByPolicy(ctx, policy.ID)
+	return k.expireObjectsByPolicy(ctx, policy.ID)
+
+}
 
+//ExpireObjectsByPolicyWindow expire objects related to policies whose apply range opened or closed, or which changed, in (from, to]
+func (k DefaultKontrol) ExpireObjectsByPolicyWindow(ctx context.Context, from int64, to int64) ([]string, error) {
+	if from >= to {
+		return []string{}, nil
+	}
+	policies, err := k.store.GetPoliciesCrossingWindow(ctx, from, to)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	rs := make([]string, 0, len(policies))
+	for _, p := range policies {
+		if err := k.expireObjectsByPolicy(ctx, p.ID); err != nil {
+			return rs, err
+		}
+		rs = append(rs, p.ID)
+	}
+	return rs, nil
+}
+
+// serviceKeySign hash of a service or organization key as stored
+func (k DefaultKontrol) serviceKeySign(key string) string {
+	scert := append([]byte(k.Option.SecretKey), []byte(key)...)
+	hash := sha256.Sum256(scert)
+	return base64.URLEncoding.EncodeToString(hash[:])
+}
+
+// authorizeService allow management of service with its key, with the credentials of the organization owning it,
+// or by an admin holding role on it. An empty role is reserved to the key and organization holders
+func (k DefaultKontrol) authorizeService(ctx context.Context, service *Service, servicekey string, role string) error {
+	orgID, scoped := OrganizationFromContext(ctx)
+	if scoped && orgID != service.OrganizationID {
+		return CommonError.CROSS_TENANT
+	}
+	if scoped {
+		return nil
+	}
+	if admin, ok := AdminFromContext(ctx); ok && role != "" {
+		return k.authorizeAdmin(ctx, admin, service, role)
+	}
+	if strings.Compare(k.serviceKeySign(servicekey), service.Key) != 0 {
+		return CommonError.INVALID_TOKEN
+	}
+	return nil
 }
@@ -0,0 +1,137 @@
+package gokontrol
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//LintIssue finding of the policy linter on one permission key
+type LintIssue struct {
+	PolicyID string `json:"policy_id"`
+	Key      string `json:"key"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+//LintPolicy check the permission of policy. enforce are the enforce policies of its service, routes the
+//request samples of the service, keys are not checked against routes when there is none
+func LintPolicy(policy *Policy, enforce []*Policy, routes []string) []*LintIssue {
+	issues := make([]*LintIssue, 0)
+	report := func(key string, rule string, severity string, format string, args ...interface{}) {
+		issues = append(issues, &LintIssue{PolicyID: policy.ID, Key: key, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	keys := make([]string, 0, len(policy.Permission))
+	for key := range policy.Permission {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	compiled := make(map[string]*regexp.Regexp, len(keys))
+	for _, key := range keys {
+		v := policy.Permission[key]
+		if v < PolicyPermission.ANY || v > PolicyPermission.FALSE {
+			report(key, LintRule.INVALID_VALUE, LintSeverity.ERROR, "value %d is none of any (0), allow (1) and deny (2)", v)
+		}
+		re, err := regexp.Compile(key)
+		if err != nil {
+			report(key, LintRule.INVALID_PATTERN, LintSeverity.ERROR, "pattern does not compile: %v", err)
+			continue
+		}
+		compiled[key] = re
+	}
+
+	for _, key := range keys {
+		re, ok := compiled[key]
+		if !ok || policy.Permission[key] == PolicyPermission.ANY {
+			continue
+		}
+		granted := policy.Permission[key] == PolicyPermission.TRUE
+		if granted && re.MatchString("") {
+			report(key, LintRule.BROAD, LintSeverity.WARNING, "pattern matches every request of the service")
+		}
+		// a grant covered by another grant is redundant, a denial covered by a grant has no effect
+		for _, other := range keys {
+			ore, ok := compiled[other]
+			if !ok || other == key || policy.Permission[other] != PolicyPermission.TRUE || !covers(ore, re) {
+				continue
+			}
+			// of two keys matching the same requests, report the later one only
+			if granted && covers(re, ore) && key < other {
+				continue
+			}
+			if granted {
+				report(key, LintRule.SHADOWED, LintSeverity.WARNING, "every request matched is already allowed by %q", other)
+			} else {
+				report(key, LintRule.SHADOWED, LintSeverity.WARNING, "denial has no effect, %q allows the requests", other)
+			}
+			break
+		}
+		if !granted {
+			continue
+		}
+		for _, ep := range enforce {
+			if ep.ID != policy.ID && ep.Permission[key] == PolicyPermission.FALSE {
+				report(key, LintRule.ENFORCE_REMOVED, LintSeverity.WARNING, "enforce policy %s always removes the permission", ep.ID)
+				break
+			}
+		}
+		if len(routes) > 0 && !matchesAny(re, routes) {
+			report(key, LintRule.UNROUTED, LintSeverity.WARNING, "pattern matches no route of the service")
+		}
+	}
+	return issues
+}
+
+//LintPolicies lint every policy of a service against its enforce policies and routes
+func (k DefaultKontrol) LintPolicies(ctx context.Context, servicekey string, serviceID string) ([]*LintIssue, error) {
+	service, err := k.serviceByID(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.AUDITOR); err != nil {
+		return nil, err
+	}
+	policies, err := k.store.GetPoliciesByServiceID(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	issues := make([]*LintIssue, 0)
+	for _, p := range policies {
+		issues = append(issues, LintPolicy(p, service.EnforcePolicy, service.Routes)...)
+	}
+	return issues, nil
+}
+
+// lintErrors reject a policy with an error issue, warnings do not prevent saving it
+func lintErrors(issues []*LintIssue) error {
+	for _, issue := range issues {
+		if issue.Severity == LintSeverity.ERROR {
+			return CommonError.MALFORM_PERMISSION
+		}
+	}
+	return nil
+}
+
+// covers whether every request matched by re is matched by other. Keys are unanchored, a request matched
+// by re contains its literal prefix, so other matching the prefix matches the request too
+func covers(other *regexp.Regexp, re *regexp.Regexp) bool {
+	if strings.ContainsAny(other.String(), "^$") || strings.Contains(other.String(), `\A`) || strings.Contains(other.String(), `\z`) {
+		return false
+	}
+	prefix, _ := re.LiteralPrefix()
+	return other.MatchString(prefix)
+}
+
+func matchesAny(re *regexp.Regexp, routes []string) bool {
+	for _, r := range routes {
+		if re.MatchString(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestLintPolicy(t *testing.T) {
+	enforce := []*Policy{{ID: "no-delete", Permission: map[string]int{"DELETE@/users/.*": PolicyPermission.FALSE}}}
+	routes := []string{"GET@/users/1", "DELETE@/users/1", "POST@/orders"}
+	tests := []struct {
+		name       string
+		permission map[string]int
+		want       []string
+	}{
+		{name: "#1: clean", permission: map[string]int{"GET@/users/.*": 1, "POST@/orders": 1}, want: []string{}},
+		{name: "#2: uncompilable pattern", permission: map[string]int{"GET@/users/(": 1}, want: []string{"GET@/users/( invalid_pattern error"}},
+		{name: "#3: malformed value", permission: map[string]int{"GET@/users/.*": 3}, want: []string{"GET@/users/.* invalid_value error"}},
+		{name: "#4: grant covered by a broader grant", permission: map[string]int{"GET@/users/1": 1, "GET@/users": 1}, want: []string{"GET@/users/1 shadowed warning"}},
+		{name: "#5: same requests reported once", permission: map[string]int{"GET@/users": 1, "GET@/users(/)?": 1}, want: []string{"GET@/users(/)? shadowed warning"}},
+		{name: "#6: denial covered by a grant", permission: map[string]int{"GET@/users/1": 2, "GET@/users/.*": 1}, want: []string{"GET@/users/1 shadowed warning"}},
+		{name: "#7: anchored grants cover nothing", permission: map[string]int{"GET@/users/1": 1, "^GET@/users": 1}, want: []string{}},
+		{name: "#8: grant removed by enforce", permission: map[string]int{"DELETE@/users/.*": 1}, want: []string{"DELETE@/users/.* enforce_removed warning"}},
+		{name: "#9: key matching no route", permission: map[string]int{"PUT@/orders/.*": 1}, want: []string{"PUT@/orders/.* unrouted warning"}},
+		{name: "#10: everything", permission: map[string]int{".*": 1}, want: []string{".* broad warning"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, issue := range LintPolicy(&Policy{ID: "p", Permission: tt.permission}, enforce, routes) {
+				got = append(got, issue.Key+" "+issue.Rule+" "+issue.Severity)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LintPolicy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_CreatePolicyLinted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := &Service{ID: "sid", OrganizationID: "org-a", Key: DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("service-key")}
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+	kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "p").Return(nil, CommonError.NOT_FOUND)
+
+	k := NewBasicKontrol(kontrolStore)
+	err := k.CreatePolicy(context.Background(), "service-key", &Policy{ID: "p", ServiceID: "sid", Permission: map[string]int{"GET@/users/[": 1}})
+	if err != CommonError.MALFORM_PERMISSION {
+		t.Errorf("CreatePolicy() error = %v, wantErr %v", err, CommonError.MALFORM_PERMISSION)
+	}
+}
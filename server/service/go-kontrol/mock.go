@@ -49,6 +49,80 @@ func (mr *MockKontrolMockRecorder) AddSimpleObjectWithDefaultPolicy(ctx, externa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSimpleObjectWithDefaultPolicy", reflect.TypeOf((*MockKontrol)(nil).AddSimpleObjectWithDefaultPolicy), ctx, externalid, serviceid, servicekey)
 }
 
+// ApplyManifest mocks base method.
+func (m_2 *MockKontrol) ApplyManifest(ctx context.Context, m *Manifest) ([]*PlanChange, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "ApplyManifest", ctx, m)
+	ret0, _ := ret[0].([]*PlanChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyManifest indicates an expected call of ApplyManifest.
+func (mr *MockKontrolMockRecorder) ApplyManifest(ctx, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyManifest", reflect.TypeOf((*MockKontrol)(nil).ApplyManifest), ctx, m)
+}
+
+// ApproveServiceGrant mocks base method.
+func (m *MockKontrol) ApproveServiceGrant(ctx context.Context, servicekey, objectID, serviceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveServiceGrant", ctx, servicekey, objectID, serviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveServiceGrant indicates an expected call of ApproveServiceGrant.
+func (mr *MockKontrolMockRecorder) ApproveServiceGrant(ctx, servicekey, objectID, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveServiceGrant", reflect.TypeOf((*MockKontrol)(nil).ApproveServiceGrant), ctx, servicekey, objectID, serviceID)
+}
+
+// AuthenticateAdmin mocks base method.
+func (m *MockKontrol) AuthenticateAdmin(ctx context.Context, jwtToken string) (*Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAdmin", ctx, jwtToken)
+	ret0, _ := ret[0].(*Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateAdmin indicates an expected call of AuthenticateAdmin.
+func (mr *MockKontrolMockRecorder) AuthenticateAdmin(ctx, jwtToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAdmin", reflect.TypeOf((*MockKontrol)(nil).AuthenticateAdmin), ctx, jwtToken)
+}
+
+// AuthenticateOrganization mocks base method.
+func (m *MockKontrol) AuthenticateOrganization(ctx context.Context, organizationID, key string) (*Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateOrganization", ctx, organizationID, key)
+	ret0, _ := ret[0].(*Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateOrganization indicates an expected call of AuthenticateOrganization.
+func (mr *MockKontrolMockRecorder) AuthenticateOrganization(ctx, organizationID, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateOrganization", reflect.TypeOf((*MockKontrol)(nil).AuthenticateOrganization), ctx, organizationID, key)
+}
+
+// BreakGlass mocks base method.
+func (m *MockKontrol) BreakGlass(ctx context.Context, req *BreakGlassRequest) (*ObjectPermission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BreakGlass", ctx, req)
+	ret0, _ := ret[0].(*ObjectPermission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BreakGlass indicates an expected call of BreakGlass.
+func (mr *MockKontrolMockRecorder) BreakGlass(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BreakGlass", reflect.TypeOf((*MockKontrol)(nil).BreakGlass), ctx, req)
+}
+
 // CreateCert mocks base method.
 func (m *MockKontrol) CreateCert(obj *Object, policy, enforce []*Policy, objectExtendServiceIds []string) (*CertForSign, string, string, error) {
 	m.ctrl.T.Helper()
@@ -80,6 +154,139 @@ func (mr *MockKontrolMockRecorder) CreatePolicy(ctx, servicekey, policy interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePolicy", reflect.TypeOf((*MockKontrol)(nil).CreatePolicy), ctx, servicekey, policy)
 }
 
+// CreatePolicyTemplate mocks base method.
+func (m *MockKontrol) CreatePolicyTemplate(ctx context.Context, servicekey string, tpl *PolicyTemplate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePolicyTemplate", ctx, servicekey, tpl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePolicyTemplate indicates an expected call of CreatePolicyTemplate.
+func (mr *MockKontrolMockRecorder) CreatePolicyTemplate(ctx, servicekey, tpl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePolicyTemplate", reflect.TypeOf((*MockKontrol)(nil).CreatePolicyTemplate), ctx, servicekey, tpl)
+}
+
+// CreateSigningKey mocks base method.
+func (m *MockKontrol) CreateSigningKey(ctx context.Context, servicekey, objectID string) (*SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSigningKey", ctx, servicekey, objectID)
+	ret0, _ := ret[0].(*SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSigningKey indicates an expected call of CreateSigningKey.
+func (mr *MockKontrolMockRecorder) CreateSigningKey(ctx, servicekey, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSigningKey", reflect.TypeOf((*MockKontrol)(nil).CreateSigningKey), ctx, servicekey, objectID)
+}
+
+// ElevateObject mocks base method.
+func (m *MockKontrol) ElevateObject(ctx context.Context, policyID, reason string, duration int64) (*Elevation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ElevateObject", ctx, policyID, reason, duration)
+	ret0, _ := ret[0].(*Elevation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ElevateObject indicates an expected call of ElevateObject.
+func (mr *MockKontrolMockRecorder) ElevateObject(ctx, policyID, reason, duration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ElevateObject", reflect.TypeOf((*MockKontrol)(nil).ElevateObject), ctx, policyID, reason, duration)
+}
+
+// ExpireElevations mocks base method.
+func (m *MockKontrol) ExpireElevations(ctx context.Context, timestamp int64) ([]*Elevation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireElevations", ctx, timestamp)
+	ret0, _ := ret[0].([]*Elevation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireElevations indicates an expected call of ExpireElevations.
+func (mr *MockKontrolMockRecorder) ExpireElevations(ctx, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireElevations", reflect.TypeOf((*MockKontrol)(nil).ExpireElevations), ctx, timestamp)
+}
+
+// ExpireObjectsByPolicyWindow mocks base method.
+func (m *MockKontrol) ExpireObjectsByPolicyWindow(ctx context.Context, from, to int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireObjectsByPolicyWindow", ctx, from, to)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireObjectsByPolicyWindow indicates an expected call of ExpireObjectsByPolicyWindow.
+func (mr *MockKontrolMockRecorder) ExpireObjectsByPolicyWindow(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireObjectsByPolicyWindow", reflect.TypeOf((*MockKontrol)(nil).ExpireObjectsByPolicyWindow), ctx, from, to)
+}
+
+// ExpireServiceGrants mocks base method.
+func (m *MockKontrol) ExpireServiceGrants(ctx context.Context, timestamp int64) ([]*ObjectServiceMess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireServiceGrants", ctx, timestamp)
+	ret0, _ := ret[0].([]*ObjectServiceMess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireServiceGrants indicates an expected call of ExpireServiceGrants.
+func (mr *MockKontrolMockRecorder) ExpireServiceGrants(ctx, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireServiceGrants", reflect.TypeOf((*MockKontrol)(nil).ExpireServiceGrants), ctx, timestamp)
+}
+
+// ExpireSignatureNonces mocks base method.
+func (m *MockKontrol) ExpireSignatureNonces(ctx context.Context, timestamp int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireSignatureNonces", ctx, timestamp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExpireSignatureNonces indicates an expected call of ExpireSignatureNonces.
+func (mr *MockKontrolMockRecorder) ExpireSignatureNonces(ctx, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireSignatureNonces", reflect.TypeOf((*MockKontrol)(nil).ExpireSignatureNonces), ctx, timestamp)
+}
+
+// ExportManifest mocks base method.
+func (m *MockKontrol) ExportManifest(ctx context.Context) (*Manifest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportManifest", ctx)
+	ret0, _ := ret[0].(*Manifest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportManifest indicates an expected call of ExportManifest.
+func (mr *MockKontrolMockRecorder) ExportManifest(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportManifest", reflect.TypeOf((*MockKontrol)(nil).ExportManifest), ctx)
+}
+
+// GatewayServices mocks base method.
+func (m *MockKontrol) GatewayServices(ctx context.Context) ([]*Service, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GatewayServices", ctx)
+	ret0, _ := ret[0].([]*Service)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GatewayServices indicates an expected call of GatewayServices.
+func (mr *MockKontrolMockRecorder) GatewayServices(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GatewayServices", reflect.TypeOf((*MockKontrol)(nil).GatewayServices), ctx)
+}
+
 // GetObjectExtendServiceIds mocks base method.
 func (m *MockKontrol) GetObjectExtendServiceIds(ctx context.Context, objId string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +302,79 @@ func (mr *MockKontrolMockRecorder) GetObjectExtendServiceIds(ctx, objId interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectExtendServiceIds", reflect.TypeOf((*MockKontrol)(nil).GetObjectExtendServiceIds), ctx, objId)
 }
 
+// GetServicePolicies mocks base method.
+func (m *MockKontrol) GetServicePolicies(ctx context.Context, servicekey, serviceID string) ([]*Policy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePolicies", ctx, servicekey, serviceID)
+	ret0, _ := ret[0].([]*Policy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServicePolicies indicates an expected call of GetServicePolicies.
+func (mr *MockKontrolMockRecorder) GetServicePolicies(ctx, servicekey, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePolicies", reflect.TypeOf((*MockKontrol)(nil).GetServicePolicies), ctx, servicekey, serviceID)
+}
+
+// GrantServiceAdmin mocks base method.
+func (m *MockKontrol) GrantServiceAdmin(ctx context.Context, servicekey string, admin *ServiceAdmin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantServiceAdmin", ctx, servicekey, admin)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantServiceAdmin indicates an expected call of GrantServiceAdmin.
+func (mr *MockKontrolMockRecorder) GrantServiceAdmin(ctx, servicekey, admin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantServiceAdmin", reflect.TypeOf((*MockKontrol)(nil).GrantServiceAdmin), ctx, servicekey, admin)
+}
+
+// IdentifyAccess mocks base method.
+func (m *MockKontrol) IdentifyAccess(c context.Context, token string, req *AccessRequest) (*Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdentifyAccess", c, token, req)
+	ret0, _ := ret[0].(*Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IdentifyAccess indicates an expected call of IdentifyAccess.
+func (mr *MockKontrolMockRecorder) IdentifyAccess(c, token, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdentifyAccess", reflect.TypeOf((*MockKontrol)(nil).IdentifyAccess), c, token, req)
+}
+
+// IdentifyRequest mocks base method.
+func (m *MockKontrol) IdentifyRequest(c context.Context, req *AccessRequest) (*Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdentifyRequest", c, req)
+	ret0, _ := ret[0].(*Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IdentifyRequest indicates an expected call of IdentifyRequest.
+func (mr *MockKontrolMockRecorder) IdentifyRequest(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdentifyRequest", reflect.TypeOf((*MockKontrol)(nil).IdentifyRequest), c, req)
+}
+
+// InstantiatePolicyTemplate mocks base method.
+func (m *MockKontrol) InstantiatePolicyTemplate(ctx context.Context, servicekey, templateID string, policy *Policy, params map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstantiatePolicyTemplate", ctx, servicekey, templateID, policy, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstantiatePolicyTemplate indicates an expected call of InstantiatePolicyTemplate.
+func (mr *MockKontrolMockRecorder) InstantiatePolicyTemplate(ctx, servicekey, templateID, policy, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstantiatePolicyTemplate", reflect.TypeOf((*MockKontrol)(nil).InstantiatePolicyTemplate), ctx, servicekey, templateID, policy, params)
+}
+
 // IssueCertForClient mocks base method.
 func (m *MockKontrol) IssueCertForClient(ctx context.Context, externalID, serID string) (*ObjectPermission, error) {
 	m.ctrl.T.Helper()
@@ -125,6 +405,137 @@ func (mr *MockKontrolMockRecorder) IssueCertForService(ctx, objID, externalid in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueCertForService", reflect.TypeOf((*MockKontrol)(nil).IssueCertForService), ctx, objID, externalid)
 }
 
+// LintPolicies mocks base method.
+func (m *MockKontrol) LintPolicies(ctx context.Context, servicekey, serviceID string) ([]*LintIssue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LintPolicies", ctx, servicekey, serviceID)
+	ret0, _ := ret[0].([]*LintIssue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LintPolicies indicates an expected call of LintPolicies.
+func (mr *MockKontrolMockRecorder) LintPolicies(ctx, servicekey, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LintPolicies", reflect.TypeOf((*MockKontrol)(nil).LintPolicies), ctx, servicekey, serviceID)
+}
+
+// OriginCORS mocks base method.
+func (m *MockKontrol) OriginCORS(ctx context.Context, origin string) (*CORS, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OriginCORS", ctx, origin)
+	ret0, _ := ret[0].(*CORS)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OriginCORS indicates an expected call of OriginCORS.
+func (mr *MockKontrolMockRecorder) OriginCORS(ctx, origin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OriginCORS", reflect.TypeOf((*MockKontrol)(nil).OriginCORS), ctx, origin)
+}
+
+// PlanManifest mocks base method.
+func (m_2 *MockKontrol) PlanManifest(ctx context.Context, m *Manifest) ([]*PlanChange, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "PlanManifest", ctx, m)
+	ret0, _ := ret[0].([]*PlanChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlanManifest indicates an expected call of PlanManifest.
+func (mr *MockKontrolMockRecorder) PlanManifest(ctx, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlanManifest", reflect.TypeOf((*MockKontrol)(nil).PlanManifest), ctx, m)
+}
+
+// RequestServiceGrant mocks base method.
+func (m *MockKontrol) RequestServiceGrant(ctx context.Context, servicekey, objectID, serviceID string, expiresAt int64) (*ObjectServiceMess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestServiceGrant", ctx, servicekey, objectID, serviceID, expiresAt)
+	ret0, _ := ret[0].(*ObjectServiceMess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestServiceGrant indicates an expected call of RequestServiceGrant.
+func (mr *MockKontrolMockRecorder) RequestServiceGrant(ctx, servicekey, objectID, serviceID, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestServiceGrant", reflect.TypeOf((*MockKontrol)(nil).RequestServiceGrant), ctx, servicekey, objectID, serviceID, expiresAt)
+}
+
+// ResolveService mocks base method.
+func (m *MockKontrol) ResolveService(c context.Context, req *AccessRequest) (*Service, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveService", c, req)
+	ret0, _ := ret[0].(*Service)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveService indicates an expected call of ResolveService.
+func (mr *MockKontrolMockRecorder) ResolveService(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveService", reflect.TypeOf((*MockKontrol)(nil).ResolveService), c, req)
+}
+
+// RevokeServiceAdmin mocks base method.
+func (m *MockKontrol) RevokeServiceAdmin(ctx context.Context, servicekey string, admin *ServiceAdmin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeServiceAdmin", ctx, servicekey, admin)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeServiceAdmin indicates an expected call of RevokeServiceAdmin.
+func (mr *MockKontrolMockRecorder) RevokeServiceAdmin(ctx, servicekey, admin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeServiceAdmin", reflect.TypeOf((*MockKontrol)(nil).RevokeServiceAdmin), ctx, servicekey, admin)
+}
+
+// RevokeServiceGrant mocks base method.
+func (m *MockKontrol) RevokeServiceGrant(ctx context.Context, servicekey, objectID, serviceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeServiceGrant", ctx, servicekey, objectID, serviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeServiceGrant indicates an expected call of RevokeServiceGrant.
+func (mr *MockKontrolMockRecorder) RevokeServiceGrant(ctx, servicekey, objectID, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeServiceGrant", reflect.TypeOf((*MockKontrol)(nil).RevokeServiceGrant), ctx, servicekey, objectID, serviceID)
+}
+
+// RevokeSigningKey mocks base method.
+func (m *MockKontrol) RevokeSigningKey(ctx context.Context, servicekey, keyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSigningKey", ctx, servicekey, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSigningKey indicates an expected call of RevokeSigningKey.
+func (mr *MockKontrolMockRecorder) RevokeSigningKey(ctx, servicekey, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSigningKey", reflect.TypeOf((*MockKontrol)(nil).RevokeSigningKey), ctx, servicekey, keyID)
+}
+
+// SignLoginReturn mocks base method.
+func (m *MockKontrol) SignLoginReturn(r *LoginReturn) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignLoginReturn", r)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// SignLoginReturn indicates an expected call of SignLoginReturn.
+func (mr *MockKontrolMockRecorder) SignLoginReturn(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignLoginReturn", reflect.TypeOf((*MockKontrol)(nil).SignLoginReturn), r)
+}
+
 // UpdateObject mocks base method.
 func (m *MockKontrol) UpdateObject(ctx context.Context, obj *Object, servicekey string) error {
 	m.ctrl.T.Helper()
@@ -153,6 +564,36 @@ func (mr *MockKontrolMockRecorder) UpdatePolicy(ctx, servicekey, policy interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePolicy", reflect.TypeOf((*MockKontrol)(nil).UpdatePolicy), ctx, servicekey, policy)
 }
 
+// UpdatePolicyTemplate mocks base method.
+func (m *MockKontrol) UpdatePolicyTemplate(ctx context.Context, servicekey string, tpl *PolicyTemplate) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePolicyTemplate", ctx, servicekey, tpl)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePolicyTemplate indicates an expected call of UpdatePolicyTemplate.
+func (mr *MockKontrolMockRecorder) UpdatePolicyTemplate(ctx, servicekey, tpl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePolicyTemplate", reflect.TypeOf((*MockKontrol)(nil).UpdatePolicyTemplate), ctx, servicekey, tpl)
+}
+
+// ValidateAccess mocks base method.
+func (m *MockKontrol) ValidateAccess(c context.Context, token string, req *AccessRequest) (*Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAccess", c, token, req)
+	ret0, _ := ret[0].(*Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateAccess indicates an expected call of ValidateAccess.
+func (mr *MockKontrolMockRecorder) ValidateAccess(c, token, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAccess", reflect.TypeOf((*MockKontrol)(nil).ValidateAccess), c, token, req)
+}
+
 // ValidateToken mocks base method.
 func (m *MockKontrol) ValidateToken(c context.Context, token, reqPath, reqMethod string) (*Object, error) {
 	m.ctrl.T.Helper()
@@ -168,6 +609,20 @@ func (mr *MockKontrolMockRecorder) ValidateToken(c, token, reqPath, reqMethod in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateToken", reflect.TypeOf((*MockKontrol)(nil).ValidateToken), c, token, reqPath, reqMethod)
 }
 
+// VerifyLoginReturn mocks base method.
+func (m *MockKontrol) VerifyLoginReturn(r *LoginReturn, sig string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyLoginReturn", r, sig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyLoginReturn indicates an expected call of VerifyLoginReturn.
+func (mr *MockKontrolMockRecorder) VerifyLoginReturn(r, sig interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyLoginReturn", reflect.TypeOf((*MockKontrol)(nil).VerifyLoginReturn), r, sig)
+}
+
 // MockKontrolStore is a mock of KontrolStore interface.
 type MockKontrolStore struct {
 	ctrl     *gomock.Controller
@@ -191,6 +646,34 @@ func (m *MockKontrolStore) EXPECT() *MockKontrolStoreMockRecorder {
 	return m.recorder
 }
 
+// CreateAuditEvent mocks base method.
+func (m *MockKontrolStore) CreateAuditEvent(c context.Context, event *AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", c, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockKontrolStoreMockRecorder) CreateAuditEvent(c, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockKontrolStore)(nil).CreateAuditEvent), c, event)
+}
+
+// CreateElevation mocks base method.
+func (m *MockKontrolStore) CreateElevation(c context.Context, elevation *Elevation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateElevation", c, elevation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateElevation indicates an expected call of CreateElevation.
+func (mr *MockKontrolStoreMockRecorder) CreateElevation(c, elevation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateElevation", reflect.TypeOf((*MockKontrolStore)(nil).CreateElevation), c, elevation)
+}
+
 // CreateObject mocks base method.
 func (m *MockKontrolStore) CreateObject(c context.Context, obj *Object) error {
 	m.ctrl.T.Helper()
@@ -219,6 +702,188 @@ func (mr *MockKontrolStoreMockRecorder) CreatePolicy(c, policy interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePolicy", reflect.TypeOf((*MockKontrolStore)(nil).CreatePolicy), c, policy)
 }
 
+// CreatePolicyTemplate mocks base method.
+func (m *MockKontrolStore) CreatePolicyTemplate(c context.Context, tpl *PolicyTemplate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePolicyTemplate", c, tpl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePolicyTemplate indicates an expected call of CreatePolicyTemplate.
+func (mr *MockKontrolStoreMockRecorder) CreatePolicyTemplate(c, tpl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePolicyTemplate", reflect.TypeOf((*MockKontrolStore)(nil).CreatePolicyTemplate), c, tpl)
+}
+
+// CreateServiceAdmin mocks base method.
+func (m *MockKontrolStore) CreateServiceAdmin(c context.Context, admin *ServiceAdmin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServiceAdmin", c, admin)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateServiceAdmin indicates an expected call of CreateServiceAdmin.
+func (mr *MockKontrolStoreMockRecorder) CreateServiceAdmin(c, admin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServiceAdmin", reflect.TypeOf((*MockKontrolStore)(nil).CreateServiceAdmin), c, admin)
+}
+
+// CreateServiceAttachment mocks base method.
+func (m *MockKontrolStore) CreateServiceAttachment(c context.Context, attachment *ServiceAttachment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServiceAttachment", c, attachment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateServiceAttachment indicates an expected call of CreateServiceAttachment.
+func (mr *MockKontrolStoreMockRecorder) CreateServiceAttachment(c, attachment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServiceAttachment", reflect.TypeOf((*MockKontrolStore)(nil).CreateServiceAttachment), c, attachment)
+}
+
+// CreateServiceGrant mocks base method.
+func (m *MockKontrolStore) CreateServiceGrant(c context.Context, grant *ObjectServiceMess) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServiceGrant", c, grant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateServiceGrant indicates an expected call of CreateServiceGrant.
+func (mr *MockKontrolStoreMockRecorder) CreateServiceGrant(c, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServiceGrant", reflect.TypeOf((*MockKontrolStore)(nil).CreateServiceGrant), c, grant)
+}
+
+// CreateSigningKey mocks base method.
+func (m *MockKontrolStore) CreateSigningKey(c context.Context, key *SigningKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSigningKey", c, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSigningKey indicates an expected call of CreateSigningKey.
+func (mr *MockKontrolStoreMockRecorder) CreateSigningKey(c, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSigningKey", reflect.TypeOf((*MockKontrolStore)(nil).CreateSigningKey), c, key)
+}
+
+// DeleteElevation mocks base method.
+func (m *MockKontrolStore) DeleteElevation(c context.Context, elevation *Elevation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteElevation", c, elevation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteElevation indicates an expected call of DeleteElevation.
+func (mr *MockKontrolStoreMockRecorder) DeleteElevation(c, elevation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteElevation", reflect.TypeOf((*MockKontrolStore)(nil).DeleteElevation), c, elevation)
+}
+
+// DeletePolicy mocks base method.
+func (m *MockKontrolStore) DeletePolicy(c context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePolicy", c, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePolicy indicates an expected call of DeletePolicy.
+func (mr *MockKontrolStoreMockRecorder) DeletePolicy(c, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePolicy", reflect.TypeOf((*MockKontrolStore)(nil).DeletePolicy), c, id)
+}
+
+// DeleteServiceAdmin mocks base method.
+func (m *MockKontrolStore) DeleteServiceAdmin(c context.Context, admin *ServiceAdmin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServiceAdmin", c, admin)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServiceAdmin indicates an expected call of DeleteServiceAdmin.
+func (mr *MockKontrolStoreMockRecorder) DeleteServiceAdmin(c, admin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServiceAdmin", reflect.TypeOf((*MockKontrolStore)(nil).DeleteServiceAdmin), c, admin)
+}
+
+// DeleteServiceAttachment mocks base method.
+func (m *MockKontrolStore) DeleteServiceAttachment(c context.Context, attachment *ServiceAttachment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServiceAttachment", c, attachment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServiceAttachment indicates an expected call of DeleteServiceAttachment.
+func (mr *MockKontrolStoreMockRecorder) DeleteServiceAttachment(c, attachment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServiceAttachment", reflect.TypeOf((*MockKontrolStore)(nil).DeleteServiceAttachment), c, attachment)
+}
+
+// DeleteServiceGrant mocks base method.
+func (m *MockKontrolStore) DeleteServiceGrant(c context.Context, grant *ObjectServiceMess) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServiceGrant", c, grant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServiceGrant indicates an expected call of DeleteServiceGrant.
+func (mr *MockKontrolStoreMockRecorder) DeleteServiceGrant(c, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServiceGrant", reflect.TypeOf((*MockKontrolStore)(nil).DeleteServiceGrant), c, grant)
+}
+
+// DeleteSignatureNonces mocks base method.
+func (m *MockKontrolStore) DeleteSignatureNonces(c context.Context, timestamp int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSignatureNonces", c, timestamp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSignatureNonces indicates an expected call of DeleteSignatureNonces.
+func (mr *MockKontrolStoreMockRecorder) DeleteSignatureNonces(c, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSignatureNonces", reflect.TypeOf((*MockKontrolStore)(nil).DeleteSignatureNonces), c, timestamp)
+}
+
+// DeleteSigningKey mocks base method.
+func (m *MockKontrolStore) DeleteSigningKey(c context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSigningKey", c, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSigningKey indicates an expected call of DeleteSigningKey.
+func (mr *MockKontrolStoreMockRecorder) DeleteSigningKey(c, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSigningKey", reflect.TypeOf((*MockKontrolStore)(nil).DeleteSigningKey), c, id)
+}
+
+// ExpireObject mocks base method.
+func (m *MockKontrolStore) ExpireObject(c context.Context, objectID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireObject", c, objectID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExpireObject indicates an expected call of ExpireObject.
+func (mr *MockKontrolStoreMockRecorder) ExpireObject(c, objectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireObject", reflect.TypeOf((*MockKontrolStore)(nil).ExpireObject), c, objectID)
+}
+
 // ExpiredObjectsByPolicy mocks base method.
 func (m *MockKontrolStore) ExpiredObjectsByPolicy(c context.Context, policyId string) error {
 	m.ctrl.T.Helper()
@@ -233,6 +898,36 @@ func (mr *MockKontrolStoreMockRecorder) ExpiredObjectsByPolicy(c, policyId inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpiredObjectsByPolicy", reflect.TypeOf((*MockKontrolStore)(nil).ExpiredObjectsByPolicy), c, policyId)
 }
 
+// GetLapsedElevations mocks base method.
+func (m *MockKontrolStore) GetLapsedElevations(c context.Context, timestamp int64) ([]*Elevation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLapsedElevations", c, timestamp)
+	ret0, _ := ret[0].([]*Elevation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLapsedElevations indicates an expected call of GetLapsedElevations.
+func (mr *MockKontrolStoreMockRecorder) GetLapsedElevations(c, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLapsedElevations", reflect.TypeOf((*MockKontrolStore)(nil).GetLapsedElevations), c, timestamp)
+}
+
+// GetLapsedServiceGrants mocks base method.
+func (m *MockKontrolStore) GetLapsedServiceGrants(c context.Context, timestamp int64) ([]*ObjectServiceMess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLapsedServiceGrants", c, timestamp)
+	ret0, _ := ret[0].([]*ObjectServiceMess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLapsedServiceGrants indicates an expected call of GetLapsedServiceGrants.
+func (mr *MockKontrolStoreMockRecorder) GetLapsedServiceGrants(c, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLapsedServiceGrants", reflect.TypeOf((*MockKontrolStore)(nil).GetLapsedServiceGrants), c, timestamp)
+}
+
 // GetObjectByExternalID mocks base method.
 func (m *MockKontrolStore) GetObjectByExternalID(c context.Context, extid, serviceid string) (*Object, error) {
 	m.ctrl.T.Helper()
@@ -293,6 +988,66 @@ func (mr *MockKontrolStoreMockRecorder) GetObjectServiceMesh(c, objectId interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectServiceMesh", reflect.TypeOf((*MockKontrolStore)(nil).GetObjectServiceMesh), c, objectId)
 }
 
+// GetOrganizationByID mocks base method.
+func (m *MockKontrolStore) GetOrganizationByID(c context.Context, id string) (*Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrganizationByID", c, id)
+	ret0, _ := ret[0].(*Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrganizationByID indicates an expected call of GetOrganizationByID.
+func (mr *MockKontrolStoreMockRecorder) GetOrganizationByID(c, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrganizationByID", reflect.TypeOf((*MockKontrolStore)(nil).GetOrganizationByID), c, id)
+}
+
+// GetPoliciesByServiceID mocks base method.
+func (m *MockKontrolStore) GetPoliciesByServiceID(c context.Context, serviceID string) ([]*Policy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoliciesByServiceID", c, serviceID)
+	ret0, _ := ret[0].([]*Policy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoliciesByServiceID indicates an expected call of GetPoliciesByServiceID.
+func (mr *MockKontrolStoreMockRecorder) GetPoliciesByServiceID(c, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoliciesByServiceID", reflect.TypeOf((*MockKontrolStore)(nil).GetPoliciesByServiceID), c, serviceID)
+}
+
+// GetPoliciesByTemplateID mocks base method.
+func (m *MockKontrolStore) GetPoliciesByTemplateID(c context.Context, templateID string) ([]*Policy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoliciesByTemplateID", c, templateID)
+	ret0, _ := ret[0].([]*Policy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoliciesByTemplateID indicates an expected call of GetPoliciesByTemplateID.
+func (mr *MockKontrolStoreMockRecorder) GetPoliciesByTemplateID(c, templateID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoliciesByTemplateID", reflect.TypeOf((*MockKontrolStore)(nil).GetPoliciesByTemplateID), c, templateID)
+}
+
+// GetPoliciesCrossingWindow mocks base method.
+func (m *MockKontrolStore) GetPoliciesCrossingWindow(c context.Context, from, to int64) ([]*Policy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoliciesCrossingWindow", c, from, to)
+	ret0, _ := ret[0].([]*Policy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoliciesCrossingWindow indicates an expected call of GetPoliciesCrossingWindow.
+func (mr *MockKontrolStoreMockRecorder) GetPoliciesCrossingWindow(c, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoliciesCrossingWindow", reflect.TypeOf((*MockKontrolStore)(nil).GetPoliciesCrossingWindow), c, from, to)
+}
+
 // GetPolicyByID mocks base method.
 func (m *MockKontrolStore) GetPolicyByID(c context.Context, id string) (*Policy, error) {
 	m.ctrl.T.Helper()
@@ -308,6 +1063,51 @@ func (mr *MockKontrolStoreMockRecorder) GetPolicyByID(c, id interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicyByID", reflect.TypeOf((*MockKontrolStore)(nil).GetPolicyByID), c, id)
 }
 
+// GetPolicyTemplateByID mocks base method.
+func (m *MockKontrolStore) GetPolicyTemplateByID(c context.Context, id string) (*PolicyTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPolicyTemplateByID", c, id)
+	ret0, _ := ret[0].(*PolicyTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPolicyTemplateByID indicates an expected call of GetPolicyTemplateByID.
+func (mr *MockKontrolStoreMockRecorder) GetPolicyTemplateByID(c, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicyTemplateByID", reflect.TypeOf((*MockKontrolStore)(nil).GetPolicyTemplateByID), c, id)
+}
+
+// GetServiceAdminRoles mocks base method.
+func (m *MockKontrolStore) GetServiceAdminRoles(c context.Context, objectID, serviceID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServiceAdminRoles", c, objectID, serviceID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceAdminRoles indicates an expected call of GetServiceAdminRoles.
+func (mr *MockKontrolStoreMockRecorder) GetServiceAdminRoles(c, objectID, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceAdminRoles", reflect.TypeOf((*MockKontrolStore)(nil).GetServiceAdminRoles), c, objectID, serviceID)
+}
+
+// GetServiceAttachments mocks base method.
+func (m *MockKontrolStore) GetServiceAttachments(c context.Context, serviceID string) ([]*ServiceAttachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServiceAttachments", c, serviceID)
+	ret0, _ := ret[0].([]*ServiceAttachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceAttachments indicates an expected call of GetServiceAttachments.
+func (mr *MockKontrolStoreMockRecorder) GetServiceAttachments(c, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceAttachments", reflect.TypeOf((*MockKontrolStore)(nil).GetServiceAttachments), c, serviceID)
+}
+
 // GetServiceByExternalId mocks base method.
 func (m *MockKontrolStore) GetServiceByExternalId(c context.Context, externalId string) (*Service, error) {
 	m.ctrl.T.Helper()
@@ -338,6 +1138,66 @@ func (mr *MockKontrolStoreMockRecorder) GetServiceByID(c, id interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceByID", reflect.TypeOf((*MockKontrolStore)(nil).GetServiceByID), c, id)
 }
 
+// GetServiceGrant mocks base method.
+func (m *MockKontrolStore) GetServiceGrant(c context.Context, objectID, serviceID string) (*ObjectServiceMess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServiceGrant", c, objectID, serviceID)
+	ret0, _ := ret[0].(*ObjectServiceMess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceGrant indicates an expected call of GetServiceGrant.
+func (mr *MockKontrolStoreMockRecorder) GetServiceGrant(c, objectID, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceGrant", reflect.TypeOf((*MockKontrolStore)(nil).GetServiceGrant), c, objectID, serviceID)
+}
+
+// GetServiceGrants mocks base method.
+func (m *MockKontrolStore) GetServiceGrants(c context.Context, serviceID string) ([]*ObjectServiceMess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServiceGrants", c, serviceID)
+	ret0, _ := ret[0].([]*ObjectServiceMess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceGrants indicates an expected call of GetServiceGrants.
+func (mr *MockKontrolStoreMockRecorder) GetServiceGrants(c, serviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceGrants", reflect.TypeOf((*MockKontrolStore)(nil).GetServiceGrants), c, serviceID)
+}
+
+// GetServices mocks base method.
+func (m *MockKontrolStore) GetServices(c context.Context) ([]*Service, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServices", c)
+	ret0, _ := ret[0].([]*Service)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServices indicates an expected call of GetServices.
+func (mr *MockKontrolStoreMockRecorder) GetServices(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServices", reflect.TypeOf((*MockKontrolStore)(nil).GetServices), c)
+}
+
+// GetSigningKey mocks base method.
+func (m *MockKontrolStore) GetSigningKey(c context.Context, id string) (*SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSigningKey", c, id)
+	ret0, _ := ret[0].(*SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSigningKey indicates an expected call of GetSigningKey.
+func (mr *MockKontrolStoreMockRecorder) GetSigningKey(c, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSigningKey", reflect.TypeOf((*MockKontrolStore)(nil).GetSigningKey), c, id)
+}
+
 // UpdateObject mocks base method.
 func (m *MockKontrolStore) UpdateObject(c context.Context, obj *Object) error {
 	m.ctrl.T.Helper()
@@ -365,3 +1225,59 @@ func (mr *MockKontrolStoreMockRecorder) UpdatePolicy(c, policy interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePolicy", reflect.TypeOf((*MockKontrolStore)(nil).UpdatePolicy), c, policy)
 }
+
+// UpdatePolicyTemplate mocks base method.
+func (m *MockKontrolStore) UpdatePolicyTemplate(c context.Context, tpl *PolicyTemplate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePolicyTemplate", c, tpl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePolicyTemplate indicates an expected call of UpdatePolicyTemplate.
+func (mr *MockKontrolStoreMockRecorder) UpdatePolicyTemplate(c, tpl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePolicyTemplate", reflect.TypeOf((*MockKontrolStore)(nil).UpdatePolicyTemplate), c, tpl)
+}
+
+// UpdateService mocks base method.
+func (m *MockKontrolStore) UpdateService(c context.Context, service *Service) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateService", c, service)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateService indicates an expected call of UpdateService.
+func (mr *MockKontrolStoreMockRecorder) UpdateService(c, service interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateService", reflect.TypeOf((*MockKontrolStore)(nil).UpdateService), c, service)
+}
+
+// UpdateServiceGrant mocks base method.
+func (m *MockKontrolStore) UpdateServiceGrant(c context.Context, grant *ObjectServiceMess) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateServiceGrant", c, grant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateServiceGrant indicates an expected call of UpdateServiceGrant.
+func (mr *MockKontrolStoreMockRecorder) UpdateServiceGrant(c, grant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateServiceGrant", reflect.TypeOf((*MockKontrolStore)(nil).UpdateServiceGrant), c, grant)
+}
+
+// UseSignatureNonce mocks base method.
+func (m *MockKontrolStore) UseSignatureNonce(c context.Context, keyID, nonce string, expiresAt int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseSignatureNonce", c, keyID, nonce, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseSignatureNonce indicates an expected call of UseSignatureNonce.
+func (mr *MockKontrolStoreMockRecorder) UseSignatureNonce(c, keyID, nonce, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseSignatureNonce", reflect.TypeOf((*MockKontrolStore)(nil).UseSignatureNonce), c, keyID, nonce, expiresAt)
+}
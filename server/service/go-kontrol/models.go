@@ -1,35 +1,86 @@
 package gokontrol
 
+import "time"
+
 //Object is basic entity
 type Object struct {
-	ID          string
-	GlobalID    string
-	ExternalID  string
-	ServiceID   string
-	Status      string
-	Attributes  map[string]interface{} // ignore for now, extension
-	Token       string
-	ExpiryDate  int64
-	ApplyPolicy []*Policy
+	ID             string
+	GlobalID       string
+	ExternalID     string
+	ServiceID      string
+	OrganizationID string
+	Status         string
+	Attributes     map[string]interface{} // nil keeps the stored attributes on update
+	Token          string
+	ExpiryDate     int64
+	ApplyPolicy    []*Policy    // permanent and elevated policies
+	Elevations     []*Elevation // time bound grants among ApplyPolicy
 }
 
 //Service is a registered serviced
 type Service struct {
-	ID            string
-	ServiceID     string
-	Name          string
-	Key           string
-	Status        string
-	ExpiryDate    int64
-	DefaultPolicy []*Policy
-	EnforcePolicy []*Policy
+	ID                 string
+	ServiceID          string
+	OrganizationID     string
+	Name               string
+	Key                string
+	Status             string
+	ExpiryDate         int64
+	Routes             []string // request samples of its API as METHOD@/path, checked by the policy linter
+	IdentityAttributes []string // object attributes forwarded to the service with the identity headers
+	Routing            ServiceRouting
+	TokenSources       []*TokenSource  // where tokens of requests to the service are read, bearer header if none
+	Network            Network         // client IP conditions of every request to the service, checked before the permission
+	Anonymous          AnonymousAccess // requests without a token it allows, none if empty
+	CORS               *CORS           // browser origins allowed to call it, none if nil
+	DefaultPolicy      []*Policy
+	EnforcePolicy      []*Policy
+}
+
+//ServiceRouting how the gateway reaches a service, services without upstream are not routed
+type ServiceRouting struct {
+	Host        string `json:"host,omitempty" yaml:"host,omitempty"`               // any host if empty
+	PathPrefix  string `json:"path_prefix,omitempty" yaml:"path_prefix,omitempty"` // /<service id>/ if empty
+	Upstream    string `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Regex       string `json:"regex,omitempty" yaml:"regex,omitempty"` // path rewrite as replacePathRegex, none if empty
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+//TokenSource where a service reads the token of a request, sources are tried in order
+type TokenSource struct {
+	Type  string   `json:"type" yaml:"type"`
+	Name  string   `json:"name,omitempty" yaml:"name,omitempty"`   // cookie, header or query parameter name
+	Paths []string `json:"paths,omitempty" yaml:"paths,omitempty"` // query: path prefixes inside the service it is read on
+}
+
+//AnonymousAccess requests to a service without a token, requests with an invalid one are still rejected
+type AnonymousAccess struct {
+	Permission []string `json:"permission,omitempty" yaml:"permission,omitempty"` // keys allowed to anyone, as policy keys
+	ObjectID   string   `json:"object_id,omitempty" yaml:"object_id,omitempty"`   // object of the service anonymous requests are identified as, its policies on the service extend Permission
+}
+
+//Organization tenant owning services, their objects and policies
+type Organization struct {
+	ID     string
+	Name   string
+	Key    string
+	Status string
 }
 
 //ObjectServiceMess support for grand permission access cross service
 type ObjectServiceMess struct {
-	ID        string
+	ID        string `json:"id"`
+	ServiceID string `json:"service_id"`
+	ObjectID  string `json:"object_id"`
+	Status    string `json:"status"`     // pending until the target service admin consents
+	ExpiresAt int64  `json:"expires_at"` // 0 for grants without expiry
+}
+
+//ServiceAttachment default or enforce policy of a service
+type ServiceAttachment struct {
 	ServiceID string
-	ObjectID  string
+	PolicyID  string
+	Type      string
 }
 
 //ObjectPermission Contains object and it's permission
@@ -39,13 +90,38 @@ type ObjectPermission struct {
 }
 
 type Policy struct {
-	ID         string
-	Name       string
-	ServiceID  string
-	Permission map[string]int
-	Status     string
-	ApplyFrom  int64
-	ApplyTo    int64
+	ID             string
+	Name           string
+	ServiceID      string
+	OrganizationID string
+	Permission     map[string]int
+	Status         string
+	ApplyFrom      int64
+	ApplyTo        int64
+	Schedule       []*PolicySchedule // recurring windows inside ApplyFrom/ApplyTo, empty means always
+	MaxElevation   int64             // longest elevation in seconds, 0 means the policy can not be elevated to
+	TemplateID     string            // template the permission is bound from, if any
+	Parameters     map[string]string // template parameter values
+	RateLimit      RateLimit         // object bucket of the objects it applies to, the highest rate wins, none if 0
+	Network        Network           // client IP conditions of the tokens it is issued into, on requests to its service
+}
+
+//PolicyTemplate permission with {parameter} placeholders in its keys, instantiated into bound policies
+type PolicyTemplate struct {
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	ServiceID      string         `json:"service_id"`
+	OrganizationID string         `json:"organization_id"`
+	Parameters     []string       `json:"parameters"`
+	Permission     map[string]int `json:"permission"`
+}
+
+//PolicySchedule recurring wall clock window in which a policy applies
+type PolicySchedule struct {
+	Days     []time.Weekday `json:"days" yaml:"days,omitempty"`           // days the window opens on, empty means every day
+	From     string         `json:"from" yaml:"from"`                     // opening time, format 15:04
+	To       string         `json:"to" yaml:"to"`                         // closing time, format 15:04, not after From means next day
+	TimeZone string         `json:"time_zone" yaml:"time_zone,omitempty"` // IANA time zone, empty means UTC
 }
 
 type CertForSign struct {
@@ -64,3 +140,55 @@ type Certificate struct {
 	Token      string `json:"token"`
 	ExpiryDate int64  `json:"expiry_date"`
 }
+
+//ServiceAdmin role of an admin object on a service
+type ServiceAdmin struct {
+	ObjectID  string `json:"object_id"`
+	ServiceID string `json:"service_id"`
+	Role      string `json:"role"`
+}
+
+//Elevation time bound grant of a policy to an object
+type Elevation struct {
+	ObjectID  string `json:"object_id"`
+	PolicyID  string `json:"policy_id"`
+	Reason    string `json:"reason"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+//SigningKey key a machine client signs its requests with as the object. The secret is random and only returned on
+//creation, it is stored sealed with AES-GCM in Sealed
+type SigningKey struct {
+	ID             string `json:"id"`
+	ObjectID       string `json:"object_id"`
+	OrganizationID string `json:"organization_id"`
+	Secret         string `json:"secret,omitempty"` // only returned on creation
+	Sealed         string `json:"-"`                // secret as stored
+}
+
+//BreakGlassAccount emergency account, Secret is sealed as service keys are
+type BreakGlassAccount struct {
+	Name     string
+	Secret   string
+	Services []string // service ids the account can open, empty means all
+}
+
+//BreakGlassRequest emergency login of an account on one service
+type BreakGlassRequest struct {
+	Account   string
+	Secret    string
+	ServiceID string
+	Reason    string
+	ClientIP  string
+}
+
+//AuditEvent security relevant event kept for review
+type AuditEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Actor     string `json:"actor"`
+	ServiceID string `json:"service_id"`
+	Reason    string `json:"reason"`
+	ClientIP  string `json:"client_ip"`
+	Detail    string `json:"detail"`
+}
@@ -0,0 +1,164 @@
+package gokontrol
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+//Network client IP conditions as CIDRs or single addresses, Deny is checked first and an empty Allow allows any
+//address not denied
+type Network struct {
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+//PolicyNetwork network condition a token carries from one of its policies, applied on requests to ServiceID
+type PolicyNetwork struct {
+	PolicyID  string  `json:"policy_id"`
+	ServiceID string  `json:"service_id"`
+	Network   Network `json:"network"`
+}
+
+//NetworkError client IP outside a network condition, Reason names the condition for the denial response
+type NetworkError struct {
+	ClientIP string
+	Reason   string
+}
+
+func (e *NetworkError) Error() string {
+	return CommonError.NETWORK_DENIED.Error() + ": " + e.Reason
+}
+
+//ParseCIDRs parse CIDRs, a single address is a network of its own
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if ip := net.ParseIP(v); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, CommonError.INVALID_NETWORK
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+//Validate check every entry is a CIDR or an address
+func (n Network) Validate() error {
+	if _, err := ParseCIDRs(n.Allow); err != nil {
+		return err
+	}
+	_, err := ParseCIDRs(n.Deny)
+	return err
+}
+
+//Empty whether the condition allows any client
+func (n Network) Empty() bool {
+	return len(n.Allow) == 0 && len(n.Deny) == 0
+}
+
+// check clientIP against the condition of owner, named in the reason of the denial. Entries are validated on save,
+// unparsable ones never match
+func (n Network) check(clientIP string, owner string) error {
+	if n.Empty() {
+		return nil
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return &NetworkError{ClientIP: clientIP, Reason: fmt.Sprintf("no client ip for the network condition of %s", owner)}
+	}
+	if cidr, ok := matchCIDR(n.Deny, ip); ok {
+		return &NetworkError{ClientIP: clientIP, Reason: fmt.Sprintf("client ip %s is denied by %s of %s", clientIP, cidr, owner)}
+	}
+	if _, ok := matchCIDR(n.Allow, ip); len(n.Allow) > 0 && !ok {
+		return &NetworkError{ClientIP: clientIP, Reason: fmt.Sprintf("client ip %s is not in the allow list of %s", clientIP, owner)}
+	}
+	return nil
+}
+
+// matchCIDR first entry of values containing ip
+func matchCIDR(values []string, ip net.IP) (string, bool) {
+	for _, v := range values {
+		nets, err := ParseCIDRs([]string{v})
+		if err == nil && nets[0].Contains(ip) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// policyNetworks network conditions of the policies, the token carries them all and each one applies
+func policyNetworks(policies ...[]*Policy) []*PolicyNetwork {
+	var conditions []*PolicyNetwork
+	for _, ps := range policies {
+		for _, p := range ps {
+			if !p.Network.Empty() {
+				conditions = append(conditions, &PolicyNetwork{PolicyID: p.ID, ServiceID: p.ServiceID, Network: p.Network})
+			}
+		}
+	}
+	return conditions
+}
+
+// checkNetwork check clientIP against the conditions of the service and of the token policies on it
+func checkNetwork(clientIP string, service *Service, conditions []*PolicyNetwork) error {
+	if err := service.Network.check(clientIP, "service "+service.ServiceID); err != nil {
+		return err
+	}
+	for _, c := range conditions {
+		if c.ServiceID != service.ID {
+			continue
+		}
+		if err := c.Network.check(clientIP, "policy "+c.PolicyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clientIP address of the client of req: walking the X-Forwarded-For hops leftward from the peer, the first one
+// which is not a trusted proxy. Without trusted proxies it is the peer the transport found
+func (k DefaultKontrol) clientIP(req *AccessRequest) string {
+	if len(k.Option.TrustedProxies) == 0 {
+		return req.ClientIP
+	}
+	var hops []string
+	if req.Header != nil {
+		for _, h := range strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hops = append(hops, h)
+			}
+		}
+	}
+	if req.ClientIP != "" && (len(hops) == 0 || hops[len(hops)-1] != req.ClientIP) {
+		hops = append(hops, req.ClientIP)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if i == 0 || !k.trustedProxy(hops[i]) {
+			return hops[i]
+		}
+	}
+	return ""
+}
+
+func (k DefaultKontrol) trustedProxy(hop string) bool {
+	ip := net.ParseIP(hop)
+	if ip == nil {
+		return false
+	}
+	for _, n := range k.Option.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
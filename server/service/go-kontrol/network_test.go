@@ -0,0 +1,129 @@
+package gokontrol
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestNetwork_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		network Network
+		wantErr bool
+	}{
+		{name: "#1: none", network: Network{}},
+		{name: "#2: cidrs and addresses", network: Network{Allow: []string{"10.8.0.0/16", "2001:db8::/32"}, Deny: []string{"10.8.4.2"}}},
+		{name: "#3: malformed allow", network: Network{Allow: []string{"10.8.0.0/33"}}, wantErr: true},
+		{name: "#4: malformed deny", network: Network{Deny: []string{"vpn"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.network.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNetwork_check(t *testing.T) {
+	vpn := Network{Allow: []string{"10.8.0.0/16"}, Deny: []string{"10.8.4.2"}}
+	tests := []struct {
+		name     string
+		network  Network
+		clientIP string
+		reason   string // empty when allowed
+	}{
+		{name: "#1: no condition", network: Network{}, clientIP: ""},
+		{name: "#2: allowed", network: vpn, clientIP: "10.8.1.1"},
+		{name: "#3: outside the allow list", network: vpn, clientIP: "203.0.113.7", reason: "client ip 203.0.113.7 is not in the allow list of service admin"},
+		{name: "#4: denied first", network: vpn, clientIP: "10.8.4.2", reason: "client ip 10.8.4.2 is denied by 10.8.4.2 of service admin"},
+		{name: "#5: deny only", network: Network{Deny: []string{"203.0.113.0/24"}}, clientIP: "198.51.100.1"},
+		{name: "#6: unknown client", network: vpn, clientIP: "", reason: "no client ip for the network condition of service admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.network.check(tt.clientIP, "service admin")
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("check() error = %v", err)
+				}
+				return
+			}
+			denied, ok := err.(*NetworkError)
+			if !ok || denied.Reason != tt.reason {
+				t.Errorf("check() error = %v, want %q", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_clientIP(t *testing.T) {
+	proxies, err := ParseCIDRs([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		trusted  bool
+		xff      string
+		clientIP string
+		want     string
+	}{
+		{name: "#1: no trusted proxy, the peer", xff: "198.51.100.1, 10.0.0.5", clientIP: "10.0.0.5", want: "10.0.0.5"},
+		{name: "#2: trusted hops skipped", trusted: true, xff: "198.51.100.1, 192.0.2.10, 10.0.0.5", clientIP: "10.0.0.5", want: "198.51.100.1"},
+		{name: "#3: spoofed hop left of the client ignored", trusted: true, xff: "10.8.0.1, 198.51.100.1, 10.0.0.5", clientIP: "10.0.0.5", want: "198.51.100.1"},
+		{name: "#4: untrusted peer", trusted: true, xff: "10.8.0.1", clientIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "#5: only trusted hops, the leftmost", trusted: true, xff: "10.0.0.7", clientIP: "10.0.0.5", want: "10.0.0.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := DefaultKontrol{}
+			if tt.trusted {
+				k.Option.TrustedProxies = proxies
+			}
+			req := &AccessRequest{ClientIP: tt.clientIP, Header: http.Header{"X-Forwarded-For": {tt.xff}}}
+			if got := k.clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_IdentifyRequest_Network(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	option := DefaultKontrolOption
+	option.TrustedProxies, _ = ParseCIDRs([]string{"10.0.0.0/24"})
+	option.CacheSize, option.CacheTTL = 10, 60
+	k := NewKontrol(store, option)
+	obj := &Object{ID: "oid", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := k.CreateCert(obj, []*Policy{{ID: "ops", ServiceID: "sid-b", Permission: map[string]int{"GET@/": 1}, Network: Network{Deny: []string{"10.8.9.0/24"}}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "admin").Return(&Service{ID: "sid-b", ServiceID: "admin", Network: Network{Allow: []string{"10.8.0.0/16"}}}, nil).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).Times(1)
+
+	identify := func(xff string) error {
+		header := http.Header{"Authorization": {"Bearer " + jwtToken}, "X-Forwarded-For": {xff}}
+		_, err := k.IdentifyRequest(context.Background(), &AccessRequest{Method: http.MethodGet, Path: "/admin/users", ClientIP: "10.0.0.5", Header: header})
+		return err
+	}
+	if err := identify("10.8.1.1, 10.0.0.5"); err != nil {
+		t.Fatalf("#1: IdentifyRequest() error = %v", err)
+	}
+	// decision cached, the conditions still apply
+	if err := identify("203.0.113.7, 10.0.0.5"); err == nil || !strings.Contains(err.Error(), "allow list of service admin") {
+		t.Errorf("#2: IdentifyRequest() error = %v, want outside the allow list of the service", err)
+	}
+	if err := identify("10.8.9.4, 10.0.0.5"); err == nil || !strings.Contains(err.Error(), "of policy ops") {
+		t.Errorf("#3: IdentifyRequest() error = %v, want denied by the policy", err)
+	}
+}
@@ -0,0 +1,39 @@
+package gokontrol
+
+import (
+	"context"
+	"strings"
+)
+
+type contextKey string
+
+const organizationContextKey contextKey = "kontrol.organization"
+
+//WithOrganization scope ctx to an authenticated organization, stores only read and write its rows
+func WithOrganization(ctx context.Context, organizationID string) context.Context {
+	return context.WithValue(ctx, organizationContextKey, organizationID)
+}
+
+//OrganizationFromContext organization ctx is scoped to, if any
+func OrganizationFromContext(ctx context.Context) (string, bool) {
+	orgID, ok := ctx.Value(organizationContextKey).(string)
+	return orgID, ok && orgID != ""
+}
+
+//AuthenticateOrganization check the organization admin credentials
+func (k DefaultKontrol) AuthenticateOrganization(ctx context.Context, organizationID string, key string) (*Organization, error) {
+	org, err := k.store.GetOrganizationByID(ctx, organizationID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if org == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_ORGANIZATION
+	}
+	if org.Status != OrganizationStatus.ENABLE {
+		return nil, CommonError.INVALID_ORGANIZATION
+	}
+	if strings.Compare(k.serviceKeySign(key), org.Key) != 0 {
+		return nil, CommonError.INVALID_TOKEN
+	}
+	return org, nil
+}
@@ -0,0 +1,246 @@
+package gokontrol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_UpdateObject_Organization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	signed := DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("service-key")
+	service := &Service{ID: "sid", OrganizationID: "org-a", Key: signed, Status: "enable"}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		key     string
+		store   func() KontrolStore
+		obj     *Object
+		wantErr error
+	}{
+		{name: "#1: policy of the same organization --> stored under the service organization",
+			ctx: context.Background(),
+			key: "service-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(&Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-a"}, nil)
+				kontrolStore.EXPECT().UpdateObject(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, obj *Object) error {
+					if obj.OrganizationID != "org-a" {
+						t.Errorf("UpdateObject() organization = %v, want org-a", obj.OrganizationID)
+					}
+					return nil
+				})
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid", ApplyPolicy: []*Policy{{ID: "p-a", ServiceID: "sid", OrganizationID: "org-a"}}},
+			wantErr: nil,
+		},
+		{name: "#2: policy of another organization --> cross tenant",
+			ctx: context.Background(),
+			key: "service-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(&Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-a"}, nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid", ApplyPolicy: []*Policy{{ID: "p-b", OrganizationID: "org-b"}}},
+			wantErr: CommonError.CROSS_TENANT,
+		},
+		{name: "#3: object of another organization --> cross tenant",
+			ctx: context.Background(),
+			key: "service-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(&Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-b"}, nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid"},
+			wantErr: CommonError.CROSS_TENANT,
+		},
+		{name: "#4: object moved to another service --> invalid object",
+			ctx: context.Background(),
+			key: "service-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(&Object{ID: "obj-1", ServiceID: "other-sid", OrganizationID: "org-a"}, nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid"},
+			wantErr: CommonError.INVALID_OBJECT,
+		},
+		{name: "#5: scoped to the service organization --> no service key needed",
+			ctx: WithOrganization(context.Background(), "org-a"),
+			key: "",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(&Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-a"}, nil)
+				kontrolStore.EXPECT().UpdateObject(gomock.Any(), gomock.Any()).Return(nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid"},
+			wantErr: nil,
+		},
+		{name: "#6: scoped to another organization --> cross tenant even with the service key",
+			ctx: WithOrganization(context.Background(), "org-b"),
+			key: "service-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid"},
+			wantErr: CommonError.CROSS_TENANT,
+		},
+		{name: "#7: wrong service key without organization scope --> invalid token",
+			ctx: context.Background(),
+			key: "wrong-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid"},
+			wantErr: CommonError.INVALID_TOKEN,
+		},
+		{name: "#8: policy of another service of the organization --> invalid policy",
+			ctx: context.Background(),
+			key: "service-key",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil)
+				kontrolStore.EXPECT().GetObjectByID(gomock.Any(), "obj-1").Return(&Object{ID: "obj-1", ServiceID: "sid", OrganizationID: "org-a"}, nil)
+				return kontrolStore
+			},
+			obj:     &Object{ID: "obj-1", ServiceID: "sid", ApplyPolicy: []*Policy{{ID: "p-b", ServiceID: "other-sid", OrganizationID: "org-a"}}},
+			wantErr: CommonError.INVALID_POLICY,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewBasicKontrol(tt.store())
+			if err := k.UpdateObject(tt.ctx, tt.obj, tt.key); err != tt.wantErr {
+				t.Errorf("UpdateObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_UpdatePolicy_Organization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(&Service{ID: "sid", OrganizationID: "org-a"}, nil)
+	kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "p-b").Return(&Policy{ID: "p-b", ServiceID: "sid", OrganizationID: "org-b"}, nil).AnyTimes()
+
+	k := NewBasicKontrol(kontrolStore)
+	err := k.UpdatePolicy(WithOrganization(context.Background(), "org-a"), "", &Policy{ID: "p-b", ServiceID: "sid"})
+	if err != CommonError.CROSS_TENANT {
+		t.Errorf("UpdatePolicy() error = %v, wantErr %v", err, CommonError.CROSS_TENANT)
+	}
+}
+
+func TestDefaultKontrol_UpdatePolicy_Service(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	signed := DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("service-key")
+	tests := []struct {
+		name    string
+		store   func() KontrolStore
+		policy  *Policy
+		wantErr error
+	}{
+		{name: "#1: policy of the service",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid-a").Return(&Service{ID: "sid-a", OrganizationID: "org-a", Key: signed}, nil)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "p-a").Return(&Policy{ID: "p-a", ServiceID: "sid-a", OrganizationID: "org-a"}, nil)
+				kontrolStore.EXPECT().UpdatePolicy(gomock.Any(), gomock.Any()).Return(nil)
+				kontrolStore.EXPECT().ExpiredObjectsByPolicy(gomock.Any(), "p-a").Return(nil)
+				return kontrolStore
+			},
+			policy: &Policy{ID: "p-a", ServiceID: "sid-a"}, wantErr: nil,
+		},
+		{name: "#2: policy of another service of the organization --> invalid policy",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid-a").Return(&Service{ID: "sid-a", OrganizationID: "org-a", Key: signed}, nil)
+				kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "p-b").Return(&Policy{ID: "p-b", ServiceID: "sid-b", OrganizationID: "org-a"}, nil)
+				return kontrolStore
+			},
+			policy: &Policy{ID: "p-b", ServiceID: "sid-a"}, wantErr: CommonError.INVALID_POLICY,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewBasicKontrol(tt.store())
+			if err := k.UpdatePolicy(context.Background(), "service-key", tt.policy); err != tt.wantErr {
+				t.Errorf("UpdatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_AuthenticateOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	signed := DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("org-key")
+	tests := []struct {
+		name    string
+		store   func() KontrolStore
+		id      string
+		key     string
+		wantErr error
+	}{
+		{name: "#1: valid credentials",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetOrganizationByID(gomock.Any(), "org-a").Return(&Organization{ID: "org-a", Key: signed, Status: "enable"}, nil)
+				return kontrolStore
+			},
+			id: "org-a", key: "org-key", wantErr: nil,
+		},
+		{name: "#2: wrong key --> invalid token",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetOrganizationByID(gomock.Any(), "org-a").Return(&Organization{ID: "org-a", Key: signed, Status: "enable"}, nil)
+				return kontrolStore
+			},
+			id: "org-a", key: "service-key", wantErr: CommonError.INVALID_TOKEN,
+		},
+		{name: "#3: disabled organization --> invalid organization",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetOrganizationByID(gomock.Any(), "org-a").Return(&Organization{ID: "org-a", Key: signed, Status: "disable"}, nil)
+				return kontrolStore
+			},
+			id: "org-a", key: "org-key", wantErr: CommonError.INVALID_ORGANIZATION,
+		},
+		{name: "#4: unknown organization --> invalid organization",
+			store: func() KontrolStore {
+				kontrolStore := NewMockKontrolStore(ctrl)
+				kontrolStore.EXPECT().GetOrganizationByID(gomock.Any(), "org-x").Return(nil, CommonError.NOT_FOUND)
+				return kontrolStore
+			},
+			id: "org-x", key: "org-key", wantErr: CommonError.INVALID_ORGANIZATION,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewBasicKontrol(tt.store())
+			if _, err := k.AuthenticateOrganization(context.Background(), tt.id, tt.key); err != tt.wantErr {
+				t.Errorf("AuthenticateOrganization() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package gokontrol
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//GatewayServices enabled services with an upstream, ordered by service id. Services with a route the gateway
+//cannot render are skipped, callers are trusted
+func (k DefaultKontrol) GatewayServices(ctx context.Context) ([]*Service, error) {
+	services, err := k.store.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	routed := make([]*Service, 0, len(services))
+	for _, service := range services {
+		if service.Status != ServiceStatus.ENABLE || service.Routing.Upstream == "" {
+			continue
+		}
+		if err := service.ValidateRouting(); err != nil {
+			continue
+		}
+		routed = append(routed, service)
+	}
+	sort.Slice(routed, func(i, j int) bool { return routed[i].ServiceID < routed[j].ServiceID })
+	return routed, nil
+}
+
+//ValidateRouting check the route of a service renders into gateway rules: an absolute upstream, a path prefix,
+//a compiling rewrite and no backquote, the rule delimiter
+func (s *Service) ValidateRouting() error {
+	r := s.Routing
+	if r == (ServiceRouting{}) {
+		return nil
+	}
+	if u, err := url.Parse(r.Upstream); err != nil || u.Scheme == "" || u.Host == "" {
+		return CommonError.INVALID_SERVICE
+	}
+	if !strings.HasPrefix(r.Prefix(s.ServiceID), "/") || strings.ContainsAny(r.Host+r.Prefix(s.ServiceID), "`") {
+		return CommonError.INVALID_SERVICE
+	}
+	if r.Regex == "" && r.Replacement != "" {
+		return CommonError.INVALID_SERVICE
+	}
+	if _, err := regexp.Compile(r.Regex); err != nil {
+		return CommonError.INVALID_SERVICE
+	}
+	return nil
+}
+
+//Prefix path prefix of the route, /<service id>/ by default as the path resolver expects
+func (r ServiceRouting) Prefix(serviceID string) string {
+	if r.PathPrefix != "" {
+		return r.PathPrefix
+	}
+	return fmt.Sprintf("/%s/", serviceID)
+}
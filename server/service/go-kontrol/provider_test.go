@@ -0,0 +1,64 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestService_ValidateRouting(t *testing.T) {
+	tests := []struct {
+		name    string
+		routing ServiceRouting
+		wantErr bool
+	}{
+		{name: "#1: not routed", routing: ServiceRouting{}},
+		{name: "#2: default prefix", routing: ServiceRouting{Upstream: "http://orders:8080"}},
+		{name: "#3: rewrite", routing: ServiceRouting{Host: "api.example.com", PathPrefix: "/orders/api/", Upstream: "http://orders:8080", Regex: "(.*?)/api/(.*)", Replacement: "/internal_api/$2"}},
+		{name: "#4: relative upstream", routing: ServiceRouting{Upstream: "orders:8080"}, wantErr: true},
+		{name: "#5: prefix without slash", routing: ServiceRouting{PathPrefix: "orders", Upstream: "http://orders:8080"}, wantErr: true},
+		{name: "#6: backquote in rule", routing: ServiceRouting{Host: "a`) || Host(`b", Upstream: "http://orders:8080"}, wantErr: true},
+		{name: "#7: regex does not compile", routing: ServiceRouting{Upstream: "http://orders:8080", Regex: "(", Replacement: "/"}, wantErr: true},
+		{name: "#8: replacement without regex", routing: ServiceRouting{Upstream: "http://orders:8080", Replacement: "/"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{ServiceID: "orders", Routing: tt.routing}
+			if err := s.ValidateRouting(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRouting() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_GatewayServices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	store.EXPECT().GetServices(gomock.Any()).Return([]*Service{
+		{ID: "1", ServiceID: "users", Status: ServiceStatus.ENABLE, Routing: ServiceRouting{Upstream: "http://users:8080"}},
+		{ID: "2", ServiceID: "orders", Status: ServiceStatus.ENABLE, Routing: ServiceRouting{Upstream: "http://orders:8080"}},
+		{ID: "3", ServiceID: "billing", Status: ServiceStatus.DISABLE, Routing: ServiceRouting{Upstream: "http://billing:8080"}},
+		{ID: "4", ServiceID: "reports", Status: ServiceStatus.ENABLE},
+		{ID: "5", ServiceID: "broken", Status: ServiceStatus.ENABLE, Routing: ServiceRouting{Upstream: "broken:8080"}},
+	}, nil)
+	k := DefaultKontrol{store: store, Option: DefaultKontrolOption}
+
+	services, err := k.GatewayServices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(services))
+	for _, s := range services {
+		got = append(got, s.ServiceID)
+	}
+	if want := []string{"orders", "users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GatewayServices() = %v, want %v", got, want)
+	}
+	if prefix := services[0].Routing.Prefix(services[0].ServiceID); prefix != "/orders/" {
+		t.Errorf("Prefix() = %q, want /orders/", prefix)
+	}
+}
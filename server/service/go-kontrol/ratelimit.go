@@ -0,0 +1,184 @@
+package gokontrol
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+//RateLimit token bucket refilled by Rate tokens per second up to Burst, none if Rate is 0
+type RateLimit struct {
+	Rate  float64 `json:"rate" yaml:"rate"`
+	Burst int     `json:"burst" yaml:"burst"`
+}
+
+//RateLimitOption buckets of the validate flow, each one is only enforced when its rate is set
+type RateLimitOption struct {
+	Object     RateLimit // per object, raised by the rate limit of its policies
+	Service    RateLimit // per requested service, all objects together
+	ClientIP   RateLimit // per client IP, checked before the token so invalid ones are counted too
+	BreakGlass RateLimit // per client IP, failed break glass attempts
+}
+
+//RateLimitError request over a bucket, RetryAfter is when the bucket holds a token again
+type RateLimitError struct {
+	RetryAfter int64 // second, at least 1
+}
+
+func (e *RateLimitError) Error() string {
+	return CommonError.RATE_LIMITED.Error()
+}
+
+//Validate check the bucket can hold a token, a rate needs a burst of at least 1
+func (l RateLimit) Validate() error {
+	if l.Rate < 0 || l.Burst < 0 || (l.Rate > 0 && l.Burst < 1) || math.IsNaN(l.Rate) || math.IsInf(l.Rate, 0) {
+		return CommonError.INVALID_RATE_LIMIT
+	}
+	return nil
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// policyRateLimit highest rate limit of the policies, the override of the object bucket, nil if none sets one
+func policyRateLimit(policies ...[]*Policy) *RateLimit {
+	var best *RateLimit
+	for _, ps := range policies {
+		for _, p := range ps {
+			if p.RateLimit.enabled() && (best == nil || p.RateLimit.Rate > best.Rate) {
+				limit := p.RateLimit
+				best = &limit
+			}
+		}
+	}
+	return best
+}
+
+// rateLimiter token buckets by key, created full on first use and dropped once refilled
+type rateLimiter struct {
+	mu      sync.Mutex
+	option  RateLimitOption
+	buckets map[string]*rateBucket
+	swept   time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// rateTake token to take from the bucket of key
+type rateTake struct {
+	key   string
+	limit RateLimit
+}
+
+// newRateLimiter limiter of option, nil, limiting nothing, if no bucket is enabled
+func newRateLimiter(option RateLimitOption) *rateLimiter {
+	if !option.Object.enabled() && !option.Service.enabled() && !option.ClientIP.enabled() && !option.BreakGlass.enabled() {
+		return nil
+	}
+	return &rateLimiter{option: option, buckets: map[string]*rateBucket{}}
+}
+
+// allowClientIP take a token of the client IP bucket
+func (l *rateLimiter) allowClientIP(clientIP string, now time.Time) error {
+	if l == nil || clientIP == "" || !l.option.ClientIP.enabled() {
+		return nil
+	}
+	return l.take(now, rateTake{key: "ip\x00" + clientIP, limit: l.option.ClientIP})
+}
+
+// allowBreakGlass take a token of the break glass bucket of the client IP, giveBackBreakGlass returns it once the
+// attempt is granted. Accounts have no bucket, anyone could lock the oncall out
+func (l *rateLimiter) allowBreakGlass(clientIP string, now time.Time) error {
+	if l == nil || clientIP == "" || !l.option.BreakGlass.enabled() {
+		return nil
+	}
+	return l.take(now, rateTake{key: "break_glass\x00" + clientIP, limit: l.option.BreakGlass})
+}
+
+// giveBackBreakGlass return the token a granted break glass attempt took
+func (l *rateLimiter) giveBackBreakGlass(clientIP string) {
+	if l == nil || clientIP == "" || !l.option.BreakGlass.enabled() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets["break_glass\x00"+clientIP]; ok {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+1)
+	}
+}
+
+// allowObject take a token of the service and of the object bucket, override replacing the object limit
+func (l *rateLimiter) allowObject(objectID string, serviceID string, override *RateLimit, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	takes := make([]rateTake, 0, 2)
+	if l.option.Service.enabled() {
+		takes = append(takes, rateTake{key: "service\x00" + serviceID, limit: l.option.Service})
+	}
+	objectLimit := l.option.Object
+	if override != nil && override.enabled() {
+		objectLimit = *override
+	}
+	if objectLimit.enabled() {
+		takes = append(takes, rateTake{key: "object\x00" + objectID, limit: objectLimit})
+	}
+	return l.take(now, takes...)
+}
+
+// take a token of every bucket, or of none with the longest wait when one is empty
+func (l *rateLimiter) take(now time.Time, takes ...rateTake) error {
+	if len(takes) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	wait := 0.0
+	buckets := make([]*rateBucket, len(takes))
+	for i, t := range takes {
+		b, ok := l.buckets[t.key]
+		if !ok || b.limit != t.limit {
+			b = &rateBucket{tokens: float64(t.limit.Burst), last: now, limit: t.limit}
+			l.buckets[t.key] = b
+		}
+		b.refill(now)
+		if b.tokens < 1 {
+			wait = math.Max(wait, (1-b.tokens)/b.limit.Rate)
+		}
+		buckets[i] = b
+	}
+	if wait > 0 {
+		return &RateLimitError{RetryAfter: int64(math.Ceil(wait))}
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return nil
+}
+
+// sweep drop the buckets refilled, as new ones, at most once a minute
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (b *rateBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
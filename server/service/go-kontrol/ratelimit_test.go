@@ -0,0 +1,147 @@
+package gokontrol
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestRateLimit_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   RateLimit
+		wantErr bool
+	}{
+		{name: "#1: none", limit: RateLimit{}},
+		{name: "#2: rate and burst", limit: RateLimit{Rate: 0.5, Burst: 10}},
+		{name: "#3: rate without burst", limit: RateLimit{Rate: 5}, wantErr: true},
+		{name: "#4: negative rate", limit: RateLimit{Rate: -1, Burst: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limit.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+
+	t.Run("#1: burst then retry after the refill", func(t *testing.T) {
+		l := newRateLimiter(RateLimitOption{ClientIP: RateLimit{Rate: 0.5, Burst: 2}})
+		for i := 0; i < 2; i++ {
+			if err := l.allowClientIP("203.0.113.7", now); err != nil {
+				t.Fatalf("allowClientIP() error = %v", err)
+			}
+		}
+		err := l.allowClientIP("203.0.113.7", now)
+		if limited, ok := err.(*RateLimitError); !ok || limited.RetryAfter != 2 {
+			t.Fatalf("allowClientIP() error = %v, want retry after 2s", err)
+		}
+		if err := l.allowClientIP("198.51.100.1", now); err != nil {
+			t.Errorf("other client limited: %v", err)
+		}
+		if err := l.allowClientIP("203.0.113.7", now.Add(2*time.Second)); err != nil {
+			t.Errorf("allowClientIP() after refill error = %v", err)
+		}
+	})
+	t.Run("#2: buckets taken together", func(t *testing.T) {
+		l := newRateLimiter(RateLimitOption{Object: RateLimit{Rate: 1, Burst: 1}, Service: RateLimit{Rate: 1, Burst: 2}})
+		if err := l.allowObject("a", "sid", nil, now); err != nil {
+			t.Fatalf("allowObject() error = %v", err)
+		}
+		// a is out of tokens, the service keeps the one it would have given
+		if err := l.allowObject("a", "sid", nil, now); err == nil {
+			t.Fatalf("allowObject() over the object limit allowed")
+		}
+		if err := l.allowObject("b", "sid", nil, now); err != nil {
+			t.Errorf("allowObject() error = %v, the denied request took a service token", err)
+		}
+		if err := l.allowObject("c", "sid", nil, now); err == nil {
+			t.Errorf("allowObject() over the service limit allowed")
+		}
+	})
+	t.Run("#3: policy override", func(t *testing.T) {
+		l := newRateLimiter(RateLimitOption{Object: RateLimit{Rate: 1, Burst: 1}})
+		override := &RateLimit{Rate: 10, Burst: 3}
+		for i := 0; i < 3; i++ {
+			if err := l.allowObject("premium", "sid", override, now); err != nil {
+				t.Fatalf("allowObject() error = %v", err)
+			}
+		}
+		if err := l.allowObject("premium", "sid", override, now); err == nil {
+			t.Errorf("allowObject() over the override allowed")
+		}
+	})
+	t.Run("#4: idle buckets swept", func(t *testing.T) {
+		l := newRateLimiter(RateLimitOption{ClientIP: RateLimit{Rate: 1, Burst: 1}})
+		l.allowClientIP("203.0.113.7", now)
+		l.allowClientIP("198.51.100.1", now.Add(2*time.Minute))
+		if len(l.buckets) != 1 {
+			t.Errorf("%d buckets, want the refilled one swept", len(l.buckets))
+		}
+	})
+	t.Run("#5: disabled", func(t *testing.T) {
+		l := newRateLimiter(RateLimitOption{})
+		if l != nil || l.allowClientIP("203.0.113.7", now) != nil || l.allowObject("a", "sid", nil, now) != nil {
+			t.Errorf("disabled limiter limited")
+		}
+	})
+}
+
+func TestPolicyRateLimit(t *testing.T) {
+	got := policyRateLimit([]*Policy{{ID: "default"}}, []*Policy{{ID: "premium", RateLimit: RateLimit{Rate: 50, Burst: 100}}, {ID: "gold", RateLimit: RateLimit{Rate: 20, Burst: 500}}})
+	if got == nil || *got != (RateLimit{Rate: 50, Burst: 100}) {
+		t.Errorf("policyRateLimit() = %v, want the highest rate", got)
+	}
+	if got := policyRateLimit([]*Policy{{ID: "default"}}); got != nil {
+		t.Errorf("policyRateLimit() = %v, want nil", got)
+	}
+}
+
+func TestDefaultKontrol_IdentifyRequest_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	option := DefaultKontrolOption
+	option.RateLimit = RateLimitOption{Object: RateLimit{Rate: 1, Burst: 1}, ClientIP: RateLimit{Rate: 1, Burst: 5}}
+	k := NewKontrol(store, option)
+	obj := &Object{ID: "oid", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60,
+		ApplyPolicy: []*Policy{{ID: "premium", ServiceID: "sid-a", RateLimit: RateLimit{Rate: 1, Burst: 2}}}}
+	_, sign, jwtToken, err := k.CreateCert(obj, []*Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&Service{ID: "sid-b", ServiceID: "orders"}, nil).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).AnyTimes()
+
+	identify := func(header http.Header) error {
+		_, err := k.IdentifyRequest(context.Background(), &AccessRequest{Method: http.MethodGet, Path: "/orders/list", ClientIP: "203.0.113.7", Header: header})
+		return err
+	}
+	bearer := http.Header{"Authorization": {"Bearer " + jwtToken}}
+	// the premium policy carried by the token raises the object burst to 2
+	for i := 0; i < 2; i++ {
+		if err := identify(bearer); err != nil {
+			t.Fatalf("#1: IdentifyRequest() error = %v", err)
+		}
+	}
+	if _, ok := identify(bearer).(*RateLimitError); !ok {
+		t.Fatalf("#2: IdentifyRequest() over the object limit not limited")
+	}
+	// requests without a token count on the client IP, 3 taken so far
+	for i := 0; i < 2; i++ {
+		if err := identify(nil); err != CommonError.MISSING_TOKEN {
+			t.Fatalf("#3: IdentifyRequest() error = %v, want %v", err, CommonError.MISSING_TOKEN)
+		}
+	}
+	if _, ok := identify(nil).(*RateLimitError); !ok {
+		t.Errorf("#4: IdentifyRequest() over the client IP limit not limited")
+	}
+}
@@ -0,0 +1,180 @@
+package gokontrol
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type resolvertype struct {
+	PATH   string
+	HOST   string
+	HEADER string
+	ROUTES string
+}
+
+var ResolverType = resolvertype{
+	PATH:   "path",   // first path segment, /idt/api/... --> idt
+	HOST:   "host",   // host table, or first host label when the table is empty
+	HEADER: "header", // header holding the service prefix, e.g. X-Forwarded-Prefix
+	ROUTES: "routes", // explicit route table
+}
+
+//AccessRequest request to authorize, as seen by the gateway
+type AccessRequest struct {
+	Method   string
+	Proto    string // scheme, http or https
+	Host     string
+	Path     string // without the query
+	Query    string
+	ClientIP string
+	Header   http.Header
+	Body     []byte // checked against the Content-Digest of signed requests, nil when the proxy does not forward it
+}
+
+//ServiceResolver find the external id of the service a request targets and the path inside the service
+type ServiceResolver interface {
+	Resolve(req *AccessRequest) (serviceID string, path string, err error)
+}
+
+//Route map requests of Host (any host if empty) under PathPrefix to Service
+type Route struct {
+	Host        string
+	PathPrefix  string
+	Service     string
+	StripPrefix bool // match the permissions against the path without the prefix
+}
+
+//ResolverOption resolution settings of a deployment
+type ResolverOption struct {
+	Type   string
+	Header string
+	Hosts  map[string]string
+	Routes []*Route
+}
+
+//NewServiceResolver resolver of option, path resolver when no type is set
+func NewServiceResolver(option ResolverOption) (ServiceResolver, error) {
+	switch option.Type {
+	case "", ResolverType.PATH:
+		return PathResolver{}, nil
+	case ResolverType.HOST:
+		hosts := make(map[string]string, len(option.Hosts))
+		for h, s := range option.Hosts {
+			hosts[strings.ToLower(h)] = s
+		}
+		return HostResolver{Hosts: hosts}, nil
+	case ResolverType.HEADER:
+		if option.Header == "" {
+			return nil, CommonError.INVALID_RESOLVER
+		}
+		return HeaderResolver{Header: option.Header}, nil
+	case ResolverType.ROUTES:
+		for _, r := range option.Routes {
+			if r == nil || r.Service == "" {
+				return nil, CommonError.INVALID_RESOLVER
+			}
+		}
+		return NewRouteResolver(option.Routes), nil
+	}
+	return nil, CommonError.INVALID_RESOLVER
+}
+
+//PathResolver service is the first path segment, path is the rest
+type PathResolver struct{}
+
+func (PathResolver) Resolve(req *AccessRequest) (string, string, error) {
+	splitPaths := strings.SplitN(strings.TrimPrefix(req.Path, "/"), "/", 2)
+	if splitPaths[0] == "" {
+		return "", "", CommonError.SERVICE_UNRESOLVED
+	}
+	if len(splitPaths) == 1 {
+		return splitPaths[0], "/", nil
+	}
+	return splitPaths[0], "/" + splitPaths[1], nil
+}
+
+//HostResolver service of the request host
+type HostResolver struct {
+	Hosts map[string]string // lower case host --> service, empty means the first host label is the service
+}
+
+func (r HostResolver) Resolve(req *AccessRequest) (string, string, error) {
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		return "", "", CommonError.SERVICE_UNRESOLVED
+	}
+	if len(r.Hosts) > 0 {
+		service, ok := r.Hosts[host]
+		if !ok {
+			return "", "", CommonError.SERVICE_UNRESOLVED
+		}
+		return service, req.Path, nil
+	}
+	label := strings.SplitN(host, ".", 2)[0]
+	if net.ParseIP(host) != nil || label == "" {
+		return "", "", CommonError.SERVICE_UNRESOLVED
+	}
+	return label, req.Path, nil
+}
+
+//HeaderResolver service named by a header set by the gateway, e.g. X-Forwarded-Prefix: /idt
+type HeaderResolver struct {
+	Header string
+}
+
+func (r HeaderResolver) Resolve(req *AccessRequest) (string, string, error) {
+	service := strings.Trim(req.Header.Get(r.Header), "/")
+	if service == "" || strings.Contains(service, "/") {
+		return "", "", CommonError.SERVICE_UNRESOLVED
+	}
+	return service, req.Path, nil
+}
+
+//RouteResolver service of the most specific matching route, routes of a host win over routes of any host
+type RouteResolver struct {
+	routes []*Route
+}
+
+//NewRouteResolver resolver over routes
+func NewRouteResolver(routes []*Route) RouteResolver {
+	sorted := make([]*Route, len(routes))
+	copy(sorted, routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if (sorted[i].Host != "") != (sorted[j].Host != "") {
+			return sorted[i].Host != ""
+		}
+		return len(sorted[i].PathPrefix) > len(sorted[j].PathPrefix)
+	})
+	return RouteResolver{routes: sorted}
+}
+
+func (r RouteResolver) Resolve(req *AccessRequest) (string, string, error) {
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, route := range r.routes {
+		if route.Host != "" && !strings.EqualFold(route.Host, host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(route.PathPrefix, "/")
+		// prefix must end on a segment boundary, /idt does not match /idtx
+		if prefix != "" && req.Path != prefix && !strings.HasPrefix(req.Path, prefix+"/") {
+			continue
+		}
+		path := req.Path
+		if route.StripPrefix {
+			path = strings.TrimPrefix(req.Path, prefix)
+			if path == "" {
+				path = "/"
+			}
+		}
+		return route.Service, path, nil
+	}
+	return "", "", CommonError.SERVICE_UNRESOLVED
+}
@@ -0,0 +1,149 @@
+package gokontrol
+
+import "time"
+
+const scheduleClockLayout = "15:04"
+
+//Validate check the schedule can be evaluated
+func (s *PolicySchedule) Validate() error {
+	from, err := time.Parse(scheduleClockLayout, s.From)
+	if err != nil {
+		return CommonError.INVALID_SCHEDULE
+	}
+	to, err := time.Parse(scheduleClockLayout, s.To)
+	if err != nil {
+		return CommonError.INVALID_SCHEDULE
+	}
+	if from.Equal(to) {
+		return CommonError.INVALID_SCHEDULE
+	}
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		return CommonError.INVALID_SCHEDULE
+	}
+	for _, d := range s.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return CommonError.INVALID_SCHEDULE
+		}
+	}
+	return nil
+}
+
+// windows list the [open, close) ranges of the schedule opening from the day before t up to a week after,
+// which always covers the window containing t and the next boundary after it
+func (s *PolicySchedule) windows(t time.Time) [][2]time.Time {
+	from, err := time.Parse(scheduleClockLayout, s.From)
+	if err != nil {
+		return nil
+	}
+	to, err := time.Parse(scheduleClockLayout, s.To)
+	if err != nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil
+	}
+	overnight := 0
+	if !to.After(from) {
+		overnight = 1
+	}
+
+	local := t.In(loc)
+	rs := make([][2]time.Time, 0)
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+		if !s.openOn(day.Weekday()) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), from.Hour(), from.Minute(), 0, 0, loc)
+		end := time.Date(day.Year(), day.Month(), day.Day()+overnight, to.Hour(), to.Minute(), 0, 0, loc)
+		rs = append(rs, [2]time.Time{start, end})
+	}
+	return rs
+}
+
+func (s *PolicySchedule) openOn(d time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, v := range s.Days {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
+
+//InSchedule report if t falls in one of the policy windows, policies without schedule are always in schedule
+func (p *Policy) InSchedule(t time.Time) bool {
+	if len(p.Schedule) == 0 {
+		return true
+	}
+	for _, s := range p.Schedule {
+		for _, w := range s.windows(t) {
+			if !t.Before(w[0]) && t.Before(w[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//NextScheduleBoundary unix time of the next window opening or closing after t, 0 if the policy has no schedule
+func (p *Policy) NextScheduleBoundary(t time.Time) int64 {
+	var next time.Time
+	for _, s := range p.Schedule {
+		for _, w := range s.windows(t) {
+			for _, b := range w {
+				if b.After(t) && (next.IsZero() || b.Before(next)) {
+					next = b
+				}
+			}
+		}
+	}
+	if next.IsZero() {
+		return 0
+	}
+	return next.Unix()
+}
+
+//ValidateSchedule validate every schedule of the policy
+func (p *Policy) ValidateSchedule() error {
+	for _, s := range p.Schedule {
+		if s == nil {
+			return CommonError.INVALID_SCHEDULE
+		}
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduledPolicies keep the policies in schedule at now, cap expiry at the closest schedule boundary
+// and collect the ids of the kept scheduled policies
+func scheduledPolicies(now time.Time, policies []*Policy, expiry int64, scheduled []string) ([]*Policy, int64, []string) {
+	rs := make([]*Policy, 0, len(policies))
+	for _, p := range policies {
+		if b := p.NextScheduleBoundary(now); b > 0 && b < expiry {
+			expiry = b
+		}
+		if !p.InSchedule(now) {
+			continue
+		}
+		if len(p.Schedule) > 0 && !containsString(scheduled, p.ID) {
+			scheduled = append(scheduled, p.ID)
+		}
+		rs = append(rs, p)
+	}
+	return rs, expiry, scheduled
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package gokontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestPolicy_InSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+	weekdays := []*PolicySchedule{{
+		Days:     []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		From:     "08:00",
+		To:       "18:00",
+		TimeZone: "Europe/Berlin",
+	}}
+	overnight := []*PolicySchedule{{
+		From:     "22:00",
+		To:       "06:00",
+		TimeZone: "Europe/Berlin",
+	}}
+	tests := []struct {
+		name         string
+		schedule     []*PolicySchedule
+		at           time.Time
+		want         bool
+		wantBoundary time.Time
+	}{
+		{name: "#1: no schedule, always applied",
+			schedule: nil,
+			at:       time.Date(2022, 6, 4, 3, 0, 0, 0, berlin),
+			want:     true,
+		},
+		{name: "#2: weekday inside window, boundary is today closing",
+			schedule:     weekdays,
+			at:           time.Date(2022, 6, 8, 9, 30, 0, 0, berlin),
+			want:         true,
+			wantBoundary: time.Date(2022, 6, 8, 18, 0, 0, 0, berlin),
+		},
+		{name: "#3: weekday before window, boundary is today opening",
+			schedule:     weekdays,
+			at:           time.Date(2022, 6, 8, 7, 59, 0, 0, berlin),
+			want:         false,
+			wantBoundary: time.Date(2022, 6, 8, 8, 0, 0, 0, berlin),
+		},
+		{name: "#4: closing time is excluded",
+			schedule:     weekdays,
+			at:           time.Date(2022, 6, 8, 18, 0, 0, 0, berlin),
+			want:         false,
+			wantBoundary: time.Date(2022, 6, 9, 8, 0, 0, 0, berlin),
+		},
+		{name: "#5: saturday, boundary is monday opening",
+			schedule:     weekdays,
+			at:           time.Date(2022, 6, 4, 12, 0, 0, 0, berlin),
+			want:         false,
+			wantBoundary: time.Date(2022, 6, 6, 8, 0, 0, 0, berlin),
+		},
+		{name: "#6: evaluated in the schedule time zone",
+			schedule:     weekdays,
+			at:           time.Date(2022, 6, 8, 6, 30, 0, 0, time.UTC),
+			want:         true,
+			wantBoundary: time.Date(2022, 6, 8, 18, 0, 0, 0, berlin),
+		},
+		{name: "#7: overnight window after midnight belongs to the previous day",
+			schedule:     overnight,
+			at:           time.Date(2022, 6, 8, 2, 0, 0, 0, berlin),
+			want:         true,
+			wantBoundary: time.Date(2022, 6, 8, 6, 0, 0, 0, berlin),
+		},
+		{name: "#8: invalid schedule never applies",
+			schedule: []*PolicySchedule{{From: "8h", To: "18:00"}},
+			at:       time.Date(2022, 6, 8, 9, 0, 0, 0, berlin),
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Policy{Schedule: tt.schedule}
+			if got := p.InSchedule(tt.at); got != tt.want {
+				t.Errorf("InSchedule() = %v, want %v", got, tt.want)
+			}
+			var want int64
+			if !tt.wantBoundary.IsZero() {
+				want = tt.wantBoundary.Unix()
+			}
+			if got := p.NextScheduleBoundary(tt.at); got != want {
+				t.Errorf("NextScheduleBoundary() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPolicy_ValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule []*PolicySchedule
+		wantErr  bool
+	}{
+		{name: "#1: valid schedule", schedule: []*PolicySchedule{{Days: []time.Weekday{time.Monday}, From: "08:00", To: "18:00", TimeZone: "UTC"}}, wantErr: false},
+		{name: "#2: malformed clock", schedule: []*PolicySchedule{{From: "08:00", To: "25:00"}}, wantErr: true},
+		{name: "#3: empty window", schedule: []*PolicySchedule{{From: "08:00", To: "08:00"}}, wantErr: true},
+		{name: "#4: unknown time zone", schedule: []*PolicySchedule{{From: "08:00", To: "18:00", TimeZone: "Mars/Olympus"}}, wantErr: true},
+		{name: "#5: unknown week day", schedule: []*PolicySchedule{{Days: []time.Weekday{9}, From: "08:00", To: "18:00"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Policy{Schedule: tt.schedule}
+			if err := p.ValidateSchedule(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_CreateCert_Schedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a window opening an hour ago and closing in an hour, whatever the current time
+	now := time.Now().UTC()
+	open := &PolicySchedule{From: now.Add(-time.Hour).Format("15:04"), To: now.Add(time.Hour).Format("15:04")}
+	closed := &PolicySchedule{From: now.Add(2 * time.Hour).Format("15:04"), To: now.Add(3 * time.Hour).Format("15:04")}
+
+	k := DefaultKontrol{store: NewMockKontrolStore(ctrl), Option: DefaultKontrolOption}
+	obj := &Object{ID: "obj-1", ServiceID: "sid", ExpiryDate: now.Unix() + 24*3600}
+	cert, _, _, err := k.CreateCert(obj, []*Policy{
+		{ID: "in-window", ServiceID: "sid", Permission: map[string]int{"view_payroll": PolicyPermission.TRUE}, Schedule: []*PolicySchedule{open}},
+		{ID: "out-of-window", ServiceID: "sid", Permission: map[string]int{"edit_payroll": PolicyPermission.TRUE}, Schedule: []*PolicySchedule{closed}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateCert() error = %v", err)
+	}
+	if !cert.Permission["sid"]["view_payroll"] || cert.Permission["sid"]["edit_payroll"] {
+		t.Errorf("CreateCert() permission = %v, want only view_payroll", cert.Permission)
+	}
+	if cert.ExpiryDate > now.Add(time.Hour).Unix() {
+		t.Errorf("CreateCert() expiry = %v, want capped at window closing %v", cert.ExpiryDate, now.Add(time.Hour).Unix())
+	}
+	if obj.ExpiryDate != cert.ExpiryDate {
+		t.Errorf("CreateCert() object expiry = %v, want %v", obj.ExpiryDate, cert.ExpiryDate)
+	}
+}
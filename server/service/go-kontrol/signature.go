@@ -0,0 +1,291 @@
+package gokontrol
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// signatureScheme authorization scheme of signed requests:
+//
+//	Authorization: Signature keyId="<key id>",nonce="<nonce>",signature="<base64 hmac-sha256>"
+const signatureScheme = "Signature"
+
+// maxNonce length of a nonce, as stored
+const maxNonce = 128
+
+// requestSignature parameters of the Signature authorization
+type requestSignature struct {
+	KeyID     string
+	Nonce     string
+	Signature []byte
+}
+
+//SignedRequest whether req carries a Signature authorization rather than a token
+func SignedRequest(req *AccessRequest) bool {
+	scheme, _, _ := strings.Cut(strings.TrimSpace(req.Header.Get("Authorization")), " ")
+	return strings.EqualFold(scheme, signatureScheme)
+}
+
+// parseSignature parameters of a Signature authorization, every one is mandatory
+func parseSignature(authorization string) (*requestSignature, error) {
+	_, params, _ := strings.Cut(strings.TrimSpace(authorization), " ")
+	sig := &requestSignature{}
+	for _, param := range strings.Split(params, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+			return nil, CommonError.INVALID_SIGNATURE
+		}
+		value = value[1 : len(value)-1]
+		switch name {
+		case "keyId":
+			sig.KeyID = value
+		case "nonce":
+			sig.Nonce = value
+		case "signature":
+			raw, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, CommonError.INVALID_SIGNATURE
+			}
+			sig.Signature = raw
+		}
+	}
+	if sig.KeyID == "" || sig.Nonce == "" || len(sig.Nonce) > maxNonce || len(sig.Signature) == 0 {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	return sig, nil
+}
+
+// signingString what the client signs, one line per component:
+//
+//	(request-target): <lower case method> <path>[?<query>]
+//	date: <Date>
+//	content-digest: <Content-Digest, empty without body>
+//	nonce: <nonce>
+func signingString(req *AccessRequest, nonce string) string {
+	target := req.Path
+	if req.Query != "" {
+		target += "?" + req.Query
+	}
+	return strings.Join([]string{
+		"(request-target): " + strings.ToLower(req.Method) + " " + target,
+		"date: " + req.Header.Get("Date"),
+		"content-digest: " + req.Header.Get("Content-Digest"),
+		"nonce: " + nonce,
+	}, "\n")
+}
+
+// checkDigest the Content-Digest of req covers its body. Without the body, as forwardAuth has, the signed digest is
+// trusted and requests announcing a body must carry one
+func checkDigest(req *AccessRequest) error {
+	header := req.Header.Get("Content-Digest")
+	if req.Body == nil {
+		if header == "" && req.Header.Get("Content-Length") != "" && req.Header.Get("Content-Length") != "0" {
+			return CommonError.INVALID_SIGNATURE
+		}
+		return nil
+	}
+	if header == "" {
+		if len(req.Body) > 0 {
+			return CommonError.INVALID_SIGNATURE
+		}
+		return nil
+	}
+	sum := sha256.Sum256(req.Body)
+	want := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	for _, digest := range strings.Split(header, ",") {
+		if strings.TrimSpace(digest) == want {
+			return nil
+		}
+	}
+	return CommonError.INVALID_SIGNATURE
+}
+
+// secretCipher cipher the secrets of signing keys are sealed with, keyed by the secret key. Changing the secret key
+// revokes every signing key as it does the tokens
+func (k DefaultKontrol) secretCipher() (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte("signing_key\x00" + k.Option.SecretKey))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSecret secret of a signing key as stored, the store alone does not sign requests
+func (k DefaultKontrol) sealSecret(secret string) (string, error) {
+	aead, err := k.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// openSecret secret of a signing key from its sealed form
+func (k DefaultKontrol) openSecret(sealed string) (string, error) {
+	aead, err := k.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", CommonError.INVALID_SIGNATURE
+	}
+	secret, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", CommonError.INVALID_SIGNATURE
+	}
+	return string(secret), nil
+}
+
+// validateSignature object of the signing key of req allowed to make it on reqPath of reqService. The Date must be
+// within the configured skew, the nonce is accepted once while it is, and the object is authorized by the policies
+// its token would get. Signed requests are never cached, each has its own nonce
+func (k DefaultKontrol) validateSignature(c context.Context, reqService *Service, reqPath string, req *AccessRequest, clientIP string, t time.Time) (*Object, error) {
+	sig, err := parseSignature(req.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	if skew := t.Sub(date); skew > time.Duration(k.Option.SignatureSkew)*time.Second || -skew > time.Duration(k.Option.SignatureSkew)*time.Second {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	key, err := k.store.GetSigningKey(c, sig.KeyID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if key == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	if err := checkDigest(req); err != nil {
+		return nil, err
+	}
+	secret, err := k.openSecret(key.Sealed)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingString(req, sig.Nonce)))
+	if !hmac.Equal(mac.Sum(nil), sig.Signature) {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	// a replay would carry a date within the skew until then
+	if err := k.store.UseSignatureNonce(c, key.ID, sig.Nonce, date.Unix()+k.Option.SignatureSkew); err != nil {
+		return nil, err
+	}
+
+	object, err := k.store.GetObjectByID(c, key.ObjectID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if object == nil || err == CommonError.NOT_FOUND || object.Status != ObjectStatus.ENABLE {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	home, err := k.store.GetServiceByID(c, object.ServiceID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if home == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.INVALID_SIGNATURE
+	}
+	policy, enforce, extendServiceIds, err := k.clientPolicies(c, object, home, t.Unix()+k.Option.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	_, claims, err := k.certClaims(object, policy, enforce, extendServiceIds)
+	if err != nil {
+		return nil, err
+	}
+	if err := claimPermits(object, claims, reqService, reqPath, req); err != nil {
+		return nil, err
+	}
+	object, _, err = k.allow(object, reqService, claims.Network, claims.RateLimit, clientIP, t)
+	return object, err
+}
+
+//CreateSigningKey key a machine client signs its requests with as the object, authorized on the object service.
+//The secret is only returned here
+func (k DefaultKontrol) CreateSigningKey(ctx context.Context, servicekey string, objectID string) (*SigningKey, error) {
+	obj, err := k.store.GetObjectByID(ctx, objectID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if obj == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.OBJECT_NOT_FOUND
+	}
+	service, err := k.serviceByID(ctx, obj.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+	sealed, err := k.sealSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	key := &SigningKey{
+		ID:             uuid.NewString(),
+		ObjectID:       obj.ID,
+		OrganizationID: obj.OrganizationID,
+		Sealed:         sealed,
+	}
+	if err := k.store.CreateSigningKey(ctx, key); err != nil {
+		return nil, err
+	}
+	key.Secret = secret
+	return key, nil
+}
+
+//RevokeSigningKey remove the key, authorized on the service of its object
+func (k DefaultKontrol) RevokeSigningKey(ctx context.Context, servicekey string, keyID string) error {
+	key, err := k.store.GetSigningKey(ctx, keyID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if key == nil || err == CommonError.NOT_FOUND {
+		return CommonError.KEY_NOT_FOUND
+	}
+	obj, err := k.store.GetObjectByID(ctx, key.ObjectID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if obj == nil || err == CommonError.NOT_FOUND {
+		return CommonError.OBJECT_NOT_FOUND
+	}
+	service, err := k.serviceByID(ctx, obj.ServiceID)
+	if err != nil {
+		return err
+	}
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.USER_ADMIN); err != nil {
+		return err
+	}
+	return k.store.DeleteSigningKey(ctx, key.ID)
+}
+
+//ExpireSignatureNonces forget the nonces whose signature date is out of the skew at timestamp, a replay is refused
+//by its date
+func (k DefaultKontrol) ExpireSignatureNonces(ctx context.Context, timestamp int64) error {
+	return k.store.DeleteSignatureNonces(ctx, timestamp)
+}
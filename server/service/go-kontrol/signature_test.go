@@ -0,0 +1,146 @@
+package gokontrol
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+// signRequest sign req as a machine client holding secret
+func signRequest(req *AccessRequest, keyID string, secret string, nonce string) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingString(req, nonce)))
+	req.Header.Set("Authorization", `Signature keyId="`+keyID+`",nonce="`+nonce+`",signature="`+base64.StdEncoding.EncodeToString(mac.Sum(nil))+`"`)
+}
+
+func TestDefaultKontrol_IdentifyRequest_Signature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	k := NewBasicKontrol(store).(*DefaultKontrol)
+	secret := "s3cr3t"
+	sealed, err := k.sealSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cron := &Object{ID: "cron", ServiceID: "sid-a", Status: ObjectStatus.ENABLE}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "jobs").Return(&Service{ID: "sid-a", ServiceID: "jobs"}, nil).AnyTimes()
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "billing").Return(&Service{ID: "sid-b", ServiceID: "billing"}, nil).AnyTimes()
+	store.EXPECT().GetServiceByID(gomock.Any(), "sid-a").Return(&Service{ID: "sid-a", ServiceID: "jobs",
+		DefaultPolicy: []*Policy{{ID: "invoices", ServiceID: "sid-b", Permission: map[string]int{"POST@/invoices": 1}}}}, nil).AnyTimes()
+	store.EXPECT().GetSigningKey(gomock.Any(), "key-1").Return(&SigningKey{ID: "key-1", ObjectID: "cron", Sealed: sealed}, nil).AnyTimes()
+	store.EXPECT().GetSigningKey(gomock.Any(), "key-2").Return(&SigningKey{ID: "key-2", ObjectID: "disabled", Sealed: sealed}, nil).AnyTimes()
+	store.EXPECT().GetSigningKey(gomock.Any(), "revoked").Return(nil, CommonError.NOT_FOUND).AnyTimes()
+	store.EXPECT().GetObjectByID(gomock.Any(), "cron").Return(cron, nil).AnyTimes()
+	store.EXPECT().GetObjectByID(gomock.Any(), "disabled").Return(&Object{ID: "disabled", ServiceID: "sid-a", Status: ObjectStatus.DISABLE}, nil).AnyTimes()
+	store.EXPECT().GetObjectServiceMesh(gomock.Any(), "cron").Return(nil, nil).AnyTimes()
+	store.EXPECT().UseSignatureNonce(gomock.Any(), gomock.Any(), "used", gomock.Any()).Return(CommonError.SIGNATURE_REPLAYED).AnyTimes()
+	store.EXPECT().UseSignatureNonce(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	body := []byte(`{"amount":42}`)
+	sum := sha256.Sum256(body)
+	digest := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	now := time.Now().UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		keyID   string
+		secret  string
+		nonce   string
+		date    string
+		digest  string
+		body    []byte
+		tamper  func(req *AccessRequest)
+		wantErr error
+	}{
+		{name: "#1: own service", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: secret, nonce: "n1", date: now},
+		{name: "#2: policy on another service", method: http.MethodPost, path: "/billing/invoices", keyID: "key-1", secret: secret, nonce: "n2", date: now, digest: digest, body: body},
+		{name: "#3: outside the policies", method: http.MethodDelete, path: "/billing/invoices", keyID: "key-1", secret: secret, nonce: "n3", date: now, wantErr: CommonError.INVALID_SERVICE},
+		{name: "#4: wrong secret", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: "guess", nonce: "n4", date: now, wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#5: path changed", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: secret, nonce: "n5", date: now,
+			tamper: func(req *AccessRequest) { req.Path = "/jobs/purge" }, wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#6: body changed", method: http.MethodPost, path: "/billing/invoices", keyID: "key-1", secret: secret, nonce: "n6", date: now, digest: digest,
+			body: []byte(`{"amount":4200}`), wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#7: body without digest", method: http.MethodPost, path: "/billing/invoices", keyID: "key-1", secret: secret, nonce: "n7", date: now,
+			body: body, wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#8: date out of the skew", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: secret, nonce: "n8",
+			date: time.Now().Add(-10 * time.Minute).UTC().Format(http.TimeFormat), wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#9: no date", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: secret, nonce: "n9", wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#10: replayed nonce", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: secret, nonce: "used", date: now, wantErr: CommonError.SIGNATURE_REPLAYED},
+		{name: "#11: revoked key", method: http.MethodGet, path: "/jobs/run", keyID: "revoked", secret: secret, nonce: "n11", date: now, wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#12: disabled object", method: http.MethodGet, path: "/jobs/run", keyID: "key-2", secret: secret, nonce: "n12", date: now, wantErr: CommonError.INVALID_SIGNATURE},
+		{name: "#13: malformed authorization", method: http.MethodGet, path: "/jobs/run", keyID: "key-1", secret: secret, nonce: "n13", date: now,
+			tamper: func(req *AccessRequest) { req.Header.Set("Authorization", `Signature keyId="key-1"`) }, wantErr: CommonError.INVALID_SIGNATURE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &AccessRequest{Method: tt.method, Path: tt.path, Header: http.Header{}, Body: tt.body}
+			if tt.date != "" {
+				req.Header.Set("Date", tt.date)
+			}
+			if tt.digest != "" {
+				req.Header.Set("Content-Digest", tt.digest)
+			}
+			signRequest(req, tt.keyID, tt.secret, tt.nonce)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			identity, err := k.IdentifyRequest(context.Background(), req)
+			if err != tt.wantErr {
+				t.Fatalf("IdentifyRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (identity.ObjectID != "cron" || identity.Anonymous) {
+				t.Errorf("IdentifyRequest() = %+v, want object cron", identity)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_CreateSigningKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	k := NewBasicKontrol(store).(*DefaultKontrol)
+	store.EXPECT().GetObjectByID(gomock.Any(), "cron").Return(&Object{ID: "cron", ServiceID: "sid-a", OrganizationID: "org"}, nil).AnyTimes()
+	store.EXPECT().GetObjectByID(gomock.Any(), "none").Return(nil, CommonError.NOT_FOUND).AnyTimes()
+	store.EXPECT().GetServiceByID(gomock.Any(), "sid-a").Return(&Service{ID: "sid-a", OrganizationID: "org", Key: k.serviceKeySign("key")}, nil).AnyTimes()
+	var stored SigningKey
+	store.EXPECT().CreateSigningKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key *SigningKey) error {
+		stored = *key
+		return nil
+	}).Times(2)
+
+	if _, err := k.CreateSigningKey(context.Background(), "wrong", "cron"); err != CommonError.INVALID_TOKEN {
+		t.Errorf("#1: CreateSigningKey() error = %v, want %v", err, CommonError.INVALID_TOKEN)
+	}
+	if _, err := k.CreateSigningKey(context.Background(), "key", "none"); err != CommonError.OBJECT_NOT_FOUND {
+		t.Errorf("#2: CreateSigningKey() error = %v, want %v", err, CommonError.OBJECT_NOT_FOUND)
+	}
+	key, err := k.CreateSigningKey(context.Background(), "key", "cron")
+	if err != nil {
+		t.Fatalf("#3: CreateSigningKey() error = %v", err)
+	}
+	if key.ObjectID != "cron" || key.OrganizationID != "org" || key.Secret == "" || strings.Contains(key.Secret, key.ID) {
+		t.Errorf("#3: CreateSigningKey() = %+v", key)
+	}
+	// the store only holds the sealed secret
+	if stored.Secret != "" || stored.Sealed == "" || strings.Contains(stored.Sealed, key.Secret) {
+		t.Errorf("#3: stored %+v", stored)
+	}
+	if secret, err := k.openSecret(stored.Sealed); err != nil || secret != key.Secret {
+		t.Errorf("#3: openSecret() = %q, %v, want %q", secret, err, key.Secret)
+	}
+	if other, err := k.CreateSigningKey(context.Background(), "key", "cron"); err != nil || other.Secret == key.Secret {
+		t.Errorf("#4: CreateSigningKey() secret reused")
+	}
+}
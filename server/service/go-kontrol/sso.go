@@ -0,0 +1,64 @@
+package gokontrol
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+//LoginReturn where the login page sends the browser back to, signed by the validate endpoint so that the login
+//page cannot be used as an open redirect
+type LoginReturn struct {
+	ServiceID string // service the session token is issued for
+	ReturnTo  string // absolute url of the original request
+	Expires   int64  // unix second
+}
+
+//ResolveService service a request is for, as the configured resolver finds it
+func (k DefaultKontrol) ResolveService(c context.Context, req *AccessRequest) (*Service, error) {
+	service, _, err := k.resolveService(c, req)
+	return service, err
+}
+
+//SignLoginReturn signature of r, base64url(hmac-sha256(login return key, service, return url and expiry))
+func (k DefaultKontrol) SignLoginReturn(r *LoginReturn) string {
+	mac := hmac.New(sha256.New, k.loginReturnKey())
+	mac.Write([]byte(r.ServiceID + "\n" + r.ReturnTo + "\n" + strconv.FormatInt(r.Expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// loginReturnKey key of the login return signatures, derived from the secret key so they can not stand for the
+// tokens or the other values it signs
+func (k DefaultKontrol) loginReturnKey() []byte {
+	mac := hmac.New(sha256.New, []byte(k.Option.SecretKey))
+	mac.Write([]byte("login_return"))
+	return mac.Sum(nil)
+}
+
+//VerifyLoginReturn check sig is the signature of r, r is not expired and returns to an http(s) url
+func (k DefaultKontrol) VerifyLoginReturn(r *LoginReturn, sig string) error {
+	if r.Expires < time.Now().Unix() || !hmac.Equal([]byte(sig), []byte(k.SignLoginReturn(r))) {
+		return CommonError.INVALID_RETURN_URL
+	}
+	u, err := url.Parse(r.ReturnTo)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return CommonError.INVALID_RETURN_URL
+	}
+	return nil
+}
+
+// tokenSources sources of the service followed by the session cookie, accepted by every service
+func (k DefaultKontrol) tokenSources(service *Service) []*TokenSource {
+	sources := service.TokenSources
+	if len(sources) == 0 {
+		sources = defaultTokenSources
+	}
+	if k.Option.SessionCookie == "" {
+		return sources
+	}
+	return append(append(make([]*TokenSource, 0, len(sources)+1), sources...), &TokenSource{Type: TokenSourceType.COOKIE, Name: k.Option.SessionCookie})
+}
@@ -0,0 +1,94 @@
+package gokontrol
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestDefaultKontrol_VerifyLoginReturn(t *testing.T) {
+	k := DefaultKontrol{Option: DefaultKontrolOption}
+	valid := &LoginReturn{ServiceID: "sid", ReturnTo: "https://app.example.com/orders/list?page=2", Expires: time.Now().Unix() + 60}
+	sig := k.SignLoginReturn(valid)
+	// same message keyed by the secret key itself, as it signs other values
+	mac := hmac.New(sha256.New, []byte(k.Option.SecretKey))
+	mac.Write([]byte(valid.ServiceID + "\n" + valid.ReturnTo + "\n" + strconv.FormatInt(valid.Expires, 10)))
+	rawSig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name    string
+		ret     LoginReturn
+		sig     string
+		wantErr error
+	}{
+		{name: "#1: signed", ret: *valid, sig: sig},
+		{name: "#2: other return url", ret: LoginReturn{ServiceID: "sid", ReturnTo: "https://evil.example.com/", Expires: valid.Expires}, sig: sig, wantErr: CommonError.INVALID_RETURN_URL},
+		{name: "#3: other service", ret: LoginReturn{ServiceID: "admin", ReturnTo: valid.ReturnTo, Expires: valid.Expires}, sig: sig, wantErr: CommonError.INVALID_RETURN_URL},
+		{name: "#4: extended expiry", ret: LoginReturn{ServiceID: "sid", ReturnTo: valid.ReturnTo, Expires: valid.Expires + 3600}, sig: sig, wantErr: CommonError.INVALID_RETURN_URL},
+		{name: "#5: no signature", ret: *valid, wantErr: CommonError.INVALID_RETURN_URL},
+		{name: "#6: keyed by the secret key", ret: *valid, sig: rawSig, wantErr: CommonError.INVALID_RETURN_URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := k.VerifyLoginReturn(&tt.ret, tt.sig); err != tt.wantErr {
+				t.Errorf("VerifyLoginReturn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("#6: expired", func(t *testing.T) {
+		ret := &LoginReturn{ServiceID: "sid", ReturnTo: valid.ReturnTo, Expires: time.Now().Unix() - 1}
+		if err := k.VerifyLoginReturn(ret, k.SignLoginReturn(ret)); err != CommonError.INVALID_RETURN_URL {
+			t.Errorf("VerifyLoginReturn() error = %v, wantErr %v", err, CommonError.INVALID_RETURN_URL)
+		}
+	})
+	t.Run("#7: not http", func(t *testing.T) {
+		ret := &LoginReturn{ServiceID: "sid", ReturnTo: "javascript:alert(1)", Expires: valid.Expires}
+		if err := k.VerifyLoginReturn(ret, k.SignLoginReturn(ret)); err != CommonError.INVALID_RETURN_URL {
+			t.Errorf("VerifyLoginReturn() error = %v, wantErr %v", err, CommonError.INVALID_RETURN_URL)
+		}
+	})
+}
+
+func TestDefaultKontrol_IdentifyRequest_SessionCookie(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockKontrolStore(ctrl)
+	option := DefaultKontrolOption
+	option.SessionCookie = "kontrol_session"
+	k := NewKontrol(store, option)
+	obj := &Object{ID: "oid", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := k.CreateCert(obj, []*Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&Service{ID: "sid-b", ServiceID: "orders",
+		TokenSources: []*TokenSource{{Type: TokenSourceType.HEADER, Name: "X-Api-Token"}}}, nil).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).AnyTimes()
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		wantErr error
+	}{
+		{name: "#1: service source", header: http.Header{"X-Api-Token": {jwtToken}}},
+		{name: "#2: session cookie after the service sources", header: http.Header{"Cookie": {"kontrol_session=" + jwtToken}}},
+		{name: "#3: other cookie", header: http.Header{"Cookie": {"session=" + jwtToken}}, wantErr: CommonError.MISSING_TOKEN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := k.IdentifyRequest(context.Background(), &AccessRequest{Method: http.MethodGet, Path: "/orders/list", Header: tt.header})
+			if err != tt.wantErr {
+				t.Errorf("IdentifyRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package gokontrol
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+var (
+	templateParameterName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+	templatePlaceholder   = regexp.MustCompile(`\{([a-zA-Z][a-zA-Z0-9_]*)\}`)
+)
+
+//Validate check parameter names and that every placeholder of the permission keys is a parameter
+func (t *PolicyTemplate) Validate() error {
+	if len(t.Permission) == 0 {
+		return CommonError.INVALID_TEMPLATE
+	}
+	declared := make(map[string]bool, len(t.Parameters))
+	for _, p := range t.Parameters {
+		if !templateParameterName.MatchString(p) || declared[p] {
+			return CommonError.INVALID_TEMPLATE
+		}
+		declared[p] = true
+	}
+	for key, v := range t.Permission {
+		if v < PolicyPermission.ANY || v > PolicyPermission.FALSE {
+			return CommonError.MALFORM_PERMISSION
+		}
+		for _, m := range templatePlaceholder.FindAllStringSubmatch(key, -1) {
+			if !declared[m[1]] {
+				return CommonError.INVALID_TEMPLATE
+			}
+		}
+	}
+	return nil
+}
+
+//Bind permission with the placeholders replaced by the quoted parameter values.
+//Every parameter needs a value, values are single path segments
+func (t *PolicyTemplate) Bind(params map[string]string) (map[string]int, error) {
+	if len(params) != len(t.Parameters) {
+		return nil, CommonError.INVALID_PARAMETER
+	}
+	for _, p := range t.Parameters {
+		v, ok := params[p]
+		if !ok || v == "" || strings.Contains(v, "/") {
+			return nil, CommonError.INVALID_PARAMETER
+		}
+	}
+	rs := make(map[string]int, len(t.Permission))
+	for key, v := range t.Permission {
+		bound := templatePlaceholder.ReplaceAllStringFunc(key, func(ph string) string {
+			return regexp.QuoteMeta(params[ph[1:len(ph)-1]])
+		})
+		if _, err := regexp.Compile(bound); err != nil {
+			return nil, CommonError.MALFORM_PERMISSION
+		}
+		rs[bound] = v
+	}
+	return rs, nil
+}
+
+//CreatePolicyTemplate create a template of the service
+func (k DefaultKontrol) CreatePolicyTemplate(ctx context.Context, servicekey string, tpl *PolicyTemplate) error {
+	service, err := k.serviceByID(ctx, tpl.ServiceID)
+	if err != nil {
+		return err
+	}
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.POLICY_ADMIN); err != nil {
+		return err
+	}
+	if err := tpl.Validate(); err != nil {
+		return err
+	}
+	old, err := k.store.GetPolicyTemplateByID(ctx, tpl.ID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return err
+	}
+	if old != nil {
+		return CommonError.INVALID_TEMPLATE
+	}
+	tpl.OrganizationID = service.OrganizationID
+	return k.store.CreatePolicyTemplate(ctx, tpl)
+}
+
+//UpdatePolicyTemplate update the template then rebind every instance and expire their objects,
+//return the ids of the updated instances
+func (k DefaultKontrol) UpdatePolicyTemplate(ctx context.Context, servicekey string, tpl *PolicyTemplate) ([]string, error) {
+	old, err := k.policyTemplate(ctx, servicekey, tpl.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tpl.Validate(); err != nil {
+		return nil, err
+	}
+	tpl.ServiceID = old.ServiceID
+	tpl.OrganizationID = old.OrganizationID
+
+	service, err := k.serviceByID(ctx, tpl.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := k.store.GetPoliciesByTemplateID(ctx, tpl.ID)
+	if err != nil {
+		return nil, err
+	}
+	// rebind all first, a template unable to bind an instance or binding one the linter rejects is rejected as a whole
+	for _, p := range instances {
+		if p.Permission, err = tpl.Bind(p.Parameters); err != nil {
+			return nil, err
+		}
+		if err := lintErrors(LintPolicy(p, service.EnforcePolicy, service.Routes)); err != nil {
+			return nil, err
+		}
+	}
+	if err := k.store.UpdatePolicyTemplate(ctx, tpl); err != nil {
+		return nil, err
+	}
+	rs := make([]string, 0, len(instances))
+	for _, p := range instances {
+		if err := k.store.UpdatePolicy(ctx, p); err != nil {
+			return rs, err
+		}
+		if err := k.expireObjectsByPolicy(ctx, p.ID); err != nil {
+			return rs, err
+		}
+		rs = append(rs, p.ID)
+	}
+	return rs, nil
+}
+
+//InstantiatePolicyTemplate create policy bound from the template with params, the policy belongs to the template service
+func (k DefaultKontrol) InstantiatePolicyTemplate(ctx context.Context, servicekey string, templateID string, policy *Policy, params map[string]string) error {
+	tpl, err := k.policyTemplate(ctx, servicekey, templateID)
+	if err != nil {
+		return err
+	}
+	perm, err := tpl.Bind(params)
+	if err != nil {
+		return err
+	}
+	policy.ServiceID = tpl.ServiceID
+	policy.TemplateID = tpl.ID
+	policy.Parameters = params
+	policy.Permission = perm
+	return k.CreatePolicy(ctx, servicekey, policy)
+}
+
+// policyTemplate load the template, authorized as policy admin of its service
+func (k DefaultKontrol) policyTemplate(ctx context.Context, servicekey string, templateID string) (*PolicyTemplate, error) {
+	tpl, err := k.store.GetPolicyTemplateByID(ctx, templateID)
+	if err != nil && err != CommonError.NOT_FOUND {
+		return nil, err
+	}
+	if tpl == nil || err == CommonError.NOT_FOUND {
+		return nil, CommonError.TEMPLATE_NOT_FOUND
+	}
+	service, err := k.serviceByID(ctx, tpl.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.authorizeService(ctx, service, servicekey, AdminRole.POLICY_ADMIN); err != nil {
+		return nil, err
+	}
+	if tpl.OrganizationID != service.OrganizationID {
+		return nil, CommonError.CROSS_TENANT
+	}
+	return tpl, nil
+}
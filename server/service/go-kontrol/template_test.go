@@ -0,0 +1,124 @@
+package gokontrol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestPolicyTemplate_Bind(t *testing.T) {
+	tpl := &PolicyTemplate{
+		Parameters: []string{"project"},
+		Permission: map[string]int{"GET@/projects/{project}/.*": PolicyPermission.TRUE, "DELETE@/projects/{project}": PolicyPermission.FALSE},
+	}
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    map[string]int
+		wantErr error
+	}{
+		{name: "#1: bound keys",
+			params: map[string]string{"project": "apollo"},
+			want:   map[string]int{"GET@/projects/apollo/.*": PolicyPermission.TRUE, "DELETE@/projects/apollo": PolicyPermission.FALSE},
+		},
+		{name: "#2: values are quoted",
+			params: map[string]string{"project": "a.b"},
+			want:   map[string]int{`GET@/projects/a\.b/.*`: PolicyPermission.TRUE, `DELETE@/projects/a\.b`: PolicyPermission.FALSE},
+		},
+		{name: "#3: missing parameter", params: map[string]string{}, wantErr: CommonError.INVALID_PARAMETER},
+		{name: "#4: unknown parameter", params: map[string]string{"project": "apollo", "region": "eu"}, wantErr: CommonError.INVALID_PARAMETER},
+		{name: "#5: value spanning segments", params: map[string]string{"project": "apollo/admin"}, wantErr: CommonError.INVALID_PARAMETER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tpl.Bind(tt.params)
+			if err != tt.wantErr {
+				t.Errorf("Bind() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tpl     *PolicyTemplate
+		wantErr bool
+	}{
+		{name: "#1: valid", tpl: &PolicyTemplate{Parameters: []string{"region"}, Permission: map[string]int{"GET@/{region}/.{1,3}": 1}}, wantErr: false},
+		{name: "#2: undeclared placeholder", tpl: &PolicyTemplate{Parameters: []string{"region"}, Permission: map[string]int{"GET@/{project}": 1}}, wantErr: true},
+		{name: "#3: duplicated parameter", tpl: &PolicyTemplate{Parameters: []string{"region", "region"}, Permission: map[string]int{"GET@/{region}": 1}}, wantErr: true},
+		{name: "#4: malformed permission value", tpl: &PolicyTemplate{Parameters: []string{"region"}, Permission: map[string]int{"GET@/{region}": 3}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tpl.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultKontrol_UpdatePolicyTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := &Service{ID: "sid", OrganizationID: "org-a", Key: DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("service-key")}
+	old := &PolicyTemplate{ID: "tpl-1", ServiceID: "sid", OrganizationID: "org-a", Parameters: []string{"project"}, Permission: map[string]int{"GET@/projects/{project}": 1}}
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetPolicyTemplateByID(gomock.Any(), "tpl-1").Return(old, nil)
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil).Times(2)
+	kontrolStore.EXPECT().GetPoliciesByTemplateID(gomock.Any(), "tpl-1").Return([]*Policy{
+		{ID: "apollo", TemplateID: "tpl-1", Parameters: map[string]string{"project": "apollo"}},
+		{ID: "gemini", TemplateID: "tpl-1", Parameters: map[string]string{"project": "gemini"}},
+	}, nil)
+	kontrolStore.EXPECT().UpdatePolicyTemplate(gomock.Any(), gomock.Any()).Return(nil)
+	kontrolStore.EXPECT().UpdatePolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p *Policy) error {
+		want := map[string]int{"(GET|HEAD)@/projects/" + p.Parameters["project"] + "/.*": 1}
+		if !reflect.DeepEqual(p.Permission, want) {
+			t.Errorf("UpdatePolicy() permission = %v, want %v", p.Permission, want)
+		}
+		return nil
+	}).Times(2)
+	kontrolStore.EXPECT().ExpiredObjectsByPolicy(gomock.Any(), "apollo").Return(nil)
+	kontrolStore.EXPECT().ExpiredObjectsByPolicy(gomock.Any(), "gemini").Return(nil)
+
+	k := NewBasicKontrol(kontrolStore)
+	got, err := k.UpdatePolicyTemplate(context.Background(), "service-key", &PolicyTemplate{
+		ID: "tpl-1", Parameters: []string{"project"}, Permission: map[string]int{"(GET|HEAD)@/projects/{project}/.*": 1},
+	})
+	if err != nil {
+		t.Fatalf("UpdatePolicyTemplate() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"apollo", "gemini"}) {
+		t.Errorf("UpdatePolicyTemplate() got = %v", got)
+	}
+}
+
+func TestDefaultKontrol_InstantiatePolicyTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := &Service{ID: "sid", OrganizationID: "org-a", Key: DefaultKontrol{Option: DefaultKontrolOption}.serviceKeySign("service-key")}
+	kontrolStore := NewMockKontrolStore(ctrl)
+	kontrolStore.EXPECT().GetPolicyTemplateByID(gomock.Any(), "tpl-1").Return(&PolicyTemplate{ID: "tpl-1", ServiceID: "sid", OrganizationID: "org-a", Parameters: []string{"project"}, Permission: map[string]int{"GET@/projects/{project}": 1}}, nil)
+	kontrolStore.EXPECT().GetServiceByID(gomock.Any(), "sid").Return(service, nil).Times(2)
+	kontrolStore.EXPECT().GetPolicyByID(gomock.Any(), "p-1").Return(nil, CommonError.NOT_FOUND)
+	kontrolStore.EXPECT().CreatePolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p *Policy) error {
+		if p.TemplateID != "tpl-1" || p.ServiceID != "sid" || p.Permission["GET@/projects/apollo"] != 1 {
+			t.Errorf("CreatePolicy() got = %+v", p)
+		}
+		return nil
+	})
+
+	k := NewBasicKontrol(kontrolStore)
+	if err := k.InstantiatePolicyTemplate(context.Background(), "service-key", "tpl-1", &Policy{ID: "p-1"}, map[string]string{"project": "apollo"}); err != nil {
+		t.Errorf("InstantiatePolicyTemplate() error = %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+)
+
+var (
+	errUnknownDialect  = errors.New("unknown gateway dialect")
+	errUndetected      = errors.New("no gateway dialect detected, X-Forwarded-Uri or X-Original-URI is mandatory")
+	errOriginalHeaders = errors.New("X-Original-URI and X-Original-Method are mandatory")
+)
+
+//GatewayDialect headers a gateway describes the original request with when asking the auth endpoint
+type GatewayDialect interface {
+	Name() string
+	Detect(r *http.Request) bool                                     // whether r carries the dialect headers
+	AccessRequest(r *http.Request) (*gokontrol.AccessRequest, error) // original request described by r
+}
+
+//GatewayDialects supported dialects, in auto-detection order
+var GatewayDialects = []GatewayDialect{TraefikDialect{}, CaddyDialect{}, NginxDialect{}}
+
+//GatewayAccessRequest request to authorize described by r in the dialect name, detected when name is empty
+func GatewayAccessRequest(r *http.Request, name string) (*gokontrol.AccessRequest, error) {
+	for _, d := range GatewayDialects {
+		if name == d.Name() || (name == "" && d.Detect(r)) {
+			return d.AccessRequest(r)
+		}
+	}
+	if name != "" {
+		return nil, errUnknownDialect
+	}
+	return nil, errUndetected
+}
+
+//TraefikDialect forwardAuth with trustForwardHeader, X-Forwarded-Method and X-Forwarded-Uri
+type TraefikDialect struct{}
+
+func (TraefikDialect) Name() string { return "traefik" }
+
+func (TraefikDialect) Detect(r *http.Request) bool {
+	return r.Header.Get("X-Forwarded-Uri") != ""
+}
+
+func (TraefikDialect) AccessRequest(r *http.Request) (*gokontrol.AccessRequest, error) {
+	return ForwardAuthRequest(r)
+}
+
+//CaddyDialect forward_auth, the headers of Traefik: Caddy sets X-Forwarded-Method and X-Forwarded-Uri and
+//replaces X-Forwarded-For of untrusted clients by their address
+type CaddyDialect struct{}
+
+func (CaddyDialect) Name() string { return "caddy" }
+
+// Detect requests of Caddy are detected as Traefik ones, which read the same
+func (CaddyDialect) Detect(r *http.Request) bool {
+	return r.Header.Get("X-Forwarded-Uri") != ""
+}
+
+func (CaddyDialect) AccessRequest(r *http.Request) (*gokontrol.AccessRequest, error) {
+	return ForwardAuthRequest(r)
+}
+
+//NginxDialect auth_request subrequest, the location sets X-Original-URI to $request_uri, X-Original-Method to
+//$request_method and X-Real-IP to $remote_addr, subrequests are always GET
+type NginxDialect struct{}
+
+func (NginxDialect) Name() string { return "nginx" }
+
+func (NginxDialect) Detect(r *http.Request) bool {
+	return r.Header.Get("X-Original-Uri") != ""
+}
+
+func (NginxDialect) AccessRequest(r *http.Request) (*gokontrol.AccessRequest, error) {
+	method := r.Header.Get("X-Original-Method")
+	uri := r.Header.Get("X-Original-Uri")
+	if method == "" || uri == "" {
+		return nil, errOriginalHeaders
+	}
+	req := &gokontrol.AccessRequest{
+		Method: strings.ToUpper(method),
+		Proto:  firstHeader(r.Header, "X-Original-Proto", "X-Forwarded-Proto", "X-Scheme"),
+		Host:   firstHeader(r.Header, "X-Original-Host", "X-Forwarded-Host"),
+		Path:   uri,
+		Header: r.Header,
+	}
+	if req.Host == "" {
+		req.Host = r.Host
+	}
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		req.Path, req.Query = uri[:i], uri[i+1:]
+	}
+	// nginx appends $remote_addr to X-Forwarded-For only when configured, X-Real-IP is the usual peer
+	req.ClientIP = r.Header.Get("X-Real-Ip")
+	if req.ClientIP == "" {
+		req.ClientIP = forwardedClientIP(r)
+	}
+	return req, nil
+}
+
+// firstHeader value of the first header of names set
+func firstHeader(h http.Header, names ...string) string {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
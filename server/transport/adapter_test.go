@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+// gatewayAuthRequest auth request a gateway of dialect sends for r from client 203.0.113.7
+type gatewayAuthRequest func(t *testing.T, authURL string, r *http.Request) *http.Request
+
+// dialectGateways emulate the auth call of each gateway as its documented configuration makes it
+var dialectGateways = map[string]gatewayAuthRequest{
+	// forwardAuth with trustForwardHeader
+	"traefik": func(t *testing.T, authURL string, r *http.Request) *http.Request {
+		authReq := newAuthRequest(t, authURL, r)
+		authReq.Header.Set("X-Forwarded-Method", r.Method)
+		authReq.Header.Set("X-Forwarded-Proto", "https")
+		authReq.Header.Set("X-Forwarded-Host", r.Host)
+		authReq.Header.Set("X-Forwarded-Uri", r.URL.RequestURI())
+		authReq.Header.Set("X-Forwarded-For", "203.0.113.7")
+		return authReq
+	},
+	// forward_auth, X-Forwarded-For of an untrusted client is replaced by its address
+	"caddy": func(t *testing.T, authURL string, r *http.Request) *http.Request {
+		authReq := newAuthRequest(t, authURL, r)
+		authReq.Header.Set("X-Forwarded-Method", r.Method)
+		authReq.Header.Set("X-Forwarded-Uri", r.URL.RequestURI())
+		authReq.Header.Set("X-Forwarded-Host", r.Host)
+		authReq.Header.Set("X-Forwarded-Proto", "https")
+		authReq.Header.Set("X-Forwarded-For", "203.0.113.7")
+		return authReq
+	},
+	// auth_request location with proxy_set_header X-Original-URI, X-Original-Method, X-Original-Host,
+	// X-Forwarded-Proto and X-Real-IP
+	"nginx": func(t *testing.T, authURL string, r *http.Request) *http.Request {
+		authReq := newAuthRequest(t, authURL, r)
+		authReq.Header.Set("X-Original-Uri", r.URL.RequestURI())
+		authReq.Header.Set("X-Original-Method", r.Method)
+		authReq.Header.Set("X-Original-Host", r.Host)
+		authReq.Header.Set("X-Forwarded-Proto", "https")
+		authReq.Header.Set("X-Real-Ip", "203.0.113.7")
+		return authReq
+	},
+}
+
+func newAuthRequest(t *testing.T, authURL string, r *http.Request) *http.Request {
+	authReq, err := http.NewRequest(http.MethodGet, authURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range r.Header {
+		authReq.Header[name] = values
+	}
+	return authReq
+}
+
+func TestGatewayAccessRequest_Conformance(t *testing.T) {
+	want := gokontrol.AccessRequest{Method: "DELETE", Proto: "https", Host: "api.example.com", Path: "/orders/1", Query: "force=true", ClientIP: "203.0.113.7"}
+	for name, gateway := range dialectGateways {
+		for _, dialect := range []string{name, ""} {
+			t.Run(name+"/"+dialect, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodDelete, "http://api.example.com/orders/1?force=true", nil)
+				got, err := GatewayAccessRequest(gateway(t, "http://sso.local/internal_api/validate", r), dialect)
+				if err != nil {
+					t.Fatalf("GatewayAccessRequest() error = %v", err)
+				}
+				got.Header = nil
+				if !reflect.DeepEqual(*got, want) {
+					t.Errorf("GatewayAccessRequest() got = %+v, want %+v", *got, want)
+				}
+			})
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://sso.local/internal_api/validate", nil)
+	if _, err := GatewayAccessRequest(r, ""); err != errUndetected {
+		t.Errorf("GatewayAccessRequest() error = %v, want %v", err, errUndetected)
+	}
+	if _, err := GatewayAccessRequest(r, "haproxy"); err != errUnknownDialect {
+		t.Errorf("GatewayAccessRequest() error = %v, want %v", err, errUnknownDialect)
+	}
+	r.Header.Set("X-Original-Uri", "/orders/1")
+	if _, err := GatewayAccessRequest(r, "nginx"); err != errOriginalHeaders {
+		t.Errorf("GatewayAccessRequest() error = %v, want %v", err, errOriginalHeaders)
+	}
+}
+
+func TestForwardAuthHandler_Dialects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kontrol, jwtToken := extAuthzKontrol(t, ctrl)
+
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+	e.GET("/internal_api/validate/:dialect", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	tests := []struct {
+		name          string
+		method        string
+		header        http.Header
+		wantStatus    int
+		wantChallenge string
+	}{
+		{name: "#1: allowed", method: http.MethodGet, header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusOK},
+		{name: "#2: method of the original request", method: http.MethodPost, header: http.Header{"Authorization": {"Bearer " + jwtToken}},
+			wantStatus: http.StatusForbidden, wantChallenge: `Bearer realm="kontrol", error="insufficient_scope"`},
+		{name: "#3: no token", method: http.MethodGet, wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+	}
+	for name, gateway := range dialectGateways {
+		for _, path := range []string{"/internal_api/validate/" + name, "/internal_api/validate"} {
+			for _, tt := range tests {
+				t.Run(name+path+tt.name, func(t *testing.T) {
+					r := httptest.NewRequest(tt.method, "http://gateway.local/orders/list", nil)
+					for k, values := range tt.header {
+						r.Header[k] = values
+					}
+					resp, err := http.DefaultClient.Do(gateway(t, auth.URL+path, r))
+					if err != nil {
+						t.Fatal(err)
+					}
+					resp.Body.Close()
+					if resp.StatusCode != tt.wantStatus {
+						t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+					}
+					if got := resp.Header.Get("WWW-Authenticate"); got != tt.wantChallenge {
+						t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+					}
+					if tt.wantStatus == http.StatusOK && resp.Header.Get("X-Auth-Object-Id") != "oid" {
+						t.Errorf("X-Auth-Object-Id = %q, want oid", resp.Header.Get("X-Auth-Object-Id"))
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("unknown dialect", func(t *testing.T) {
+		resp, err := http.Get(auth.URL + "/internal_api/validate/haproxy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+		}
+	})
+}
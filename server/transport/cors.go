@@ -0,0 +1,83 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	"github.com/neko-neko/echo-logrus/v2/log"
+)
+
+// corsSkipper endpoints Traefik, Envoy or the gateway call on behalf of the browser, their CORS is the one the proxy
+// renders for the requested service
+func corsSkipper(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return strings.HasPrefix(path, "/internal_api/validate") || strings.HasPrefix(path, "/internal_api/ext_authz")
+}
+
+//CORSHandler CORS of the SSO endpoints, an origin is allowed as the enabled services allowing it configure, merged.
+//Preflights are answered here, disallowed origins get no CORS header and the browser blocks them
+func CORSHandler(s *wrapper.Service) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			origin := req.Header.Get(echo.HeaderOrigin)
+			if origin == "" || corsSkipper(c) {
+				return next(c)
+			}
+			h := c.Response().Header()
+			h.Add(echo.HeaderVary, echo.HeaderOrigin)
+			preflight := req.Method == http.MethodOptions && req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+
+			cors, err := s.Kontrol.OriginCORS(req.Context(), origin)
+			if err != nil {
+				log.Logger().Error(err)
+			}
+			if cors == nil {
+				if preflight {
+					return c.NoContent(http.StatusNoContent)
+				}
+				return next(c)
+			}
+			h.Set(echo.HeaderAccessControlAllowOrigin, origin)
+			if cors.AllowCredentials {
+				h.Set(echo.HeaderAccessControlAllowCredentials, "true")
+			}
+			if !preflight {
+				if len(cors.ExposeHeaders) > 0 {
+					h.Set(echo.HeaderAccessControlExposeHeaders, strings.Join(cors.ExposeHeaders, ","))
+				}
+				return next(c)
+			}
+
+			h.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+			h.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+			h.Set(echo.HeaderAccessControlAllowMethods, strings.Join(cors.Methods(), ","))
+			if len(cors.AllowHeaders) > 0 {
+				h.Set(echo.HeaderAccessControlAllowHeaders, strings.Join(cors.AllowHeaders, ","))
+			}
+			if cors.MaxAge > 0 {
+				h.Set(echo.HeaderAccessControlMaxAge, strconv.Itoa(cors.MaxAge))
+			}
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+}
+
+// corsPreflight whether req is a CORS preflight of an origin its service allows, browsers send them without
+// credentials. Other OPTIONS requests are authorized as any method
+func corsPreflight(ctx context.Context, s *wrapper.Service, req *gokontrol.AccessRequest) bool {
+	origin := req.Header.Get(echo.HeaderOrigin)
+	if req.Method != http.MethodOptions || origin == "" || req.Header.Get(echo.HeaderAccessControlRequestMethod) == "" {
+		return false
+	}
+	service, err := s.Kontrol.ResolveService(ctx, req)
+	if err != nil {
+		return false
+	}
+	return service.CORS.AllowsOrigin(origin)
+}
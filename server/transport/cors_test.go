@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCORSHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	kontrol := gokontrol.NewMockKontrol(ctrl)
+	allowed := &gokontrol.CORS{AllowOrigins: []string{"https://app.example.com"}, AllowMethods: []string{"GET", "POST"},
+		AllowHeaders: []string{"Authorization", "Content-Type"}, ExposeHeaders: []string{"X-Request-Id"}, AllowCredentials: true, MaxAge: 600}
+	kontrol.EXPECT().OriginCORS(gomock.Any(), "https://app.example.com").Return(allowed, nil).AnyTimes()
+	kontrol.EXPECT().OriginCORS(gomock.Any(), "https://evil.example.com").Return(nil, nil).AnyTimes()
+
+	e := echo.New()
+	e.Use(CORSHandler(&wrapper.Service{Kontrol: kontrol}))
+	e.POST("/internal_api/cert", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.GET("/internal_api/validate", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{name: "#1: preflight of an allowed origin", method: http.MethodOptions, target: "/internal_api/cert", origin: "https://app.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Allow-Methods": "GET,POST",
+				"Access-Control-Allow-Headers": "Authorization,Content-Type", "Access-Control-Allow-Credentials": "true", "Access-Control-Max-Age": "600"}},
+		{name: "#2: request of an allowed origin", method: http.MethodPost, target: "/internal_api/cert", origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Expose-Headers": "X-Request-Id", "Access-Control-Allow-Methods": ""}},
+		{name: "#3: preflight of another origin", method: http.MethodOptions, target: "/internal_api/cert", origin: "https://evil.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""}},
+		{name: "#4: request of another origin", method: http.MethodPost, target: "/internal_api/cert", origin: "https://evil.example.com",
+			wantStatus: http.StatusOK, wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""}},
+		{name: "#5: validate left to the proxy", method: http.MethodGet, target: "/internal_api/validate", origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set(echo.HeaderOrigin, tt.origin)
+			if tt.preflight {
+				req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
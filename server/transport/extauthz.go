@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/hungvtc/traefik-integrate/server/constant"
+	"github.com/hungvtc/traefik-integrate/server/repository"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// extAuthzPrefix path_prefix of the Envoy http_service, Envoy appends the original path
+const extAuthzPrefix = "/internal_api/ext_authz"
+
+// extAuthzHeadersToRemove response header listing the upstream request headers Envoy removes
+const extAuthzHeadersToRemove = "X-Envoy-Auth-Headers-To-Remove"
+
+//ExtAuthzRequest request to authorize of an Envoy ext_authz http_service check, Envoy sends the original method,
+//path after the path_prefix, host and the allowed headers
+func ExtAuthzRequest(r *http.Request) *gokontrol.AccessRequest {
+	req := &gokontrol.AccessRequest{
+		Method:   r.Method,
+		Proto:    r.Header.Get("X-Forwarded-Proto"),
+		Host:     r.Host,
+		Path:     strings.TrimPrefix(r.URL.Path, extAuthzPrefix),
+		Query:    r.URL.RawQuery,
+		ClientIP: forwardedClientIP(r),
+		Header:   r.Header,
+	}
+	if req.Path == "" {
+		req.Path = "/"
+	}
+	return req
+}
+
+//ExtAuthzHandler Envoy ext_authz http_service endpoint, answered as forwardAuth. Allowed requests get the identity
+//headers, to list in allowed_upstream_headers, and the client supplied identity headers to remove
+func ExtAuthzHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type ExtAuthzResponse struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+
+		req := ExtAuthzRequest(c.Request())
+		if corsPreflight(c.Request().Context(), s, req) {
+			return c.JSON(http.StatusOK, ExtAuthzResponse{Code: http.StatusOK, Message: "ok"})
+		}
+
+		identity, denial := identifyRequest(c.Request().Context(), s, req)
+		if denial != nil {
+			for name, values := range denial.Header() {
+				c.Response().Header()[name] = values
+			}
+			return c.JSON(denial.Code, ExtAuthzResponse{Code: denial.Code, Message: denial.Message})
+		}
+
+		header := identity.Header()
+		for name, values := range header {
+			c.Response().Header()[name] = values
+		}
+		if remove := spoofedIdentityHeaders(req.Header, header); len(remove) > 0 {
+			c.Response().Header().Set(extAuthzHeadersToRemove, strings.Join(remove, ","))
+		}
+		return c.JSON(http.StatusOK, ExtAuthzResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
+
+//ExtAuthzServer Envoy envoy.service.auth.v3.Authorization, checks are answered as forwardAuth requests
+type ExtAuthzServer struct {
+	authv3.UnimplementedAuthorizationServer
+	s *wrapper.Service
+}
+
+//NewExtAuthzServer authorization service validating with the kontrol of s
+func NewExtAuthzServer(s *wrapper.Service) *ExtAuthzServer {
+	return &ExtAuthzServer{s: s}
+}
+
+//NewExtAuthzGRPC grpc server of the authorization service, each check gets a store session
+func NewExtAuthzGRPC(s *wrapper.Service) *grpc.Server {
+	g := grpc.NewServer(grpc.UnaryInterceptor(grpcSessionInterceptor(s.DB)))
+	authv3.RegisterAuthorizationServer(g, NewExtAuthzServer(s))
+	return g
+}
+
+//ExtAuthzCheckRequest request to authorize of an Authorization/Check call. Envoy lower cases the header names and
+//sends the query in the path, the client IP is the downstream peer
+func ExtAuthzCheckRequest(check *authv3.CheckRequest) (*gokontrol.AccessRequest, error) {
+	r := check.GetAttributes().GetRequest().GetHttp()
+	if r == nil || r.GetMethod() == "" || r.GetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "attributes.request.http method and path are mandatory")
+	}
+	req := &gokontrol.AccessRequest{
+		Method:   strings.ToUpper(r.GetMethod()),
+		Proto:    r.GetScheme(),
+		Host:     r.GetHost(),
+		Path:     r.GetPath(),
+		Query:    r.GetQuery(),
+		ClientIP: check.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress(),
+		Header:   http.Header{},
+	}
+	if i := strings.IndexByte(req.Path, '?'); i >= 0 {
+		req.Path, req.Query = req.Path[:i], req.Path[i+1:]
+	}
+	for name, value := range r.GetHeaders() {
+		if !strings.HasPrefix(name, ":") {
+			req.Header.Set(name, value)
+		}
+	}
+	return req, nil
+}
+
+//Check allow the request with the identity headers to set upstream, or deny it with the forwardAuth status
+func (a *ExtAuthzServer) Check(ctx context.Context, check *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	req, err := ExtAuthzCheckRequest(check)
+	if err != nil {
+		return nil, err
+	}
+	if corsPreflight(ctx, a.s, req) {
+		return &authv3.CheckResponse{Status: &rpcstatus.Status{Code: int32(codes.OK)}, HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{}}}, nil
+	}
+
+	identity, denial := identifyRequest(ctx, a.s, req)
+	if denial != nil {
+		code := codes.Internal
+		switch denial.Code {
+		case http.StatusUnauthorized, http.StatusFound:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusTooManyRequests:
+			code = codes.ResourceExhausted
+		}
+		denied := &authv3.DeniedHttpResponse{Status: &typev3.HttpStatus{Code: typev3.StatusCode(denial.Code)}, Body: denial.Message}
+		if header := denial.Header(); len(header) > 0 {
+			denied.Headers = extAuthzHeaders(header)
+		}
+		return &authv3.CheckResponse{Status: &rpcstatus.Status{Code: int32(code), Message: denial.Message}, HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: denied}}, nil
+	}
+
+	header := identity.Header()
+	ok := &authv3.OkHttpResponse{Headers: extAuthzHeaders(header)}
+	for _, name := range spoofedIdentityHeaders(req.Header, header) {
+		ok.HeadersToRemove = append(ok.HeadersToRemove, strings.ToLower(name))
+	}
+	return &authv3.CheckResponse{Status: &rpcstatus.Status{Code: int32(codes.OK)}, HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok}}, nil
+}
+
+// extAuthzHeaders header as envoy options replacing the request values
+func extAuthzHeaders(h http.Header) []*corev3.HeaderValueOption {
+	options := make([]*corev3.HeaderValueOption, 0, len(h))
+	for name, values := range h {
+		for _, v := range values {
+			options = append(options, &corev3.HeaderValueOption{
+				Header:       &corev3.HeaderValue{Key: name, Value: v},
+				AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			})
+		}
+	}
+	return options
+}
+
+// spoofedIdentityHeaders identity headers of the client request the identity does not overwrite, the proxy must
+// remove them as strip-auth-headers does for Traefik
+func spoofedIdentityHeaders(request http.Header, identity http.Header) []string {
+	names := make([]string, 0)
+	for name := range request {
+		if strings.HasPrefix(name, identityHeaderPrefix) && identity.Get(name) == "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// grpcSessionInterceptor store session of the call, as gormSessionHandler for http
+func grpcSessionInterceptor(db repository.Database) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		txi, err := db.Session()
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return handler(context.WithValue(ctx, constant.ContextKeyTransaction, txi), req)
+	}
+}
+
+//ServeExtAuthzGRPC listen on port and serve the authorization service until it fails
+func ServeExtAuthzGRPC(s *wrapper.Service, port string) error {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	return NewExtAuthzGRPC(s).Serve(lis)
+}
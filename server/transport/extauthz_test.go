@@ -0,0 +1,219 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// extAuthzKontrol kontrol allowing GET /orders/list to the token
+func extAuthzKontrol(t *testing.T, ctrl *gomock.Controller) (gokontrol.Kontrol, string) {
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	kontrol := gokontrol.NewBasicKontrol(store)
+	obj := &gokontrol.Object{ID: "oid", GlobalID: "gid", ExternalID: "alice", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := kontrol.CreateCert(obj, []*gokontrol.Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders"}, nil).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).AnyTimes()
+	return kontrol, jwtToken
+}
+
+// envoyHTTPService call the auth server the way the Envoy ext_authz http_service does with path_prefix
+func envoyHTTPService(t *testing.T, authURL string, r *http.Request) *http.Response {
+	authReq, err := http.NewRequest(r.Method, authURL+extAuthzPrefix+r.URL.RequestURI(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authReq.Host = r.Host
+	for name, values := range r.Header {
+		authReq.Header[name] = values
+	}
+	authReq.Header.Set("X-Forwarded-For", "203.0.113.7")
+	authReq.Header.Set("X-Forwarded-Proto", "https")
+	resp, err := http.DefaultClient.Do(authReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestExtAuthzHandler_Envoy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kontrol, jwtToken := extAuthzKontrol(t, ctrl)
+
+	e := echo.New()
+	e.Any(extAuthzPrefix+"/*", ExtAuthzHandler(&wrapper.Service{Kontrol: kontrol}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		header        http.Header
+		wantStatus    int
+		wantChallenge string
+		wantRemove    string
+	}{
+		{name: "#1: allowed", method: http.MethodGet, target: "/orders/list?page=2",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusOK},
+		{name: "#2: method of the original request", method: http.MethodPost, target: "/orders/list",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusForbidden, wantChallenge: `Bearer realm="kontrol", error="insufficient_scope"`},
+		{name: "#3: no token", method: http.MethodGet, target: "/orders/list",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+		{name: "#4: invalid token", method: http.MethodGet, target: "/orders/list",
+			header: http.Header{"Authorization": {"Bearer not-a-jwt"}}, wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol", error="invalid_token"`},
+		{name: "#5: client supplied identity headers removed", method: http.MethodGet, target: "/orders/list",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}, "X-Auth-Attribute-Role": {"admin"}}, wantStatus: http.StatusOK, wantRemove: "X-Auth-Attribute-Role"},
+		{name: "#6: options without preflight headers", method: http.MethodOptions, target: "/orders/list",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://gateway.local"+tt.target, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			resp := envoyHTTPService(t, auth.URL, r)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if got := resp.Header.Get(extAuthzHeadersToRemove); got != tt.wantRemove {
+				t.Errorf("%s = %q, want %q", extAuthzHeadersToRemove, got, tt.wantRemove)
+			}
+			if tt.wantStatus == http.StatusOK && tt.method != http.MethodOptions && resp.Header.Get("X-Auth-Object-Id") != "oid" {
+				t.Errorf("X-Auth-Object-Id = %q, want oid", resp.Header.Get("X-Auth-Object-Id"))
+			}
+		})
+	}
+}
+
+func TestExtAuthzServer_Check(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kontrol, jwtToken := extAuthzKontrol(t, ctrl)
+
+	// the interceptor is left out, the mock store needs no session
+	g := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(g, NewExtAuthzServer(&wrapper.Service{Kontrol: kontrol}))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go g.Serve(lis)
+	defer g.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := authv3.NewAuthorizationClient(conn)
+
+	check := func(method string, path string, headers map[string]string) *authv3.CheckRequest {
+		return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+			Source: &authv3.AttributeContext_Peer{Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{Address: "203.0.113.7"}}}},
+			Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+				Method: method, Path: path, Host: "gateway.local", Scheme: "https", Headers: headers}},
+		}}
+	}
+
+	tests := []struct {
+		name          string
+		req           *authv3.CheckRequest
+		wantCode      codes.Code
+		wantHTTP      int
+		wantChallenge string
+		wantHeaders   map[string]string
+		wantRemove    []string
+	}{
+		{name: "#1: allowed, query stripped from the permission target",
+			req:      check("GET", "/orders/list?page=2", map[string]string{"authorization": "Bearer " + jwtToken}),
+			wantCode: codes.OK, wantHeaders: map[string]string{"X-Auth-Object-Id": "oid", "X-Auth-External-Id": "alice"}},
+		{name: "#2: no permission",
+			req:      check("POST", "/orders/list", map[string]string{"authorization": "Bearer " + jwtToken}),
+			wantCode: codes.PermissionDenied, wantHTTP: http.StatusForbidden, wantChallenge: `Bearer realm="kontrol", error="insufficient_scope"`},
+		{name: "#3: no token", req: check("GET", "/orders/list", nil),
+			wantCode: codes.Unauthenticated, wantHTTP: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+		{name: "#4: invalid token", req: check("GET", "/orders/list", map[string]string{"authorization": "Bearer not-a-jwt"}),
+			wantCode: codes.Unauthenticated, wantHTTP: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol", error="invalid_token"`},
+		{name: "#5: client supplied identity headers removed",
+			req:      check("GET", "/orders/list", map[string]string{"authorization": "Bearer " + jwtToken, "x-auth-object-id": "root", "x-auth-attribute-role": "admin"}),
+			wantCode: codes.OK, wantHeaders: map[string]string{"X-Auth-Object-Id": "oid"}, wantRemove: []string{"x-auth-attribute-role"}},
+		{name: "#6: options without preflight headers", req: check("OPTIONS", "/orders/list", nil),
+			wantCode: codes.Unauthenticated, wantHTTP: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Check(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := codes.Code(resp.GetStatus().GetCode()); got != tt.wantCode {
+				t.Errorf("status = %v, want %v", got, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK {
+				denied := resp.GetDeniedResponse()
+				if got := int(denied.GetStatus().GetCode()); got != tt.wantHTTP {
+					t.Errorf("http status = %d, want %d", got, tt.wantHTTP)
+				}
+				challenge := ""
+				for _, h := range denied.GetHeaders() {
+					if h.GetHeader().GetKey() == "Www-Authenticate" {
+						challenge = h.GetHeader().GetValue()
+					}
+				}
+				if challenge != tt.wantChallenge {
+					t.Errorf("WWW-Authenticate = %q, want %q", challenge, tt.wantChallenge)
+				}
+				return
+			}
+			ok := resp.GetOkResponse()
+			headers := map[string]string{}
+			for _, h := range ok.GetHeaders() {
+				if h.GetAppendAction() != corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD {
+					t.Errorf("%s append action = %v", h.GetHeader().GetKey(), h.GetAppendAction())
+				}
+				headers[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+			}
+			for name, want := range tt.wantHeaders {
+				if headers[name] != want {
+					t.Errorf("%s = %q, want %q", name, headers[name], want)
+				}
+			}
+			if !reflect.DeepEqual(ok.GetHeadersToRemove(), tt.wantRemove) {
+				t.Errorf("headers to remove = %v, want %v", ok.GetHeadersToRemove(), tt.wantRemove)
+			}
+		})
+	}
+
+	t.Run("#7: not an http check", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &authv3.CheckRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("error = %v, want InvalidArgument", err)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	"github.com/neko-neko/echo-logrus/v2/log"
+)
+
+// forwardAuthRealm realm of the WWW-Authenticate challenges
+const forwardAuthRealm = "kontrol"
+
+var errForwardHeaders = errors.New("X-Forwarded-Method and X-Forwarded-Uri are mandatory")
+
+//ForwardAuthRequest request to authorize, described by the X-Forwarded-* headers Traefik sets on forwardAuth
+//calls. The client IP is the last X-Forwarded-For hop, the peer Traefik saw, as earlier hops are sent by the client
+func ForwardAuthRequest(r *http.Request) (*gokontrol.AccessRequest, error) {
+	method := r.Header.Get("X-Forwarded-Method")
+	uri := r.Header.Get("X-Forwarded-Uri")
+	if method == "" || uri == "" {
+		return nil, errForwardHeaders
+	}
+	req := &gokontrol.AccessRequest{
+		Method: strings.ToUpper(method),
+		Proto:  r.Header.Get("X-Forwarded-Proto"),
+		Host:   r.Header.Get("X-Forwarded-Host"),
+		Path:   uri,
+		Header: r.Header,
+	}
+	if req.Host == "" {
+		req.Host = r.Host
+	}
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		req.Path, req.Query = uri[:i], uri[i+1:]
+	}
+	req.ClientIP = forwardedClientIP(r)
+	return req, nil
+}
+
+// forwardedClientIP last X-Forwarded-For hop, the peer of the proxy asking, then X-Real-Ip and the peer
+func forwardedClientIP(r *http.Request) string {
+	if hops := strings.Split(r.Header.Get("X-Forwarded-For"), ","); strings.TrimSpace(hops[len(hops)-1]) != "" {
+		return strings.TrimSpace(hops[len(hops)-1])
+	}
+	if ip := r.Header.Get("X-Real-Ip"); ip != "" {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return ""
+}
+
+//ForwardAuthHandler forwardAuth endpoint of Traefik, Caddy and nginx auth_request, in the dialect of the :dialect
+//path parameter or the detected one. Allowed requests get the identity headers, a missing or invalid token gets 401
+//with a Bearer challenge, a valid token without the permission 403
+func ForwardAuthHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type ForwardAuthResponse struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+
+		req, err := GatewayAccessRequest(c.Request(), c.Param("dialect"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ForwardAuthResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		}
+		if corsPreflight(c.Request().Context(), s, req) {
+			return c.JSON(http.StatusOK, ForwardAuthResponse{Code: http.StatusOK, Message: "ok"})
+		}
+
+		identity, denial := identifyRequest(c.Request().Context(), s, req)
+		if denial != nil {
+			for name, values := range denial.Header() {
+				c.Response().Header()[name] = values
+			}
+			return c.JSON(denial.Code, ForwardAuthResponse{Code: denial.Code, Message: denial.Message})
+		}
+
+		// traefik copies them to the upstream request, see authResponseHeaders
+		for name, values := range identity.Header() {
+			c.Response().Header()[name] = values
+		}
+		return c.JSON(http.StatusOK, ForwardAuthResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
+
+// authDenial answer to a request identifyRequest refuses
+type authDenial struct {
+	Code       int
+	Challenge  string // WWW-Authenticate, empty for none
+	Location   string // login page browsers are redirected to, empty for none
+	RetryAfter int64  // second, set on 429
+	Message    string
+}
+
+// Header response headers of the denial
+func (d *authDenial) Header() http.Header {
+	h := http.Header{}
+	if d.Challenge != "" {
+		h.Set("WWW-Authenticate", d.Challenge)
+	}
+	if d.Location != "" {
+		h.Set("Location", d.Location)
+	}
+	if d.RetryAfter > 0 {
+		h.Set("Retry-After", strconv.FormatInt(d.RetryAfter, 10))
+	}
+	return h
+}
+
+// identifyRequest validate the token of req from the sources of its service, shared by forwardAuth and the gateway.
+// A missing or invalid token is denied with 401 and a Bearer challenge, or redirected to the login page for browser
+// navigations, an invalid or replayed signature with 401 and a Signature challenge, a valid token without the permission or a client outside the network conditions with 403, naming the
+// failed condition, and a request over a rate limit with 429
+func identifyRequest(ctx context.Context, s *wrapper.Service, req *gokontrol.AccessRequest) (*gokontrol.Identity, *authDenial) {
+	identity, err := s.Kontrol.IdentifyRequest(ctx, req)
+	var limited *gokontrol.RateLimitError
+	if errors.As(err, &limited) {
+		log.Logger().Warn(fmt.Sprintf("%v: client %s host %s uri %s", err, req.ClientIP, req.Host, req.Path))
+		return nil, &authDenial{Code: http.StatusTooManyRequests, RetryAfter: limited.RetryAfter, Message: err.Error()}
+	}
+	var network *gokontrol.NetworkError
+	if errors.As(err, &network) {
+		log.Logger().Warn(fmt.Sprintf("%v: host %s uri %s", err, req.Host, req.Path))
+		return nil, &authDenial{Code: http.StatusForbidden, Message: err.Error()}
+	}
+	switch err {
+	case nil:
+		return identity, nil
+	case gokontrol.CommonError.MISSING_TOKEN:
+		return nil, loginRedirect(ctx, s, req, &authDenial{Code: http.StatusUnauthorized, Challenge: fmt.Sprintf(`Bearer realm="%s"`, forwardAuthRealm), Message: err.Error()})
+	case gokontrol.CommonError.INVALID_TOKEN:
+		return nil, loginRedirect(ctx, s, req, &authDenial{Code: http.StatusUnauthorized, Challenge: fmt.Sprintf(`Bearer realm="%s", error="invalid_token"`, forwardAuthRealm), Message: err.Error()})
+	case gokontrol.CommonError.INVALID_SIGNATURE, gokontrol.CommonError.SIGNATURE_REPLAYED:
+		// machine clients, never redirected
+		log.Logger().Warn(fmt.Sprintf("%v: client %s host %s uri %s", err, req.ClientIP, req.Host, req.Path))
+		return nil, &authDenial{Code: http.StatusUnauthorized, Challenge: fmt.Sprintf(`Signature realm="%s", error="invalid_signature"`, forwardAuthRealm), Message: err.Error()}
+	case gokontrol.CommonError.SERVICE_UNRESOLVED, gokontrol.CommonError.SERVICE_NOT_FOUND:
+		log.Logger().Warn(fmt.Sprintf("%v: host %s uri %s", err, req.Host, req.Path))
+		return nil, &authDenial{Code: http.StatusForbidden, Message: err.Error()}
+	case gokontrol.CommonError.INVALID_SERVICE:
+		return nil, &authDenial{Code: http.StatusForbidden, Challenge: fmt.Sprintf(`Bearer realm="%s", error="insufficient_scope"`, forwardAuthRealm), Message: "forbidden"}
+	default:
+		log.Logger().Error(err)
+		return nil, &authDenial{Code: http.StatusInternalServerError, Message: "internal error"}
+	}
+}
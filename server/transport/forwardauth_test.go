@@ -0,0 +1,410 @@
+package transport
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+// authResponseHeadersRegex as configured in traefik.yml
+var authResponseHeadersRegex = regexp.MustCompile(`^X-Auth-`)
+
+// forwardAuthClient as Traefik, redirects of the auth server are returned to the client rather than followed
+var forwardAuthClient = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// traefikForwardAuth call the auth server the way Traefik forwardAuth does with trustForwardHeader, and on success
+// return the upstream request headers
+func traefikForwardAuth(t *testing.T, authURL string, r *http.Request) (*http.Response, http.Header) {
+	authReq, err := http.NewRequest(http.MethodGet, authURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range r.Header {
+		authReq.Header[name] = values
+	}
+	authReq.Header.Set("X-Forwarded-Method", r.Method)
+	authReq.Header.Set("X-Forwarded-Proto", "https")
+	authReq.Header.Set("X-Forwarded-Host", r.Host)
+	authReq.Header.Set("X-Forwarded-Uri", r.URL.RequestURI())
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		authReq.Header.Set("X-Forwarded-For", xff+", 203.0.113.7")
+	} else {
+		authReq.Header.Set("X-Forwarded-For", "203.0.113.7")
+	}
+	resp, err := forwardAuthClient.Do(authReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, nil
+	}
+	upstream := r.Header.Clone()
+	for name := range upstream {
+		if authResponseHeadersRegex.MatchString(name) {
+			upstream.Del(name)
+		}
+	}
+	for name, values := range resp.Header {
+		if authResponseHeadersRegex.MatchString(name) {
+			upstream[name] = values
+		}
+	}
+	return resp, upstream
+}
+
+func TestForwardAuthHandler_Traefik(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	kontrol := gokontrol.NewBasicKontrol(store)
+	obj := &gokontrol.Object{ID: "oid", GlobalID: "gid", ExternalID: "alice", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := kontrol.CreateCert(obj, []*gokontrol.Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSources := []*gokontrol.TokenSource{{Type: gokontrol.TokenSourceType.BEARER}, {Type: gokontrol.TokenSourceType.COOKIE, Name: "session"},
+		{Type: gokontrol.TokenSourceType.QUERY, Name: "access_token", Paths: []string{"/list"}}}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders", TokenSources: tokenSources,
+		CORS: &gokontrol.CORS{AllowOrigins: []string{"https://app.example.com"}}}, nil).AnyTimes()
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "unknown").Return(nil, gokontrol.CommonError.NOT_FOUND).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).AnyTimes()
+
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		header        http.Header
+		wantStatus    int
+		wantChallenge string
+	}{
+		{name: "#1: allowed, query stripped from the permission target", method: http.MethodGet, target: "/orders/list?page=2",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusOK},
+		{name: "#2: method of the original request", method: http.MethodPost, target: "/orders/list",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusForbidden, wantChallenge: `Bearer realm="kontrol", error="insufficient_scope"`},
+		{name: "#3: no token", method: http.MethodGet, target: "/orders/list",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+		{name: "#4: invalid token", method: http.MethodGet, target: "/orders/list",
+			header: http.Header{"Authorization": {"Bearer not-a-jwt"}}, wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol", error="invalid_token"`},
+		{name: "#5: unknown service", method: http.MethodGet, target: "/unknown/list",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusForbidden},
+		{name: "#6: options without preflight headers", method: http.MethodOptions, target: "/orders/list",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+		{name: "#7: token in cookie", method: http.MethodGet, target: "/orders/list",
+			header: http.Header{"Cookie": {"session=" + jwtToken}}, wantStatus: http.StatusOK},
+		{name: "#8: token in query of a listed path", method: http.MethodGet, target: "/orders/list?access_token=" + jwtToken, wantStatus: http.StatusOK},
+		{name: "#9: token in query of another path", method: http.MethodGet, target: "/orders/export?access_token=" + jwtToken,
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+		{name: "#10: malformed authorization", method: http.MethodGet, target: "/orders/list",
+			header: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}, "Cookie": {"session=" + jwtToken}}, wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol", error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://gateway.local"+tt.target, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			resp, _ := traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+
+	t.Run("#11: identity replaces client supplied headers", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://gateway.local/orders/list", nil)
+		r.Header.Set("Authorization", "Bearer "+jwtToken)
+		r.Header.Set("X-Auth-Object-Id", "root")
+		r.Header.Set("X-Auth-Attribute-Role", "admin")
+		resp, upstream := traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if got := upstream.Get("X-Auth-Object-Id"); got != "oid" {
+			t.Errorf("X-Auth-Object-Id = %q, want oid", got)
+		}
+		if got := upstream.Get("X-Auth-External-Id"); got != "alice" {
+			t.Errorf("X-Auth-External-Id = %q, want alice", got)
+		}
+		if got := upstream.Get("X-Auth-Attribute-Role"); got != "" {
+			t.Errorf("X-Auth-Attribute-Role = %q, want stripped", got)
+		}
+	})
+
+	t.Run("#12: not called through traefik", func(t *testing.T) {
+		resp, err := http.Get(auth.URL + "/internal_api/validate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+		}
+	})
+
+	for _, tt := range []struct {
+		name       string
+		origin     string
+		wantStatus int
+	}{
+		{name: "#13: preflight of an allowed origin", origin: "https://app.example.com", wantStatus: http.StatusOK},
+		{name: "#14: preflight of another origin", origin: "https://evil.example.com", wantStatus: http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "http://gateway.local/orders/list", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			resp, _ := traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestForwardAuthRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		remote string
+		want   gokontrol.AccessRequest
+	}{
+		{name: "#1: every forwarded header",
+			header: http.Header{"X-Forwarded-Method": {"delete"}, "X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"api.example.com"},
+				"X-Forwarded-Uri": {"/orders/1?force=true"}, "X-Forwarded-For": {"198.51.100.1, 203.0.113.7"}},
+			want: gokontrol.AccessRequest{Method: "DELETE", Proto: "https", Host: "api.example.com", Path: "/orders/1", Query: "force=true", ClientIP: "203.0.113.7"},
+		},
+		{name: "#2: host and client ip of the auth request",
+			header: http.Header{"X-Forwarded-Method": {"GET"}, "X-Forwarded-Uri": {"/orders"}},
+			remote: "192.0.2.10:4321",
+			want:   gokontrol.AccessRequest{Method: "GET", Host: "sso.local", Path: "/orders", ClientIP: "192.0.2.10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://sso.local/internal_api/validate", nil)
+			r.Header = tt.header
+			if tt.remote != "" {
+				r.RemoteAddr = tt.remote
+			}
+			got, err := ForwardAuthRequest(r)
+			if err != nil {
+				t.Fatalf("ForwardAuthRequest() error = %v", err)
+			}
+			got.Header = nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ForwardAuthRequest() got = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+	if _, err := ForwardAuthRequest(httptest.NewRequest(http.MethodGet, "/internal_api/validate", nil)); err == nil || !strings.Contains(err.Error(), "X-Forwarded-Uri") {
+		t.Errorf("ForwardAuthRequest() error = %v, want missing headers", err)
+	}
+}
+
+func TestForwardAuthHandler_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	option := gokontrol.DefaultKontrolOption
+	option.RateLimit.ClientIP = gokontrol.RateLimit{Rate: 0.2, Burst: 1}
+	kontrol := gokontrol.NewKontrol(store, option)
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders"}, nil).AnyTimes()
+
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	for i, want := range []struct {
+		status     int
+		retryAfter string
+	}{{http.StatusUnauthorized, ""}, {http.StatusTooManyRequests, "5"}} {
+		resp, _ := traefikForwardAuth(t, auth.URL+"/internal_api/validate", httptest.NewRequest(http.MethodGet, "http://gateway.local/orders/list", nil))
+		if resp.StatusCode != want.status {
+			t.Errorf("#%d: status = %d, want %d", i+1, resp.StatusCode, want.status)
+		}
+		if got := resp.Header.Get("Retry-After"); got != want.retryAfter {
+			t.Errorf("#%d: Retry-After = %q, want %q", i+1, got, want.retryAfter)
+		}
+	}
+}
+
+func TestForwardAuthHandler_Network(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	kontrol := gokontrol.NewKontrol(store, gokontrol.DefaultKontrolOption)
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders", Network: gokontrol.Network{Allow: []string{"10.8.0.0/16"}}}, nil).AnyTimes()
+
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+
+	for i, tt := range []struct {
+		xff    string
+		status int
+		body   string
+	}{
+		{xff: "203.0.113.7", status: http.StatusForbidden, body: "client ip 203.0.113.7 is not in the allow list of service orders"},
+		{xff: "10.8.1.1", status: http.StatusUnauthorized, body: gokontrol.CommonError.MISSING_TOKEN.Error()},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/internal_api/validate", nil)
+		req.Header.Set("X-Forwarded-Method", http.MethodGet)
+		req.Header.Set("X-Forwarded-Host", "gateway.local")
+		req.Header.Set("X-Forwarded-Uri", "/orders/list")
+		req.Header.Set("X-Forwarded-For", tt.xff)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("#%d: status = %d body = %s, want %d %q", i+1, rec.Code, rec.Body.String(), tt.status, tt.body)
+		}
+	}
+}
+
+func TestForwardAuthHandler_Anonymous(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	kontrol := gokontrol.NewBasicKontrol(store)
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders",
+		Anonymous: gokontrol.AnonymousAccess{Permission: []string{"GET@/healthz$"}}}, nil).AnyTimes()
+
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	r := httptest.NewRequest(http.MethodGet, "http://gateway.local/orders/healthz", nil)
+	r.Header.Set("X-Auth-Object-Id", "root")
+	resp, upstream := traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("#1: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := upstream.Get("X-Auth-Anonymous"); got != "true" {
+		t.Errorf("#1: X-Auth-Anonymous = %q, want true", got)
+	}
+	if got := upstream.Get("X-Auth-Object-Id"); got != "" {
+		t.Errorf("#1: X-Auth-Object-Id = %q, want empty", got)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "http://gateway.local/orders/healthz", nil)
+	r.Header.Set("Authorization", "Bearer not-a-jwt")
+	if resp, _ = traefikForwardAuth(t, auth.URL+"/internal_api/validate", r); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("#2: invalid token status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+// signHTTPRequest sign r as a machine client holding the secret of keyID, see the Signature scheme of go-kontrol
+func signHTTPRequest(r *http.Request, keyID string, secret string, nonce string, body []byte) {
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if body != nil {
+		sum := sha256.Sum256(body)
+		r.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{
+		"(request-target): " + strings.ToLower(r.Method) + " " + r.URL.RequestURI(),
+		"date: " + r.Header.Get("Date"),
+		"content-digest: " + r.Header.Get("Content-Digest"),
+		"nonce: " + nonce,
+	}, "\n")))
+	r.Header.Set("Authorization", `Signature keyId="`+keyID+`",nonce="`+nonce+`",signature="`+base64.StdEncoding.EncodeToString(mac.Sum(nil))+`"`)
+}
+
+// signingKeyKontrol kontrol with a signing key of object cron, of service jobs, whose nonces are accepted once
+func signingKeyKontrol(t *testing.T, ctrl *gomock.Controller) (gokontrol.Kontrol, *gokontrol.SigningKey) {
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	kontrol := gokontrol.NewBasicKontrol(store)
+	cron := &gokontrol.Object{ID: "cron", ServiceID: "sid-a", OrganizationID: "org", Status: gokontrol.ObjectStatus.ENABLE}
+	var key *gokontrol.SigningKey
+	store.EXPECT().GetObjectByID(gomock.Any(), "cron").Return(cron, nil).AnyTimes()
+	store.EXPECT().GetServiceByID(gomock.Any(), "sid-a").Return(&gokontrol.Service{ID: "sid-a", ServiceID: "jobs", OrganizationID: "org"}, nil).AnyTimes()
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "jobs").Return(&gokontrol.Service{ID: "sid-a", ServiceID: "jobs"}, nil).AnyTimes()
+	store.EXPECT().CreateSigningKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k *gokontrol.SigningKey) error {
+		key = &gokontrol.SigningKey{ID: k.ID, ObjectID: k.ObjectID, Sealed: k.Sealed}
+		return nil
+	})
+	store.EXPECT().GetSigningKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id string) (*gokontrol.SigningKey, error) {
+		if key == nil || key.ID != id {
+			return nil, gokontrol.CommonError.NOT_FOUND
+		}
+		return key, nil
+	}).AnyTimes()
+	used := map[string]bool{}
+	store.EXPECT().UseSignatureNonce(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, keyID string, nonce string, _ int64) error {
+		if used[keyID+nonce] {
+			return gokontrol.CommonError.SIGNATURE_REPLAYED
+		}
+		used[keyID+nonce] = true
+		return nil
+	}).AnyTimes()
+	store.EXPECT().GetObjectServiceMesh(gomock.Any(), "cron").Return(nil, nil).AnyTimes()
+
+	// created by the organization admin
+	ctx := gokontrol.WithOrganization(context.Background(), "org")
+	signingKey, err := kontrol.CreateSigningKey(ctx, "", "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kontrol, signingKey
+}
+
+func TestForwardAuthHandler_Signature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	kontrol, key := signingKeyKontrol(t, ctrl)
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "http://gateway.local/jobs/run?batch=7", nil)
+	signHTTPRequest(r, key.ID, key.Secret, "n1", []byte(`{"job":"nightly"}`))
+	resp, upstream := traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("#1: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := upstream.Get("X-Auth-Object-Id"); got != "cron" {
+		t.Errorf("#1: X-Auth-Object-Id = %q, want cron", got)
+	}
+
+	// replayed as is
+	resp, _ = traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+	if resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Signature ") {
+		t.Errorf("#2: replay status = %d WWW-Authenticate = %q, want %d Signature", resp.StatusCode, resp.Header.Get("WWW-Authenticate"), http.StatusUnauthorized)
+	}
+
+	// browser like request, still no login redirect
+	r = httptest.NewRequest(http.MethodGet, "http://gateway.local/jobs/run", nil)
+	r.Header.Set("Accept", "text/html")
+	signHTTPRequest(r, key.ID, "not-the-secret", "n3", nil)
+	if resp, _ = traefikForwardAuth(t, auth.URL+"/internal_api/validate", r); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("#3: wrong secret status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,232 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/constant"
+	"github.com/hungvtc/traefik-integrate/server/repository"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// identityHeaderPrefix of the headers only the gateway sets, authResponseHeadersRegex in traefik.yml
+const identityHeaderPrefix = "X-Auth-"
+
+// maxSignedBody bytes of the body of signed requests the gateway reads to check their Content-Digest
+const maxSignedBody = 1 << 20
+
+// gatewayRoute compiled config.GatewayRoute
+type gatewayRoute struct {
+	host        string
+	pathPrefix  string
+	regex       *regexp.Regexp
+	replacement string
+	public      bool
+	proxy       *httputil.ReverseProxy
+}
+
+//NewGateway reverse proxy serving the gateway routes of the config, validating requests in process
+func NewGateway(s *wrapper.Service) (*echo.Echo, error) {
+	handler, err := GatewayHandler(s)
+	if err != nil {
+		return nil, err
+	}
+	e := echo.New()
+	e.Logger = s.Logger
+	e.HideBanner = true
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	// validation only reads, no transaction is held while the upstream answers
+	e.Use(gormSessionHandler(s.DB))
+	e.Any("/*", handler)
+	return e, nil
+}
+
+//GatewayHandler proxy a request to the upstream of the longest matching route. Requests of non public routes are
+//validated as forwardAuth does, the upstream gets the identity headers and never client supplied ones
+func GatewayHandler(s *wrapper.Service) (echo.HandlerFunc, error) {
+	type GatewayResponse struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	routes := make([]*gatewayRoute, 0)
+	if s.Config != nil && s.Config.Gateway != nil {
+		for _, r := range s.Config.Gateway.Routes {
+			route, err := newGatewayRoute(r)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, route)
+		}
+	}
+	// longest prefix first, host routes before any host ones
+	sort.SliceStable(routes, func(i, j int) bool {
+		if len(routes[i].pathPrefix) != len(routes[j].pathPrefix) {
+			return len(routes[i].pathPrefix) > len(routes[j].pathPrefix)
+		}
+		return routes[i].host != "" && routes[j].host == ""
+	})
+
+	return func(c echo.Context) error {
+		r := c.Request()
+		// dot segments and repeated slashes would authorize one path and reach another upstream
+		if !cleanPath(r.URL.Path) {
+			return c.JSON(http.StatusBadRequest, GatewayResponse{Code: http.StatusBadRequest, Message: "invalid path"})
+		}
+		route := matchGatewayRoute(routes, r)
+		if route == nil {
+			return c.JSON(http.StatusNotFound, GatewayResponse{Code: http.StatusNotFound, Message: "no route"})
+		}
+
+		// client supplied copies never reach the upstream, as strip-auth-headers and authResponseHeadersRegex do
+		for name := range r.Header {
+			if strings.HasPrefix(name, identityHeaderPrefix) {
+				r.Header.Del(name)
+			}
+		}
+
+		req := gatewayAccessRequest(r)
+		if !route.public && !corsPreflight(r.Context(), s, req) {
+			if err := readSignedBody(r, req); err != nil {
+				return c.JSON(http.StatusRequestEntityTooLarge, GatewayResponse{Code: http.StatusRequestEntityTooLarge, Message: err.Error()})
+			}
+			identity, denial := identifyRequest(r.Context(), s, req)
+			if denial != nil {
+				for name, values := range denial.Header() {
+					c.Response().Header()[name] = values
+				}
+				return c.JSON(denial.Code, GatewayResponse{Code: denial.Code, Message: denial.Message})
+			}
+			for name, values := range identity.Header() {
+				r.Header[name] = values
+			}
+		}
+
+		if route.regex != nil && route.regex.MatchString(r.URL.Path) {
+			r.Header.Set("X-Replaced-Path", r.URL.Path)
+			r.URL.Path = route.regex.ReplaceAllString(r.URL.Path, route.replacement)
+			r.URL.RawPath = ""
+		}
+		r.Header.Set("X-Forwarded-Host", r.Host)
+		r.Header.Set("X-Forwarded-Proto", c.Scheme())
+		route.proxy.ServeHTTP(c.Response(), r)
+		return nil
+	}, nil
+}
+
+// newGatewayRoute compile a route, the upstream must be an absolute url
+func newGatewayRoute(r *config.GatewayRoute) (*gatewayRoute, error) {
+	target, err := url.Parse(r.Upstream)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("gateway route %s: invalid upstream %q", r.PathPrefix, r.Upstream)
+	}
+	route := &gatewayRoute{
+		host:        r.Host,
+		pathPrefix:  r.PathPrefix,
+		replacement: r.Replacement,
+		public:      r.Public,
+		proxy:       httputil.NewSingleHostReverseProxy(target),
+	}
+	if r.Regex != "" {
+		if route.regex, err = regexp.Compile(r.Regex); err != nil {
+			return nil, fmt.Errorf("gateway route %s: %v", r.PathPrefix, err)
+		}
+	}
+	return route, nil
+}
+
+// matchGatewayRoute first route of host under whose prefix the path is, routes are sorted
+func matchGatewayRoute(routes []*gatewayRoute, r *http.Request) *gatewayRoute {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, route := range routes {
+		if (route.host == "" || strings.EqualFold(route.host, host)) && underPrefix(r.URL.Path, route.pathPrefix) {
+			return route
+		}
+	}
+	return nil
+}
+
+// cleanPath whether p is unchanged by path.Clean, a trailing slash aside
+func cleanPath(p string) bool {
+	cleaned := path.Clean(p)
+	return p == cleaned || p == cleaned+"/"
+}
+
+// underPrefix whether p is prefix or under it on a segment boundary, /api covers /api/list but not /apiadmin
+func underPrefix(p string, prefix string) bool {
+	if !strings.HasPrefix(p, prefix) {
+		return false
+	}
+	return len(p) == len(prefix) || strings.HasSuffix(prefix, "/") || p[len(prefix)] == '/'
+}
+
+// gatewayAccessRequest request to authorize as the client sent it, before any rewrite. The gateway is the edge,
+// the client IP is the peer, X-Forwarded-For is the client's
+func gatewayAccessRequest(r *http.Request) *gokontrol.AccessRequest {
+	req := &gokontrol.AccessRequest{
+		Method: r.Method,
+		Proto:  "http",
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header,
+	}
+	if r.TLS != nil {
+		req.Proto = "https"
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		req.ClientIP = host
+	}
+	return req
+}
+
+// readSignedBody body of a signed request, checked against its Content-Digest, put back for the upstream. Bodies
+// over maxSignedBody are refused rather than forwarded unchecked
+func readSignedBody(r *http.Request, req *gokontrol.AccessRequest) error {
+	if r.Body == nil || r.Body == http.NoBody || !gokontrol.SignedRequest(req) {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBody+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxSignedBody {
+		return fmt.Errorf("signed request body over %d bytes", maxSignedBody)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	req.Body = body
+	return nil
+}
+
+// gormSessionHandler store session of the request, without transaction
+func gormSessionHandler(db repository.Database) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			txi, err := db.Session()
+			if err != nil {
+				return err
+			}
+			c.Set(constant.ContextKeyTransaction, txi)
+			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), constant.ContextKeyTransaction, txi)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package transport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGatewayHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	kontrol := gokontrol.NewBasicKontrol(store)
+	obj := &gokontrol.Object{ID: "oid", GlobalID: "gid", ExternalID: "alice", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := kontrol.CreateCert(obj, []*gokontrol.Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/api/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders"}, nil).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).AnyTimes()
+
+	// upstream answers the path and headers it got
+	type upstreamResponse struct {
+		Path   string      `json:"path"`
+		Header http.Header `json:"header"`
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(upstreamResponse{Path: r.URL.Path, Header: r.Header})
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{Gateway: &config.Gateway{Routes: []*config.GatewayRoute{
+		{PathPrefix: "/orders/api/", Upstream: upstream.URL, Regex: "(.*?)/api/(.*)", Replacement: "/internal_api/$2"},
+		{PathPrefix: "/login", Upstream: upstream.URL, Regex: "^/login$", Replacement: "/internal_api/authorize", Public: true},
+		{PathPrefix: "/", Host: "static.local", Upstream: upstream.URL, Public: true},
+	}}}
+	handler, err := GatewayHandler(&wrapper.Service{Config: cfg, Kontrol: kontrol})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Any("/*", handler)
+	gateway := httptest.NewServer(e)
+	defer gateway.Close()
+
+	tests := []struct {
+		name          string
+		method        string
+		host          string
+		target        string
+		header        http.Header
+		wantStatus    int
+		wantPath      string
+		wantChallenge string
+	}{
+		{name: "#1: allowed, path rewritten", method: http.MethodGet, target: "/orders/api/list?page=2",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusOK, wantPath: "/internal_api/list"},
+		{name: "#2: no permission", method: http.MethodPost, target: "/orders/api/list",
+			header: http.Header{"Authorization": {"Bearer " + jwtToken}}, wantStatus: http.StatusForbidden, wantChallenge: `Bearer realm="kontrol", error="insufficient_scope"`},
+		{name: "#3: no token", method: http.MethodGet, target: "/orders/api/list",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+		{name: "#4: invalid token", method: http.MethodGet, target: "/orders/api/list",
+			header: http.Header{"Authorization": {"Bearer not-a-jwt"}}, wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol", error="invalid_token"`},
+		{name: "#5: public route", method: http.MethodPost, target: "/login", wantStatus: http.StatusOK, wantPath: "/internal_api/authorize"},
+		{name: "#6: no route", method: http.MethodGet, target: "/unknown", wantStatus: http.StatusNotFound},
+		{name: "#7: host route", method: http.MethodGet, host: "static.local", target: "/unknown", wantStatus: http.StatusOK, wantPath: "/unknown"},
+		{name: "#8: options without preflight headers", method: http.MethodOptions, target: "/orders/api/list",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="kontrol"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(tt.method, gateway.URL+tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Host = tt.host
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if tt.wantPath == "" {
+				return
+			}
+			var got upstreamResponse
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Path != tt.wantPath {
+				t.Errorf("upstream path = %q, want %q", got.Path, tt.wantPath)
+			}
+		})
+	}
+
+	t.Run("#9: identity replaces client supplied headers", func(t *testing.T) {
+		r, err := http.NewRequest(http.MethodGet, gateway.URL+"/orders/api/list", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+jwtToken)
+		r.Header.Set("X-Auth-Object-Id", "root")
+		r.Header.Set("X-Auth-Attribute-Role", "admin")
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var got upstreamResponse
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if v := got.Header.Get("X-Auth-Object-Id"); v != "oid" {
+			t.Errorf("X-Auth-Object-Id = %q, want oid", v)
+		}
+		if v := got.Header.Get("X-Auth-Attribute-Role"); v != "" {
+			t.Errorf("X-Auth-Attribute-Role = %q, want stripped", v)
+		}
+		if v := got.Header.Get("X-Replaced-Path"); v != "/orders/api/list" {
+			t.Errorf("X-Replaced-Path = %q, want /orders/api/list", v)
+		}
+	})
+
+	t.Run("#10: invalid upstream", func(t *testing.T) {
+		bad := &config.Config{Gateway: &config.Gateway{Routes: []*config.GatewayRoute{{PathPrefix: "/", Upstream: "upstream:80"}}}}
+		if _, err := GatewayHandler(&wrapper.Service{Config: bad, Kontrol: kontrol}); err == nil {
+			t.Error("want error")
+		}
+	})
+
+	for _, tt := range []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{name: "#11: dot segments", target: "/orders/api/../../admin", wantStatus: http.StatusBadRequest},
+		{name: "#12: repeated slashes", target: "/orders//api/list", wantStatus: http.StatusBadRequest},
+		{name: "#13: prefix inside a segment", target: "/loginadmin", wantStatus: http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, gateway.URL+tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Authorization", "Bearer "+jwtToken)
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGatewayHandler_Signature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	kontrol, key := signingKeyKontrol(t, ctrl)
+	// upstream answers the body it got
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer upstream.Close()
+	cfg := &config.Config{Gateway: &config.Gateway{Routes: []*config.GatewayRoute{{PathPrefix: "/jobs/", Upstream: upstream.URL}}}}
+	handler, err := GatewayHandler(&wrapper.Service{Config: cfg, Kontrol: kontrol})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Any("/*", handler)
+	gateway := httptest.NewServer(e)
+	defer gateway.Close()
+
+	tests := []struct {
+		name       string
+		nonce      string
+		signed     string
+		sent       string
+		wantStatus int
+	}{
+		{name: "#1: signed body", nonce: "n1", signed: `{"job":"nightly"}`, sent: `{"job":"nightly"}`, wantStatus: http.StatusOK},
+		{name: "#2: body changed", nonce: "n2", signed: `{"job":"nightly"}`, sent: `{"job":"purge"}`, wantStatus: http.StatusUnauthorized},
+		{name: "#3: body over the limit", nonce: "n3", signed: "", sent: strings.Repeat("a", maxSignedBody+1), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, gateway.URL+"/jobs/run", strings.NewReader(tt.sent))
+			if err != nil {
+				t.Fatal(err)
+			}
+			signHTTPRequest(r, key.ID, key.Secret, tt.nonce, []byte(tt.signed))
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got, _ := io.ReadAll(resp.Body); tt.wantStatus == http.StatusOK && string(got) != tt.sent {
+				t.Errorf("upstream body = %q, want %q", got, tt.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package transport
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	"github.com/neko-neko/echo-logrus/v2/log"
+)
+
+// traefikConfiguration dynamic configuration served to the Traefik HTTP provider
+type traefikConfiguration struct {
+	HTTP *traefikHTTP `json:"http"`
+}
+
+type traefikHTTP struct {
+	Routers     map[string]*traefikRouter     `json:"routers"`
+	Middlewares map[string]*traefikMiddleware `json:"middlewares"`
+	Services    map[string]*traefikService    `json:"services"`
+}
+
+type traefikRouter struct {
+	Rule        string   `json:"rule"`
+	Service     string   `json:"service"`
+	Middlewares []string `json:"middlewares,omitempty"`
+	EntryPoints []string `json:"entryPoints,omitempty"`
+}
+
+type traefikMiddleware struct {
+	ForwardAuth      *traefikForwardAuthConfig `json:"forwardAuth,omitempty"`
+	Headers          *traefikHeaders           `json:"headers,omitempty"`
+	ReplacePathRegex *traefikReplacePathRegex  `json:"replacePathRegex,omitempty"`
+}
+
+type traefikForwardAuthConfig struct {
+	Address                  string   `json:"address"`
+	TrustForwardHeader       bool     `json:"trustForwardHeader"`
+	AuthResponseHeaders      []string `json:"authResponseHeaders"`
+	AuthResponseHeadersRegex string   `json:"authResponseHeadersRegex"`
+}
+
+type traefikHeaders struct {
+	CustomRequestHeaders          map[string]string `json:"customRequestHeaders,omitempty"`
+	AccessControlAllowOriginList  []string          `json:"accessControlAllowOriginList,omitempty"`
+	AccessControlAllowMethods     []string          `json:"accessControlAllowMethods,omitempty"`
+	AccessControlAllowHeaders     []string          `json:"accessControlAllowHeaders,omitempty"`
+	AccessControlExposeHeaders    []string          `json:"accessControlExposeHeaders,omitempty"`
+	AccessControlAllowCredentials bool              `json:"accessControlAllowCredentials,omitempty"`
+	AccessControlMaxAge           int               `json:"accessControlMaxAge,omitempty"`
+	AddVaryHeader                 bool              `json:"addVaryHeader,omitempty"`
+}
+
+type traefikReplacePathRegex struct {
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement"`
+}
+
+type traefikService struct {
+	LoadBalancer *traefikLoadBalancer `json:"loadBalancer"`
+}
+
+type traefikLoadBalancer struct {
+	Servers []*traefikServer `json:"servers"`
+}
+
+type traefikServer struct {
+	URL string `json:"url"`
+}
+
+//TraefikProviderHandler Traefik HTTP provider endpoint, routers, middlewares and services of every routed service as
+//traefik.yml declares them by hand
+func TraefikProviderHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type TraefikProviderResponse struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+
+		provider := &config.Provider{}
+		if s.Config != nil && s.Config.Provider != nil {
+			provider = s.Config.Provider
+		}
+		if provider.Token != "" {
+			reqToken, _ := gokontrol.BearerToken(c.Request().Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare([]byte(reqToken), []byte(provider.Token)) != 1 {
+				return c.JSON(http.StatusUnauthorized, TraefikProviderResponse{Code: http.StatusUnauthorized, Message: "unauthorized"})
+			}
+		}
+
+		services, err := s.Kontrol.GatewayServices(c.Request().Context())
+		if err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusInternalServerError, TraefikProviderResponse{Code: http.StatusInternalServerError, Message: "internal error"})
+		}
+		return c.JSON(http.StatusOK, renderTraefikConfiguration(provider, services))
+	}
+}
+
+// renderTraefikConfiguration render the dynamic configuration of the services, each gets a router behind the auth
+// middleware, its path rewrite and a load balancer on its upstream. Services with CORS get a headers middleware in
+// front of auth, Traefik answers their preflights
+func renderTraefikConfiguration(provider *config.Provider, services []*gokontrol.Service) *traefikConfiguration {
+	h := &traefikHTTP{
+		Routers: make(map[string]*traefikRouter, len(services)),
+		Middlewares: map[string]*traefikMiddleware{
+			"auth": {ForwardAuth: &traefikForwardAuthConfig{
+				Address:            provider.ForwardAuthAddress,
+				TrustForwardHeader: true,
+				AuthResponseHeaders: []string{
+					gokontrol.IdentityHeader.OBJECT_ID, gokontrol.IdentityHeader.GLOBAL_ID,
+					gokontrol.IdentityHeader.EXTERNAL_ID, gokontrol.IdentityHeader.SERVICE,
+					gokontrol.IdentityHeader.ANONYMOUS,
+				},
+				AuthResponseHeadersRegex: "^" + identityHeaderPrefix,
+			}},
+			// client supplied copies never reach the services
+			"strip-auth-headers": {Headers: &traefikHeaders{CustomRequestHeaders: map[string]string{
+				gokontrol.IdentityHeader.OBJECT_ID: "", gokontrol.IdentityHeader.GLOBAL_ID: "",
+				gokontrol.IdentityHeader.EXTERNAL_ID: "", gokontrol.IdentityHeader.SERVICE: "",
+				gokontrol.IdentityHeader.ANONYMOUS: "",
+			}}},
+		},
+		Services: make(map[string]*traefikService, len(services)),
+	}
+	for _, service := range services {
+		r := service.Routing
+		rule := fmt.Sprintf("PathPrefix(`%s`)", r.Prefix(service.ServiceID))
+		if r.Host != "" {
+			rule = fmt.Sprintf("Host(`%s`) && %s", r.Host, rule)
+		}
+		router := &traefikRouter{
+			Rule:        rule,
+			Service:     fmt.Sprintf("route-to-api-service-%s", service.ServiceID),
+			Middlewares: []string{"strip-auth-headers", "auth"},
+			EntryPoints: provider.EntryPoints,
+		}
+		if !service.CORS.Empty() {
+			name := fmt.Sprintf("cors-%s", service.ServiceID)
+			h.Middlewares[name] = &traefikMiddleware{Headers: &traefikHeaders{
+				AccessControlAllowOriginList:  service.CORS.AllowOrigins,
+				AccessControlAllowMethods:     service.CORS.Methods(),
+				AccessControlAllowHeaders:     service.CORS.AllowHeaders,
+				AccessControlExposeHeaders:    service.CORS.ExposeHeaders,
+				AccessControlAllowCredentials: service.CORS.AllowCredentials,
+				AccessControlMaxAge:           service.CORS.MaxAge,
+				AddVaryHeader:                 true,
+			}}
+			router.Middlewares = append([]string{name}, router.Middlewares...)
+		}
+		if r.Regex != "" {
+			name := fmt.Sprintf("replacepath-regex-%s", service.ServiceID)
+			h.Middlewares[name] = &traefikMiddleware{ReplacePathRegex: &traefikReplacePathRegex{Regex: r.Regex, Replacement: r.Replacement}}
+			router.Middlewares = append(router.Middlewares, name)
+		}
+		h.Routers[fmt.Sprintf("route-to-%s", service.ServiceID)] = router
+		h.Services[router.Service] = &traefikService{LoadBalancer: &traefikLoadBalancer{Servers: []*traefikServer{{URL: r.Upstream}}}}
+	}
+	return &traefikConfiguration{HTTP: h}
+}
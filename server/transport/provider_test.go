@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+func TestTraefikProviderHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	store.EXPECT().GetServices(gomock.Any()).Return([]*gokontrol.Service{
+		{ID: "1", ServiceID: "idt", Status: gokontrol.ServiceStatus.ENABLE, Routing: gokontrol.ServiceRouting{
+			PathPrefix: "/idt/api/", Upstream: "http://dummy_service:4448", Regex: "(.*?)/api/(.*)", Replacement: "/internal_api/$2"}},
+		{ID: "2", ServiceID: "orders", Status: gokontrol.ServiceStatus.ENABLE, Routing: gokontrol.ServiceRouting{
+			Host: "api.example.com", Upstream: "http://orders:8080"},
+			CORS: &gokontrol.CORS{AllowOrigins: []string{"https://app.example.com"}, AllowHeaders: []string{"Authorization"}, AllowCredentials: true}},
+	}, nil).AnyTimes()
+	cfg := &config.Config{Provider: &config.Provider{Token: "s3cr3t", ForwardAuthAddress: "http://sso_service:4445/internal_api/validate", EntryPoints: []string{"web"}}}
+
+	e := echo.New()
+	e.GET("/provider/traefik", TraefikProviderHandler(&wrapper.Service{Config: cfg, Kontrol: gokontrol.NewBasicKontrol(store)}))
+
+	t.Run("#1: no token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/provider/traefik", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("#2: routers, middlewares and services of the routed services", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/provider/traefik", nil)
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		var got traefikConfiguration
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		wantRouters := map[string]*traefikRouter{
+			"route-to-idt": {Rule: "PathPrefix(`/idt/api/`)", Service: "route-to-api-service-idt",
+				Middlewares: []string{"strip-auth-headers", "auth", "replacepath-regex-idt"}, EntryPoints: []string{"web"}},
+			"route-to-orders": {Rule: "Host(`api.example.com`) && PathPrefix(`/orders/`)", Service: "route-to-api-service-orders",
+				Middlewares: []string{"cors-orders", "strip-auth-headers", "auth"}, EntryPoints: []string{"web"}},
+		}
+		if !reflect.DeepEqual(got.HTTP.Routers, wantRouters) {
+			t.Errorf("routers = %+v, want %+v", got.HTTP.Routers, wantRouters)
+		}
+		if auth := got.HTTP.Middlewares["auth"]; auth == nil || auth.ForwardAuth == nil || auth.ForwardAuth.Address != cfg.Provider.ForwardAuthAddress {
+			t.Errorf("auth middleware = %+v", auth)
+		}
+		if rw := got.HTTP.Middlewares["replacepath-regex-idt"]; rw == nil || rw.ReplacePathRegex == nil || rw.ReplacePathRegex.Replacement != "/internal_api/$2" {
+			t.Errorf("replacepath-regex-idt middleware = %+v", rw)
+		}
+		wantCORS := &traefikHeaders{AccessControlAllowOriginList: []string{"https://app.example.com"}, AccessControlAllowMethods: []string{"GET", "HEAD", "POST"},
+			AccessControlAllowHeaders: []string{"Authorization"}, AccessControlAllowCredentials: true, AddVaryHeader: true}
+		if cors := got.HTTP.Middlewares["cors-orders"]; cors == nil || !reflect.DeepEqual(cors.Headers, wantCORS) {
+			t.Errorf("cors-orders middleware = %+v, want headers %+v", cors, wantCORS)
+		}
+		if s := got.HTTP.Services["route-to-api-service-orders"]; s == nil || s.LoadBalancer.Servers[0].URL != "http://orders:8080" {
+			t.Errorf("route-to-api-service-orders = %+v", s)
+		}
+	})
+}
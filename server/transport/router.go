@@ -22,10 +22,23 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/neko-neko/echo-logrus/v2/log"
+	prom "github.com/prometheus/client_golang/prometheus"
 	"gopkg.in/go-playground/validator.v9"
 	"gorm.io/gorm"
 )
 
+var breakGlassCounter = prom.NewCounterVec(prom.CounterOpts{
+	Name: "kontrol_break_glass_total",
+	Help: "Break glass logins by result, with the account and service of the granted ones.",
+}, []string{"account", "service", "result"})
+
+// unknownLabel account and service of the break glass attempts that were not granted
+const unknownLabel = "unknown"
+
+func init() {
+	prom.MustRegister(breakGlassCounter)
+}
+
 func urlSkipper(c echo.Context) bool {
 	if strings.HasPrefix(c.Path(), "/health") {
 		return true
@@ -60,11 +73,8 @@ func NewEcho(s *wrapper.Service) *echo.Echo {
 	// Fetch new store.
 	e.Use(GormTransactionHandler(s.DB))
 
-	//CORS
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE, echo.OPTIONS},
-	}))
+	//CORS, origins allowed by the registered services
+	e.Use(CORSHandler(s))
 
 	// Routes
 	e.GET("/health", func(c echo.Context) error {
@@ -74,16 +84,36 @@ func NewEcho(s *wrapper.Service) *echo.Echo {
 		return c.String(http.StatusOK, strconv.FormatInt(time.Now().Unix(), 10))
 	})
 	//e.POST("/login", AuthenticateHandler(s))
-	api := e.Group("/internal_api")
+	e.POST("/break-glass", BreakGlassHandler(s))
+	e.GET("/provider/traefik", TraefikProviderHandler(s))
+	e.GET("/sso/login", SSOLoginPageHandler(s))
+	e.POST("/sso/login", SSOLoginHandler(s))
+	api := e.Group("/internal_api", OrganizationHandler(s))
 	{
 		// api
-		api.POST("/object", CreateSimpleObjectHandler(s))
-		api.PUT("/object", UpdateObjectHandler(s))
+		api.POST("/object", CreateSimpleObjectHandler(s), AdminHandler(s))
+		api.PUT("/object", UpdateObjectHandler(s), AdminHandler(s))
 		api.GET("/object", GetCertForServiceHandler(s))
-		api.GET("/validate", ValidateObjectHandler(s))
+		api.POST("/object/signing_key", CreateSigningKeyHandler(s), AdminHandler(s))
+		api.DELETE("/object/signing_key", RevokeSigningKeyHandler(s), AdminHandler(s))
+		api.GET("/validate", ForwardAuthHandler(s))
+		api.GET("/validate/:dialect", ForwardAuthHandler(s))
+		api.Any("/ext_authz", ExtAuthzHandler(s))
+		api.Any("/ext_authz/*", ExtAuthzHandler(s))
 		api.POST("/cert", GetCertForClientHandler(s))
-		api.POST("/policy", CreatePolicyHandler(s))
-		api.PUT("/policy", UpdatePolicyHandler(s))
+		api.GET("/policy", GetServicePoliciesHandler(s), AdminHandler(s))
+		api.GET("/policy/lint", LintPoliciesHandler(s), AdminHandler(s))
+		api.POST("/policy", CreatePolicyHandler(s), AdminHandler(s))
+		api.PUT("/policy", UpdatePolicyHandler(s), AdminHandler(s))
+		api.POST("/policy/template", CreatePolicyTemplateHandler(s), AdminHandler(s))
+		api.PUT("/policy/template", UpdatePolicyTemplateHandler(s), AdminHandler(s))
+		api.POST("/policy/instance", InstantiatePolicyTemplateHandler(s), AdminHandler(s))
+		api.POST("/elevation", ElevateObjectHandler(s), AdminHandler(s))
+		api.POST("/service/grant", RequestServiceGrantHandler(s), AdminHandler(s))
+		api.PUT("/service/grant", ApproveServiceGrantHandler(s), AdminHandler(s))
+		api.DELETE("/service/grant", RevokeServiceGrantHandler(s), AdminHandler(s))
+		api.POST("/service/admin", GrantServiceAdminHandler(s))
+		api.DELETE("/service/admin", RevokeServiceAdminHandler(s))
 		api.POST("/authorize", AuthenticateHandler(s))
 	}
 
@@ -159,12 +189,54 @@ func GormTransactionHandler(db repository.Database) echo.MiddlewareFunc {
 
 }
 
+//OrganizationHandler scope the request to the organization authenticated by X-Organization-Id and X-Organization-Key,
+//scoped requests are authorized by the organization credentials in place of the service key
+func OrganizationHandler(s *wrapper.Service) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			orgID := c.Request().Header.Get("X-Organization-Id")
+			if orgID == "" {
+				return next(c)
+			}
+			ctx := c.Request().Context()
+			if _, err := s.Kontrol.AuthenticateOrganization(ctx, orgID, c.Request().Header.Get("X-Organization-Key")); err != nil {
+				return c.JSON(http.StatusUnauthorized, err)
+			}
+			c.SetRequest(c.Request().WithContext(gokontrol.WithOrganization(ctx, orgID)))
+			return next(c)
+		}
+	}
+}
+
+//AdminHandler authenticate the admin bearer token if any, management calls are then authorized by the admin roles
+func AdminHandler(s *wrapper.Service) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			reqToken := c.Request().Header.Get("Authorization")
+			if reqToken == "" {
+				return next(c)
+			}
+			token, ok := gokontrol.BearerToken(reqToken)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, gokontrol.CommonError.INVALID_TOKEN)
+			}
+			ctx := c.Request().Context()
+			admin, err := s.Kontrol.AuthenticateAdmin(ctx, token)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, err)
+			}
+			c.SetRequest(c.Request().WithContext(gokontrol.WithAdmin(ctx, admin)))
+			return next(c)
+		}
+	}
+}
+
 func CreateSimpleObjectHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 
 		type CreateSimpleObjectRequest struct {
 			ObjectID  string `json:"object_id" validate:"required"`
-			Token     string `json:"token" validate:"required"`
+			Token     string `json:"token"`
 			ServiceID string `json:"service_id" validate:"required"`
 		}
 
@@ -192,13 +264,14 @@ func CreateSimpleObjectHandler(s *wrapper.Service) echo.HandlerFunc {
 func UpdateObjectHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type UpdateObjectRequest struct {
-			ObjectID    string   `json:"object_id" validate:"required"`
-			Token       string   `json:"token" validate:"required"`
-			GlobalID    string   `json:"global_id"`
-			ServiceID   string   `json:"service_id" validate:"required"`
-			ExternalID  string   `json:"external_id" validate:"required"`
-			Status      string   `json:"status" validate:"required"`
-			ApplyPolicy []string `json:"apply_policy"`
+			ObjectID    string                 `json:"object_id" validate:"required"`
+			Token       string                 `json:"token"`
+			GlobalID    string                 `json:"global_id"`
+			ServiceID   string                 `json:"service_id" validate:"required"`
+			ExternalID  string                 `json:"external_id" validate:"required"`
+			Status      string                 `json:"status" validate:"required"`
+			ApplyPolicy []string               `json:"apply_policy"`
+			Attributes  map[string]interface{} `json:"attributes"` // kept when omitted
 		}
 
 		type UpdateObjectResponse struct {
@@ -227,6 +300,7 @@ func UpdateObjectHandler(s *wrapper.Service) echo.HandlerFunc {
 			ExternalID:  pr.ExternalID,
 			ServiceID:   pr.ServiceID,
 			Status:      pr.Status,
+			Attributes:  pr.Attributes,
 			ApplyPolicy: ap,
 		}, pr.Token)
 		if err != nil {
@@ -240,19 +314,24 @@ func UpdateObjectHandler(s *wrapper.Service) echo.HandlerFunc {
 func CreatePolicyHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type CreatePolicyRequest struct {
-			Token      string         `json:"token" validate:"required"`
-			Name       string         `json:"name"`
-			ServiceID  string         `json:"service_id"`
-			Permission map[string]int `json:"permission"`
-			Status     string         `json:"status"`
-			ApplyFrom  int64          `json:"apply_from"`
-			ApplyTo    int64          `json:"apply_to"`
+			Token        string                      `json:"token"`
+			Name         string                      `json:"name"`
+			ServiceID    string                      `json:"service_id"`
+			Permission   map[string]int              `json:"permission"`
+			Status       string                      `json:"status"`
+			ApplyFrom    int64                       `json:"apply_from"`
+			ApplyTo      int64                       `json:"apply_to"`
+			MaxElevation int64                       `json:"max_elevation"`
+			Schedule     []*gokontrol.PolicySchedule `json:"schedule"`
+			RateLimit    gokontrol.RateLimit         `json:"rate_limit"`
+			Network      gokontrol.Network           `json:"network"`
 		}
 
 		type CreatePolicyResponse struct {
-			Code    int               `json:"code"`
-			Message string            `json:"message"`
-			Policy  *gokontrol.Policy `json:"policy"`
+			Code     int                    `json:"code"`
+			Message  string                 `json:"message"`
+			Policy   *gokontrol.Policy      `json:"policy"`
+			Warnings []*gokontrol.LintIssue `json:"warnings,omitempty"` // linter findings, errors when the policy is rejected
 		}
 
 		pr := new(CreatePolicyRequest)
@@ -267,40 +346,52 @@ func CreatePolicyHandler(s *wrapper.Service) echo.HandlerFunc {
 			}
 		}
 		policy := &gokontrol.Policy{
-			ID:         uuid.NewString(),
-			Name:       pr.Name,
-			ServiceID:  pr.ServiceID,
-			Permission: pr.Permission,
-			Status:     pr.Status,
-			ApplyFrom:  pr.ApplyFrom,
-			ApplyTo:    pr.ApplyTo,
+			ID:           uuid.NewString(),
+			Name:         pr.Name,
+			ServiceID:    pr.ServiceID,
+			Permission:   pr.Permission,
+			Status:       pr.Status,
+			ApplyFrom:    pr.ApplyFrom,
+			ApplyTo:      pr.ApplyTo,
+			MaxElevation: pr.MaxElevation,
+			RateLimit:    pr.RateLimit,
+			Network:      pr.Network,
+			Schedule:     pr.Schedule,
 		}
 		err := s.Kontrol.CreatePolicy(c.Request().Context(), pr.Token, policy)
+		if err == gokontrol.CommonError.MALFORM_PERMISSION {
+			return c.JSON(http.StatusBadRequest, CreatePolicyResponse{Code: http.StatusBadRequest, Message: err.Error(), Warnings: gokontrol.LintPolicy(policy, nil, nil)})
+		}
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, err)
 		}
 
-		return c.JSON(http.StatusOK, CreatePolicyResponse{Code: http.StatusOK, Message: "ok", Policy: policy})
+		return c.JSON(http.StatusOK, CreatePolicyResponse{Code: http.StatusOK, Message: "ok", Policy: policy, Warnings: policyWarnings(c, s, pr.Token, policy)})
 	}
 }
 
 func UpdatePolicyHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type UpdatePolicyRequest struct {
-			Id         string         `json:"id" validate:"required"`
-			Token      string         `json:"token" validate:"required"`
-			Name       string         `json:"name"`
-			ServiceID  string         `json:"service_id"`
-			Permission map[string]int `json:"permission"`
-			Status     string         `json:"status"`
-			ApplyFrom  int64          `json:"apply_from"`
-			ApplyTo    int64          `json:"apply_to"`
+			Id           string                      `json:"id" validate:"required"`
+			Token        string                      `json:"token"`
+			Name         string                      `json:"name"`
+			ServiceID    string                      `json:"service_id"`
+			Permission   map[string]int              `json:"permission"`
+			Status       string                      `json:"status"`
+			ApplyFrom    int64                       `json:"apply_from"`
+			ApplyTo      int64                       `json:"apply_to"`
+			MaxElevation int64                       `json:"max_elevation"`
+			Schedule     []*gokontrol.PolicySchedule `json:"schedule"`
+			RateLimit    gokontrol.RateLimit         `json:"rate_limit"`
+			Network      gokontrol.Network           `json:"network"`
 		}
 
 		type UpdatePolicyResponse struct {
-			Code    int               `json:"code"`
-			Message string            `json:"message"`
-			Policy  *gokontrol.Policy `json:"policy"`
+			Code     int                    `json:"code"`
+			Message  string                 `json:"message"`
+			Policy   *gokontrol.Policy      `json:"policy"`
+			Warnings []*gokontrol.LintIssue `json:"warnings,omitempty"` // linter findings, errors when the policy is rejected
 		}
 
 		pr := new(UpdatePolicyRequest)
@@ -316,53 +407,443 @@ func UpdatePolicyHandler(s *wrapper.Service) echo.HandlerFunc {
 			}
 		}
 		policy := &gokontrol.Policy{
-			ID:         pr.Id,
-			Name:       pr.Name,
-			ServiceID:  pr.ServiceID,
-			Permission: pr.Permission,
-			Status:     pr.Status,
-			ApplyFrom:  pr.ApplyFrom,
-			ApplyTo:    pr.ApplyTo,
+			ID:           pr.Id,
+			Name:         pr.Name,
+			ServiceID:    pr.ServiceID,
+			Permission:   pr.Permission,
+			Status:       pr.Status,
+			ApplyFrom:    pr.ApplyFrom,
+			ApplyTo:      pr.ApplyTo,
+			MaxElevation: pr.MaxElevation,
+			RateLimit:    pr.RateLimit,
+			Network:      pr.Network,
+			Schedule:     pr.Schedule,
 		}
 		err := s.Kontrol.UpdatePolicy(c.Request().Context(), pr.Token, policy)
+		if err == gokontrol.CommonError.MALFORM_PERMISSION {
+			return c.JSON(http.StatusBadRequest, UpdatePolicyResponse{Code: http.StatusBadRequest, Message: err.Error(), Warnings: gokontrol.LintPolicy(policy, nil, nil)})
+		}
 		if err != nil {
 			log.Logger().Error(err)
 			return c.JSON(http.StatusInternalServerError, err)
 		}
 
-		return c.JSON(http.StatusOK, UpdatePolicyResponse{Code: http.StatusOK, Message: "ok", Policy: policy})
+		return c.JSON(http.StatusOK, UpdatePolicyResponse{Code: http.StatusOK, Message: "ok", Policy: policy, Warnings: policyWarnings(c, s, pr.Token, policy)})
+	}
+}
+
+// policyWarnings linter findings on a saved policy, a failing lint does not fail the request
+func policyWarnings(c echo.Context, s *wrapper.Service, token string, policy *gokontrol.Policy) []*gokontrol.LintIssue {
+	issues, err := s.Kontrol.LintPolicies(c.Request().Context(), token, policy.ServiceID)
+	if err != nil {
+		log.Logger().Error(err)
+		return nil
+	}
+	rs := make([]*gokontrol.LintIssue, 0)
+	for _, issue := range issues {
+		if issue.PolicyID == policy.ID {
+			rs = append(rs, issue)
+		}
+	}
+	return rs
+}
+
+// LintPoliciesHandler policy linter findings on every policy of a service
+func LintPoliciesHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type LintPoliciesRequest struct {
+			ServiceID string `query:"service_id" validate:"required"`
+			Token     string `query:"token"`
+		}
+
+		type LintPoliciesResponse struct {
+			Code    int                    `json:"code"`
+			Message string                 `json:"message"`
+			Issues  []*gokontrol.LintIssue `json:"issues"`
+		}
+
+		pr := new(LintPoliciesRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		issues, err := s.Kontrol.LintPolicies(c.Request().Context(), pr.Token, pr.ServiceID)
+		if err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, LintPoliciesResponse{Code: http.StatusOK, Message: "ok", Issues: issues})
+	}
+}
+
+// GetServicePoliciesHandler list the policies of a service
+func GetServicePoliciesHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type GetServicePoliciesRequest struct {
+			ServiceID string `query:"service_id" validate:"required"`
+			Token     string `query:"token"`
+		}
+
+		type GetServicePoliciesResponse struct {
+			Code     int                 `json:"code"`
+			Message  string              `json:"message"`
+			Policies []*gokontrol.Policy `json:"policies"`
+		}
+
+		pr := new(GetServicePoliciesRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		policies, err := s.Kontrol.GetServicePolicies(c.Request().Context(), pr.Token, pr.ServiceID)
+		if err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, GetServicePoliciesResponse{Code: http.StatusOK, Message: "ok", Policies: policies})
 	}
 }
 
-//ValidateObjectHandler quick check if token is valid
-func ValidateObjectHandler(s *wrapper.Service) echo.HandlerFunc {
+type ServiceAdminRequest struct {
+	ObjectID  string `json:"object_id" validate:"required"`
+	ServiceID string `json:"service_id" validate:"required"`
+	Role      string `json:"role" validate:"required"`
+	Token     string `json:"token"`
+}
+
+// GrantServiceAdminHandler give an object an admin role on a service, authorized by the service key or organization
+func GrantServiceAdminHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		pr := new(ServiceAdminRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		err := s.Kontrol.GrantServiceAdmin(c.Request().Context(), pr.Token, &gokontrol.ServiceAdmin{ObjectID: pr.ObjectID, ServiceID: pr.ServiceID, Role: pr.Role})
+		if err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, ServiceAdminResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
+
+// RevokeServiceAdminHandler remove an admin role of an object on a service
+func RevokeServiceAdminHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pr := new(ServiceAdminRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		err := s.Kontrol.RevokeServiceAdmin(c.Request().Context(), pr.Token, &gokontrol.ServiceAdmin{ObjectID: pr.ObjectID, ServiceID: pr.ServiceID, Role: pr.Role})
+		if err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, ServiceAdminResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
 
-		type ValidateObjectResponse struct {
+type ServiceAdminResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ElevateObjectHandler grant the object of the bearer token a policy for a bounded duration
+func ElevateObjectHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type ElevateObjectRequest struct {
+			PolicyID string `json:"policy_id" validate:"required"`
+			Reason   string `json:"reason" validate:"required"`
+			Duration int64  `json:"duration" validate:"required"` // seconds
+		}
+
+		type ElevateObjectResponse struct {
+			Code      int                  `json:"code"`
+			Message   string               `json:"message"`
+			Elevation *gokontrol.Elevation `json:"elevation"`
+		}
+
+		pr := new(ElevateObjectRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		elevation, err := s.Kontrol.ElevateObject(c.Request().Context(), pr.PolicyID, pr.Reason, pr.Duration)
+		if err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		log.Logger().Info(fmt.Sprintf("object %s elevated to policy %s until %d: %s", elevation.ObjectID, elevation.PolicyID, elevation.ExpiresAt, elevation.Reason))
+		return c.JSON(http.StatusOK, ElevateObjectResponse{Code: http.StatusOK, Message: "ok", Elevation: elevation})
+	}
+}
+
+// BreakGlassHandler emergency login, issue a short lived all permissions token on one service
+func BreakGlassHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type BreakGlassRequest struct {
+			Account   string `json:"account" validate:"required"`
+			Secret    string `json:"secret" validate:"required"`
+			ServiceID string `json:"service_id" validate:"required"`
+			Reason    string `json:"reason" validate:"required"`
+		}
+
+		type BreakGlassResponse struct {
+			Code             int                         `json:"code"`
+			Message          string                      `json:"message"`
+			ObjectPermission *gokontrol.ObjectPermission `json:"object_permission"`
+		}
+
+		pr := new(BreakGlassRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		perm, err := s.Kontrol.BreakGlass(c.Request().Context(), &gokontrol.BreakGlassRequest{
+			Account:   pr.Account,
+			Secret:    pr.Secret,
+			ServiceID: pr.ServiceID,
+			Reason:    pr.Reason,
+			ClientIP:  c.RealIP(),
+		})
+		var limited *gokontrol.RateLimitError
+		if errors.As(err, &limited) {
+			breakGlassCounter.WithLabelValues(unknownLabel, unknownLabel, "throttled").Inc()
+			log.Logger().Warn(fmt.Sprintf("BREAK GLASS throttled: account %q service %q from %s", pr.Account, pr.ServiceID, c.RealIP()))
+			c.Response().Header().Set("Retry-After", strconv.FormatInt(limited.RetryAfter, 10))
+			return c.JSON(http.StatusTooManyRequests, gokontrol.CommonError.RATE_LIMITED)
+		}
+		if err != nil {
+			// the labels of denials come from the caller, they would grow the series without bound
+			breakGlassCounter.WithLabelValues(unknownLabel, unknownLabel, "denied").Inc()
+			log.Logger().Warn(fmt.Sprintf("BREAK GLASS denied: account %q service %q from %s: %v", pr.Account, pr.ServiceID, c.RealIP(), err))
+			return c.JSON(http.StatusForbidden, constant.CommonError.FORBIDDEN)
+		}
+		breakGlassCounter.WithLabelValues(pr.Account, pr.ServiceID, "granted").Inc()
+		log.Logger().Warn(fmt.Sprintf("BREAK GLASS used: account %s service %s from %s, reason: %s", pr.Account, pr.ServiceID, c.RealIP(), pr.Reason))
+		return c.JSON(http.StatusOK, BreakGlassResponse{Code: http.StatusOK, Message: "ok", ObjectPermission: perm})
+	}
+}
+
+type ServiceGrantRequest struct {
+	ObjectID  string `json:"object_id" validate:"required"`
+	ServiceID string `json:"service_id" validate:"required"` // service the object gets access to
+	ExpiresAt int64  `json:"expires_at"`                     // unix time, 0 for no expiry
+	Token     string `json:"token"`
+}
+
+type ServiceGrantResponse struct {
+	Code    int                          `json:"code"`
+	Message string                       `json:"message"`
+	Grant   *gokontrol.ObjectServiceMess `json:"grant,omitempty"`
+}
+
+// RequestServiceGrantHandler ask access of an object to another service, authorized on the object service
+func RequestServiceGrantHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pr := new(ServiceGrantRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		grant, err := s.Kontrol.RequestServiceGrant(c.Request().Context(), pr.Token, pr.ObjectID, pr.ServiceID, pr.ExpiresAt)
+		if err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, ServiceGrantResponse{Code: http.StatusOK, Message: "ok", Grant: grant})
+	}
+}
+
+// ApproveServiceGrantHandler consent of the target service to a pending grant
+func ApproveServiceGrantHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pr := new(ServiceGrantRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		if err := s.Kontrol.ApproveServiceGrant(c.Request().Context(), pr.Token, pr.ObjectID, pr.ServiceID); err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, ServiceGrantResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
+
+// RevokeServiceGrantHandler remove the grant, the object has to get a new token
+func RevokeServiceGrantHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pr := new(ServiceGrantRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		if err := s.Kontrol.RevokeServiceGrant(c.Request().Context(), pr.Token, pr.ObjectID, pr.ServiceID); err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, ServiceGrantResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
+
+// CreateSigningKeyHandler key a machine client signs its requests with as the object, the secret is only returned here
+func CreateSigningKeyHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type CreateSigningKeyRequest struct {
+			ObjectID string `json:"object_id" validate:"required"`
+			Token    string `json:"token"`
+		}
+
+		type CreateSigningKeyResponse struct {
+			Code       int                   `json:"code"`
+			Message    string                `json:"message"`
+			SigningKey *gokontrol.SigningKey `json:"signing_key"`
+		}
+
+		pr := new(CreateSigningKeyRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		key, err := s.Kontrol.CreateSigningKey(c.Request().Context(), pr.Token, pr.ObjectID)
+		if err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		log.Logger().Info(fmt.Sprintf("signing key %s created for object %s", key.ID, key.ObjectID))
+		return c.JSON(http.StatusOK, CreateSigningKeyResponse{Code: http.StatusOK, Message: "ok", SigningKey: key})
+	}
+}
+
+// RevokeSigningKeyHandler remove a signing key, requests signed with it are denied
+func RevokeSigningKeyHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type RevokeSigningKeyRequest struct {
+			KeyID string `json:"key_id" validate:"required"`
+			Token string `json:"token"`
+		}
+
+		type RevokeSigningKeyResponse struct {
 			Code    int    `json:"code"`
 			Message string `json:"message"`
 		}
-		if c.Request().Header.Get("X-Forwarded-Method") == http.MethodOptions {
-			return c.JSON(http.StatusOK, ValidateObjectResponse{Code: http.StatusOK, Message: "ok"})
+
+		pr := new(RevokeSigningKeyRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		if err := s.Kontrol.RevokeSigningKey(c.Request().Context(), pr.Token, pr.KeyID); err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		log.Logger().Info(fmt.Sprintf("signing key %s revoked", pr.KeyID))
+		return c.JSON(http.StatusOK, RevokeSigningKeyResponse{Code: http.StatusOK, Message: "ok"})
+	}
+}
+
+type PolicyTemplateRequest struct {
+	Id         string         `json:"id"` // update only
+	Name       string         `json:"name" validate:"required"`
+	ServiceID  string         `json:"service_id"` // create only
+	Token      string         `json:"token"`
+	Parameters []string       `json:"parameters"`
+	Permission map[string]int `json:"permission" validate:"required"` // keys may hold {parameter} placeholders
+}
+
+// CreatePolicyTemplateHandler create a parameterized policy template
+func CreatePolicyTemplateHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type CreatePolicyTemplateResponse struct {
+			Code     int                       `json:"code"`
+			Message  string                    `json:"message"`
+			Template *gokontrol.PolicyTemplate `json:"template"`
+		}
+
+		pr := new(PolicyTemplateRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		tpl := &gokontrol.PolicyTemplate{ID: uuid.NewString(), Name: pr.Name, ServiceID: pr.ServiceID, Parameters: pr.Parameters, Permission: pr.Permission}
+		if err := s.Kontrol.CreatePolicyTemplate(c.Request().Context(), pr.Token, tpl); err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
 		}
+		return c.JSON(http.StatusOK, CreatePolicyTemplateResponse{Code: http.StatusOK, Message: "ok", Template: tpl})
+	}
+}
 
-		// verify Access-Token header exist
-		if _, ok := c.Request().Header["Authorization"]; !ok {
-			return c.JSON(http.StatusUnauthorized, errors.New("Header 'Authorization' is empty "))
+// UpdatePolicyTemplateHandler update a template and rebind its instances
+func UpdatePolicyTemplateHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type UpdatePolicyTemplateResponse struct {
+			Code      int      `json:"code"`
+			Message   string   `json:"message"`
+			Instances []string `json:"instances"`
 		}
-		reqToken := c.Request().Header["Authorization"][0]
-		reqToken = strings.Trim(strings.Replace(reqToken, "Bearer", "", 1), " ")
 
-		_, err := s.Kontrol.ValidateToken(c.Request().Context(), reqToken, c.Request().Header["X-Forwarded-Uri"][0], c.Request().Method)
+		pr := new(PolicyTemplateRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
+		}
+		if pr.Id == "" {
+			return c.JSON(http.StatusBadRequest, constant.CommonError.INVALID_PARAM)
+		}
+		tpl := &gokontrol.PolicyTemplate{ID: pr.Id, Name: pr.Name, Parameters: pr.Parameters, Permission: pr.Permission}
+		instances, err := s.Kontrol.UpdatePolicyTemplate(c.Request().Context(), pr.Token, tpl)
 		if err != nil {
-			log.Logger().Debug(err)
-			return c.JSON(http.StatusForbidden, constant.CommonError.FORBIDDEN)
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, UpdatePolicyTemplateResponse{Code: http.StatusOK, Message: "ok", Instances: instances})
+	}
+}
+
+// InstantiatePolicyTemplateHandler create a policy bound from a template
+func InstantiatePolicyTemplateHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type InstantiatePolicyTemplateRequest struct {
+			TemplateID string                      `json:"template_id" validate:"required"`
+			Name       string                      `json:"name" validate:"required"`
+			Token      string                      `json:"token"`
+			Parameters map[string]string           `json:"parameters"`
+			Status     string                      `json:"status" validate:"required"`
+			ApplyFrom  int64                       `json:"apply_from"`
+			ApplyTo    int64                       `json:"apply_to"`
+			Schedule   []*gokontrol.PolicySchedule `json:"schedule"`
+		}
+
+		type InstantiatePolicyTemplateResponse struct {
+			Code    int               `json:"code"`
+			Message string            `json:"message"`
+			Policy  *gokontrol.Policy `json:"policy"`
+		}
+
+		pr := new(InstantiatePolicyTemplateRequest)
+		c.Bind(pr)
+		if err := c.Validate(pr); err != nil {
+			return c.JSON(http.StatusBadRequest, err)
 		}
-		return c.JSON(http.StatusOK, ValidateObjectResponse{Code: http.StatusOK, Message: "ok"})
+		policy := &gokontrol.Policy{
+			ID:        uuid.NewString(),
+			Name:      pr.Name,
+			Status:    pr.Status,
+			ApplyFrom: pr.ApplyFrom,
+			ApplyTo:   pr.ApplyTo,
+			Schedule:  pr.Schedule,
+		}
+		if err := s.Kontrol.InstantiatePolicyTemplate(c.Request().Context(), pr.Token, pr.TemplateID, policy, pr.Parameters); err != nil {
+			log.Logger().Error(err)
+			return c.JSON(http.StatusUnprocessableEntity, err)
+		}
+		return c.JSON(http.StatusOK, InstantiatePolicyTemplateResponse{Code: http.StatusOK, Message: "ok", Policy: policy})
 	}
 }
 
-//GetCertForClientHandler return object permission after successful authn
+// GetCertForClientHandler return object permission after successful authn
 func GetCertForClientHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type GetCertForClientRequest struct {
@@ -390,7 +871,7 @@ func GetCertForClientHandler(s *wrapper.Service) echo.HandlerFunc {
 	}
 }
 
-//GetCertForServiceHandler return object permission for service to cache
+// GetCertForServiceHandler return object permission for service to cache
 func GetCertForServiceHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type GetCertForClientRequest struct {
@@ -417,7 +898,53 @@ func GetCertForServiceHandler(s *wrapper.Service) echo.HandlerFunc {
 	}
 }
 
-//AuthenticateHandler Authenticate user --> call REST API cert to get request
+// demoUser mock user for demo authenticate step in external service
+type demoUser struct {
+	ExternalId string `json:"external_id"`
+	UserName   string `json:"user_name"`
+	Password   string `json:"password"`
+}
+
+// demoUsers users of the demo login, shared by AuthenticateHandler and the SSO login page
+var demoUsers = map[string]demoUser{
+	// adt - user
+	"adtuser1":  {ExternalId: "adt_id_1", UserName: "adtuser1", Password: "pass1"},
+	"adtuser2":  {ExternalId: "adt_id_2", UserName: "adtuser2", Password: "pass2"},
+	"adtuser3":  {ExternalId: "adt_id_3", UserName: "adtuser3", Password: "pass3"},
+	"adtuser4":  {ExternalId: "adt_id_4", UserName: "adtuser4", Password: "pass4"},
+	"adtuser5":  {ExternalId: "adt_id_5", UserName: "adtuser5", Password: "pass5"},
+	"adtuser6":  {ExternalId: "adt_id_6", UserName: "adtuser6", Password: "pass6"},
+	"adtuser7":  {ExternalId: "adt_id_7", UserName: "adtuser7", Password: "pass7"},
+	"adtuser8":  {ExternalId: "adt_id_8", UserName: "adtuser8", Password: "pass8"},
+	"adtuser9":  {ExternalId: "adt_id_9", UserName: "adtuser9", Password: "pass9"},
+	"adtuser10": {ExternalId: "adt_id_19", UserName: "adtuser10", Password: "pass10"},
+
+	//idt user for login
+	"idtuser1":  {ExternalId: "idt_id_1", UserName: "idtuser1", Password: "pass1"},
+	"idtuser2":  {ExternalId: "idt_id_2", UserName: "idtuser2", Password: "pass2"},
+	"idtuser3":  {ExternalId: "idt_id_3", UserName: "idtuser3", Password: "pass3"},
+	"idtuser4":  {ExternalId: "idt_id_4", UserName: "idtuser4", Password: "pass4"},
+	"idtuser5":  {ExternalId: "idt_id_5", UserName: "idtuser5", Password: "pass5"},
+	"idtuser6":  {ExternalId: "idt_id_6", UserName: "idtuser6", Password: "pass6"},
+	"idtuser7":  {ExternalId: "idt_id_7", UserName: "idtuser7", Password: "pass7"},
+	"idtuser8":  {ExternalId: "idt_id_8", UserName: "idtuser8", Password: "pass8"},
+	"idtuser9":  {ExternalId: "idt_id_9", UserName: "idtuser9", Password: "pass9"},
+	"idtuser10": {ExternalId: "idt_id_19", UserName: "idtuser10", Password: "pass10"},
+
+	//hrd user for login
+	"hrduser1":  {ExternalId: "hrd_id_1", UserName: "hrduser1", Password: "pass1"},
+	"hrduser2":  {ExternalId: "hrd_id_2", UserName: "hrduser2", Password: "pass2"},
+	"hrduser3":  {ExternalId: "hrd_id_3", UserName: "hrduser3", Password: "pass3"},
+	"hrduser4":  {ExternalId: "hrd_id_4", UserName: "hrduser4", Password: "pass4"},
+	"hrduser5":  {ExternalId: "hrd_id_5", UserName: "hrduser5", Password: "pass5"},
+	"hrduser6":  {ExternalId: "hrd_id_6", UserName: "hrduser6", Password: "pass6"},
+	"hrduser7":  {ExternalId: "hrd_id_7", UserName: "hrduser7", Password: "pass7"},
+	"hrduser8":  {ExternalId: "hrd_id_8", UserName: "hrduser8", Password: "pass8"},
+	"hrduser9":  {ExternalId: "hrd_id_9", UserName: "hrduser9", Password: "pass9"},
+	"hrduser10": {ExternalId: "hrd_id_19", UserName: "hrduser10", Password: "pass10"},
+}
+
+// AuthenticateHandler Authenticate user --> call REST API cert to get request
 func AuthenticateHandler(s *wrapper.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type AuthenticateRequest struct {
@@ -431,49 +958,6 @@ func AuthenticateHandler(s *wrapper.Service) echo.HandlerFunc {
 			Message          string                      `json:"message"`
 			ObjectPermission *gokontrol.ObjectPermission `json:"object_permission"`
 		}
-		type User struct {
-			ExternalId string `json:"external_id"`
-			UserName   string `json:"user_name"`
-			Password   string `json:"password"`
-		}
-		// this is mock user for demo authenticate step in external service
-		users := map[string]User{
-			// adt - user
-			"adtuser1":  {ExternalId: "adt_id_1", UserName: "adtuser1", Password: "pass1"},
-			"adtuser2":  {ExternalId: "adt_id_2", UserName: "adtuser2", Password: "pass2"},
-			"adtuser3":  {ExternalId: "adt_id_3", UserName: "adtuser3", Password: "pass3"},
-			"adtuser4":  {ExternalId: "adt_id_4", UserName: "adtuser4", Password: "pass4"},
-			"adtuser5":  {ExternalId: "adt_id_5", UserName: "adtuser5", Password: "pass5"},
-			"adtuser6":  {ExternalId: "adt_id_6", UserName: "adtuser6", Password: "pass6"},
-			"adtuser7":  {ExternalId: "adt_id_7", UserName: "adtuser7", Password: "pass7"},
-			"adtuser8":  {ExternalId: "adt_id_8", UserName: "adtuser8", Password: "pass8"},
-			"adtuser9":  {ExternalId: "adt_id_9", UserName: "adtuser9", Password: "pass9"},
-			"adtuser10": {ExternalId: "adt_id_19", UserName: "adtuser10", Password: "pass10"},
-
-			//idt user for login
-			"idtuser1":  {ExternalId: "idt_id_1", UserName: "idtuser1", Password: "pass1"},
-			"idtuser2":  {ExternalId: "idt_id_2", UserName: "idtuser2", Password: "pass2"},
-			"idtuser3":  {ExternalId: "idt_id_3", UserName: "idtuser3", Password: "pass3"},
-			"idtuser4":  {ExternalId: "idt_id_4", UserName: "idtuser4", Password: "pass4"},
-			"idtuser5":  {ExternalId: "idt_id_5", UserName: "idtuser5", Password: "pass5"},
-			"idtuser6":  {ExternalId: "idt_id_6", UserName: "idtuser6", Password: "pass6"},
-			"idtuser7":  {ExternalId: "idt_id_7", UserName: "idtuser7", Password: "pass7"},
-			"idtuser8":  {ExternalId: "idt_id_8", UserName: "idtuser8", Password: "pass8"},
-			"idtuser9":  {ExternalId: "idt_id_9", UserName: "idtuser9", Password: "pass9"},
-			"idtuser10": {ExternalId: "idt_id_19", UserName: "idtuser10", Password: "pass10"},
-
-			//hrd user for login
-			"hrduser1":  {ExternalId: "hrd_id_1", UserName: "hrduser1", Password: "pass1"},
-			"hrduser2":  {ExternalId: "hrd_id_2", UserName: "hrduser2", Password: "pass2"},
-			"hrduser3":  {ExternalId: "hrd_id_3", UserName: "hrduser3", Password: "pass3"},
-			"hrduser4":  {ExternalId: "hrd_id_4", UserName: "hrduser4", Password: "pass4"},
-			"hrduser5":  {ExternalId: "hrd_id_5", UserName: "hrduser5", Password: "pass5"},
-			"hrduser6":  {ExternalId: "hrd_id_6", UserName: "hrduser6", Password: "pass6"},
-			"hrduser7":  {ExternalId: "hrd_id_7", UserName: "hrduser7", Password: "pass7"},
-			"hrduser8":  {ExternalId: "hrd_id_8", UserName: "hrduser8", Password: "pass8"},
-			"hrduser9":  {ExternalId: "hrd_id_9", UserName: "hrduser9", Password: "pass9"},
-			"hrduser10": {ExternalId: "hrd_id_19", UserName: "hrduser10", Password: "pass10"},
-		}
 		pr := new(AuthenticateRequest)
 		c.Bind(pr)
 		if err := c.Validate(pr); err != nil {
@@ -481,14 +965,14 @@ func AuthenticateHandler(s *wrapper.Service) echo.HandlerFunc {
 		}
 
 		// authenticate -- for demo :)
-		if _, ok := users[pr.UserName]; ok == true {
-			if users[pr.UserName].Password != pr.Password {
+		if _, ok := demoUsers[pr.UserName]; ok == true {
+			if demoUsers[pr.UserName].Password != pr.Password {
 				return c.JSON(http.StatusForbidden, errors.New("Invalid username or password "))
 			}
 		} else {
 			return c.JSON(http.StatusForbidden, errors.New("User is not existed "))
 		}
-		cert, err := getServerCert(s.Config, pr.ServiceID, users[pr.UserName].ExternalId)
+		cert, err := getServerCert(s.Config, pr.ServiceID, demoUsers[pr.UserName].ExternalId)
 		if err != nil {
 			log.Logger().Error(err)
 			return c.JSON(http.StatusUnprocessableEntity, err)
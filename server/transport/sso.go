@@ -0,0 +1,193 @@
+package transport
+
+import (
+	"context"
+	"crypto/subtle"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+	"github.com/neko-neko/echo-logrus/v2/log"
+)
+
+// defaultReturnTTL validity of the signed return url when sso.return_ttl is not set, second
+const defaultReturnTTL = 600
+
+// loginPage hosted login form, posting back the signed return url
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<form method="post" action="{{.Action}}">
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+<input type="hidden" name="service" value="{{.Return.ServiceID}}">
+<input type="hidden" name="return_to" value="{{.Return.ReturnTo}}">
+<input type="hidden" name="expires" value="{{.Return.Expires}}">
+<input type="hidden" name="sig" value="{{.Sig}}">
+<label>User name <input type="text" name="user_name" autocomplete="username" required></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+// ssoConfig browser session settings, nil when not configured
+func ssoConfig(s *wrapper.Service) *config.SSO {
+	if s.Config == nil {
+		return nil
+	}
+	return s.Config.SSO
+}
+
+// isBrowserNavigation whether req is a top level page load, which a browser follows a redirect of, rather than a
+// fetch or an api call expecting the 401
+func isBrowserNavigation(req *gokontrol.AccessRequest) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if mode := req.Header.Get("Sec-Fetch-Mode"); mode != "" {
+		return mode == "navigate"
+	}
+	return req.Header.Get("X-Requested-With") == "" && strings.Contains(req.Header.Get("Accept"), "text/html")
+}
+
+// requestURL absolute url of the original request
+func requestURL(req *gokontrol.AccessRequest) string {
+	u := url.URL{Scheme: req.Proto, Host: req.Host, Path: req.Path, RawQuery: req.Query}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+	return u.String()
+}
+
+// returnHostAllowed whether browsers may be sent back to host once logged in, the routing host of the service or one
+// of sso.return_hosts. The host comes from X-Forwarded-Host, signing it unchecked would redirect anywhere
+func returnHostAllowed(sso *config.SSO, service *gokontrol.Service, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		return false
+	}
+	if strings.EqualFold(service.Routing.Host, host) {
+		return true
+	}
+	for _, h := range sso.ReturnHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// loginRedirect denial of browser navigations, a redirect to the login page with the signed return url, when
+// sso.login_url is set. Other requests keep the 401 denial
+func loginRedirect(ctx context.Context, s *wrapper.Service, req *gokontrol.AccessRequest, denial *authDenial) *authDenial {
+	sso := ssoConfig(s)
+	if sso == nil || sso.LoginURL == "" || !isBrowserNavigation(req) {
+		return denial
+	}
+	service, err := s.Kontrol.ResolveService(ctx, req)
+	if err != nil || !returnHostAllowed(sso, service, req.Host) {
+		return denial
+	}
+	location, err := url.Parse(sso.LoginURL)
+	if err != nil {
+		log.Logger().Error(err)
+		return denial
+	}
+	ttl := sso.ReturnTTL
+	if ttl <= 0 {
+		ttl = defaultReturnTTL
+	}
+	ret := &gokontrol.LoginReturn{ServiceID: service.ID, ReturnTo: requestURL(req), Expires: time.Now().Unix() + ttl}
+	query := location.Query()
+	query.Set("service", ret.ServiceID)
+	query.Set("return_to", ret.ReturnTo)
+	query.Set("expires", strconv.FormatInt(ret.Expires, 10))
+	query.Set("sig", s.Kontrol.SignLoginReturn(ret))
+	location.RawQuery = query.Encode()
+	return &authDenial{Code: http.StatusFound, Location: location.String(), Message: denial.Message}
+}
+
+// loginReturn signed return url of the login page request, from the query or the posted form
+func loginReturn(c echo.Context) (*gokontrol.LoginReturn, string) {
+	expires, _ := strconv.ParseInt(c.FormValue("expires"), 10, 64)
+	return &gokontrol.LoginReturn{ServiceID: c.FormValue("service"), ReturnTo: c.FormValue("return_to"), Expires: expires}, c.FormValue("sig")
+}
+
+// renderLoginPage login form for ret, with the message of a failed attempt if any
+func renderLoginPage(c echo.Context, code int, ret *gokontrol.LoginReturn, sig string, message string) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().WriteHeader(code)
+	return loginPage.Execute(c.Response(), struct {
+		Action  string
+		Return  *gokontrol.LoginReturn
+		Sig     string
+		Message string
+	}{Action: c.Request().URL.Path, Return: ret, Sig: sig, Message: message})
+}
+
+//SSOLoginPageHandler hosted login page browsers are redirected to by the validate endpoint, the return url must be
+//signed and unexpired
+func SSOLoginPageHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ret, sig := loginReturn(c)
+		if err := s.Kontrol.VerifyLoginReturn(ret, sig); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return renderLoginPage(c, http.StatusOK, ret, sig, "")
+	}
+}
+
+//SSOLoginHandler authenticate the login form, set the session cookie on the configured domain with a token of the
+//service and redirect back to the signed return url
+func SSOLoginHandler(s *wrapper.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ret, sig := loginReturn(c)
+		if err := s.Kontrol.VerifyLoginReturn(ret, sig); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		sso := ssoConfig(s)
+		if sso == nil || sso.CookieName == "" {
+			return c.String(http.StatusNotFound, "sso is not configured")
+		}
+
+		// authenticate -- for demo, as AuthenticateHandler
+		user, ok := demoUsers[c.FormValue("user_name")]
+		if !ok || subtle.ConstantTimeCompare([]byte(user.Password), []byte(c.FormValue("password"))) != 1 {
+			return renderLoginPage(c, http.StatusUnauthorized, ret, sig, "Invalid username or password")
+		}
+		cert, err := s.Kontrol.IssueCertForClient(c.Request().Context(), user.ExternalId, ret.ServiceID)
+		switch err {
+		case nil:
+		case gokontrol.CommonError.OBJECT_NOT_FOUND, gokontrol.CommonError.INVALID_SERVICE:
+			return renderLoginPage(c, http.StatusForbidden, ret, sig, "No access to this service")
+		default:
+			log.Logger().Error(err)
+			return c.String(http.StatusInternalServerError, "internal error")
+		}
+
+		// a browser session, the token expiry still applies
+		c.SetCookie(&http.Cookie{
+			Name:     sso.CookieName,
+			Value:    cert.Token,
+			Path:     "/",
+			Domain:   sso.CookieDomain,
+			Secure:   sso.CookieSecure,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return c.Redirect(http.StatusSeeOther, ret.ReturnTo)
+	}
+}
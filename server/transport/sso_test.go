@@ -0,0 +1,165 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hungvtc/traefik-integrate/server/config"
+	"github.com/hungvtc/traefik-integrate/server/service/go-kontrol"
+	"github.com/hungvtc/traefik-integrate/server/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+func TestForwardAuthHandler_LoginRedirect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := gokontrol.NewMockKontrolStore(ctrl)
+	option := gokontrol.DefaultKontrolOption
+	option.SessionCookie = "kontrol_session"
+	kontrol := gokontrol.NewKontrol(store, option)
+	obj := &gokontrol.Object{ID: "oid", ServiceID: "sid-a", ExpiryDate: time.Now().Unix() + 60}
+	_, sign, jwtToken, err := kontrol.CreateCert(obj, []*gokontrol.Policy{{ID: "p", ServiceID: "sid-b", Permission: map[string]int{"GET@/list$": 1}}}, nil, []string{"sid-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EXPECT().GetServiceByExternalId(gomock.Any(), "orders").Return(&gokontrol.Service{ID: "sid-b", ServiceID: "orders",
+		Routing: gokontrol.ServiceRouting{Host: "gateway.local"}}, nil).AnyTimes()
+	store.EXPECT().GetObjectByToken(gomock.Any(), sign, gomock.Any()).Return(obj, nil).AnyTimes()
+	sso := &config.SSO{LoginURL: "https://sso.example.com/sso/login", CookieName: "kontrol_session", ReturnHosts: []string{"app.example.com"}}
+
+	e := echo.New()
+	e.GET("/internal_api/validate", ForwardAuthHandler(&wrapper.Service{Kontrol: kontrol, Config: &config.Config{SSO: sso}}))
+	auth := httptest.NewServer(e)
+	defer auth.Close()
+
+	tests := []struct {
+		name         string
+		method       string
+		host         string
+		header       http.Header
+		wantStatus   int
+		wantRedirect bool
+	}{
+		{name: "#1: navigation", method: http.MethodGet, header: http.Header{"Sec-Fetch-Mode": {"navigate"}}, wantStatus: http.StatusFound, wantRedirect: true},
+		{name: "#2: navigation of a browser without fetch metadata", method: http.MethodGet,
+			header: http.Header{"Accept": {"text/html,application/xhtml+xml;q=0.9,*/*;q=0.8"}}, wantStatus: http.StatusFound, wantRedirect: true},
+		{name: "#3: expired token", method: http.MethodGet, header: http.Header{"Sec-Fetch-Mode": {"navigate"}, "Authorization": {"Bearer not-a-jwt"}},
+			wantStatus: http.StatusFound, wantRedirect: true},
+		{name: "#4: fetch", method: http.MethodGet, header: http.Header{"Sec-Fetch-Mode": {"cors"}, "Accept": {"text/html"}}, wantStatus: http.StatusUnauthorized},
+		{name: "#5: api call", method: http.MethodGet, header: http.Header{"Accept": {"application/json"}}, wantStatus: http.StatusUnauthorized},
+		{name: "#6: form post", method: http.MethodPost, header: http.Header{"Sec-Fetch-Mode": {"navigate"}}, wantStatus: http.StatusUnauthorized},
+		{name: "#7: session cookie", method: http.MethodGet, header: http.Header{"Sec-Fetch-Mode": {"navigate"}, "Cookie": {"kontrol_session=" + jwtToken}},
+			wantStatus: http.StatusOK},
+		{name: "#8: navigation on a listed return host", method: http.MethodGet, host: "app.example.com", header: http.Header{"Sec-Fetch-Mode": {"navigate"}},
+			wantStatus: http.StatusFound, wantRedirect: true},
+		{name: "#9: navigation on another host", method: http.MethodGet, host: "evil.example.com", header: http.Header{"Sec-Fetch-Mode": {"navigate"}},
+			wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://gateway.local/orders/list?page=2", nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			resp, _ := traefikForwardAuth(t, auth.URL+"/internal_api/validate", r)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			location := resp.Header.Get("Location")
+			if !tt.wantRedirect {
+				if location != "" {
+					t.Errorf("Location = %q, want none", location)
+				}
+				return
+			}
+			u, err := url.Parse(location)
+			if err != nil || u.Host != "sso.example.com" || u.Path != "/sso/login" {
+				t.Fatalf("Location = %q, want the login page", location)
+			}
+			q := u.Query()
+			expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+			ret := &gokontrol.LoginReturn{ServiceID: q.Get("service"), ReturnTo: q.Get("return_to"), Expires: expires}
+			if ret.ServiceID != "sid-b" || ret.ReturnTo != "https://"+r.Host+"/orders/list?page=2" {
+				t.Errorf("return = %+v, want sid-b and the original url", ret)
+			}
+			if err := kontrol.VerifyLoginReturn(ret, q.Get("sig")); err != nil {
+				t.Errorf("VerifyLoginReturn() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSSOLoginHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	kontrol := gokontrol.NewMockKontrol(ctrl)
+	ret := &gokontrol.LoginReturn{ServiceID: "sid-b", ReturnTo: "https://gateway.local/orders/list", Expires: time.Now().Unix() + 60}
+	kontrol.EXPECT().VerifyLoginReturn(ret, "good").Return(nil).AnyTimes()
+	kontrol.EXPECT().VerifyLoginReturn(gomock.Any(), "bad").Return(gokontrol.CommonError.INVALID_RETURN_URL).AnyTimes()
+	kontrol.EXPECT().IssueCertForClient(gomock.Any(), "idt_id_1", "sid-b").Return(&gokontrol.ObjectPermission{ObjectId: "oid", Token: "jwt"}, nil).AnyTimes()
+	kontrol.EXPECT().IssueCertForClient(gomock.Any(), "adt_id_1", "sid-b").Return(nil, gokontrol.CommonError.OBJECT_NOT_FOUND).AnyTimes()
+	s := &wrapper.Service{Kontrol: kontrol, Config: &config.Config{SSO: &config.SSO{CookieName: "kontrol_session", CookieDomain: "example.com", CookieSecure: true}}}
+
+	e := echo.New()
+	e.GET("/sso/login", SSOLoginPageHandler(s))
+	e.POST("/sso/login", SSOLoginHandler(s))
+
+	form := func(sig string, user string, password string) url.Values {
+		return url.Values{"service": {ret.ServiceID}, "return_to": {ret.ReturnTo}, "expires": {strconv.FormatInt(ret.Expires, 10)},
+			"sig": {sig}, "user_name": {user}, "password": {password}}
+	}
+	tests := []struct {
+		name       string
+		method     string
+		form       url.Values
+		wantStatus int
+		wantCookie bool
+	}{
+		{name: "#1: login page", method: http.MethodGet, form: form("good", "", ""), wantStatus: http.StatusOK},
+		{name: "#2: login page of an unsigned return url", method: http.MethodGet, form: form("bad", "", ""), wantStatus: http.StatusBadRequest},
+		{name: "#3: login", method: http.MethodPost, form: form("good", "idtuser1", "pass1"), wantStatus: http.StatusSeeOther, wantCookie: true},
+		{name: "#4: wrong password", method: http.MethodPost, form: form("good", "idtuser1", "pass2"), wantStatus: http.StatusUnauthorized},
+		{name: "#5: user without access to the service", method: http.MethodPost, form: form("good", "adtuser1", "pass1"), wantStatus: http.StatusForbidden},
+		{name: "#6: unsigned return url", method: http.MethodPost, form: form("bad", "idtuser1", "pass1"), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r *http.Request
+			if tt.method == http.MethodGet {
+				r = httptest.NewRequest(tt.method, "/sso/login?"+tt.form.Encode(), nil)
+			} else {
+				r = httptest.NewRequest(tt.method, "/sso/login", strings.NewReader(tt.form.Encode()))
+				r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			cookies := w.Result().Cookies()
+			if !tt.wantCookie {
+				if len(cookies) != 0 {
+					t.Errorf("cookies = %v, want none", cookies)
+				}
+				return
+			}
+			if len(cookies) != 1 || cookies[0].Name != "kontrol_session" || cookies[0].Value != "jwt" || cookies[0].Domain != "example.com" ||
+				!cookies[0].HttpOnly || !cookies[0].Secure {
+				t.Errorf("cookies = %v, want the session cookie", cookies)
+			}
+			if got := w.Header().Get("Location"); got != ret.ReturnTo {
+				t.Errorf("Location = %q, want %q", got, ret.ReturnTo)
+			}
+		})
+	}
+}
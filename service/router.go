@@ -42,10 +42,11 @@ func NewEcho(s *Service) *echo.Echo {
 	})
 	e.GET("/internal_api/info", func(c echo.Context) error {
 		type InfoResponse struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
+			Code     int    `json:"code"`
+			Message  string `json:"message"`
+			ObjectID string `json:"object_id"` // caller identity set by the gateway after forwardAuth
 		}
-		return c.JSON(http.StatusOK, InfoResponse{Code: http.StatusOK, Message: fmt.Sprintf("Welcome to %s service", s.Config.AppName)})
+		return c.JSON(http.StatusOK, InfoResponse{Code: http.StatusOK, Message: fmt.Sprintf("Welcome to %s service", s.Config.AppName), ObjectID: c.Request().Header.Get("X-Auth-Object-Id")})
 	})
 
 	// admin := e.Group("/admin")